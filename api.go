@@ -0,0 +1,23 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"golang.org/x/net/context"
+)
+
+// API is the interface of the calls a Client makes, for code that would rather depend on an interface than on the
+// concrete *Client, so that tests can substitute a fake. As calls are added to Client, they are added here too;
+// fakes should embed API to keep compiling.
+type API interface {
+	GetJSON(ctx context.Context, config *APIConfig, apiReq APIRequest, resp interface{}, opts ...RequestOption) error
+	GetXML(ctx context.Context, config *APIConfig, apiReq APIRequest, resp interface{}, opts ...RequestOption) error
+	GetBinary(ctx context.Context, config *APIConfig, apiReq APIRequest, opts ...RequestOption) (BinaryResponse, error)
+	GetJSONStream(ctx context.Context, config *APIConfig, apiReq APIRequest, handle func(json.RawMessage) error, opts ...RequestOption) error
+	DownloadFile(ctx context.Context, config *APIConfig, apiReq APIRequest, path string, progress func(Progress), opts ...RequestOption) error
+	PostMultipart(ctx context.Context, config *APIConfig, apiReq APIRequest, fields url.Values, files []MultipartFile, resp interface{}, opts ...RequestOption) error
+	PostGraphQL(ctx context.Context, config *APIConfig, query string, variables map[string]interface{}, out interface{}, opts ...RequestOption) error
+}
+
+var _ API = (*Client)(nil)
@@ -0,0 +1,72 @@
+package apiclienttest
+
+import (
+	"sync"
+	"time"
+)
+
+// ManualClock is an apiclient.Clock whose time only moves when Advance is called, for use with WithClock: the rate
+// limiter's refills and the client's sleeps happen exactly when the test advances the clock past them.
+type ManualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*manualTicker
+}
+
+type manualTicker struct {
+	c      chan time.Time
+	period time.Duration
+	next   time.Time
+	// stopped is closed when the ticker is stopped.
+	stopped chan struct{}
+}
+
+// NewManualClock returns a ManualClock set to start.
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now returns the clock's current time.
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Tick returns a channel receiving a tick whenever the clock is advanced past a multiple of d.
+func (c *ManualClock) Tick(d time.Duration) (<-chan time.Time, func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &manualTicker{c: make(chan time.Time), period: d, next: c.now.Add(d), stopped: make(chan struct{})}
+	c.tickers = append(c.tickers, t)
+	var once sync.Once
+	return t.c, func() {
+		once.Do(func() { close(t.stopped) })
+	}
+}
+
+// Advance moves the clock forward by d. Every ticker that came due receives a single tick, as a time.Ticker drops
+// the ticks its receiver misses; Advance returns once they have all been received. The client's scheduler then runs
+// the work that came due.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+	var due []*manualTicker
+	for _, t := range c.tickers {
+		if t.next.After(now) {
+			continue
+		}
+		for !t.next.After(now) {
+			t.next = t.next.Add(t.period)
+		}
+		due = append(due, t)
+	}
+	c.mu.Unlock()
+	for _, t := range due {
+		select {
+		case t.c <- now:
+		case <-t.stopped:
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package apiclienttest
+
+import (
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	apiclient "github.com/MaTriXy/api-client"
+)
+
+// NewFixtureServer starts an httptest.Server answering requests with the content of fixture files, and returns it
+// with a client sending its requests to it, configured with options. routes maps a path, or a method and a path such
+// as "GET /v1/users/42", to the file its response is read from; the Content-Type is chosen by the file's extension.
+// Other requests get a 404. The server is closed when the test ends.
+//
+//	srv, c := apiclienttest.NewFixtureServer(t, map[string]string{"/v1/users/42": "testdata/user.json"})
+func NewFixtureServer(t testing.TB, routes map[string]string, options ...apiclient.ClientOption) (*httptest.Server, *apiclient.Client) {
+	t.Helper()
+	fixtures := make(map[string][]byte, len(routes))
+	for route, file := range routes {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("apiclienttest: fixture for %s: %v", route, err)
+		}
+		fixtures[route] = data
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.Method + " " + r.URL.Path
+		data, ok := fixtures[route]
+		if !ok {
+			route = r.URL.Path
+			data, ok = fixtures[route]
+		}
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if ct := mime.TypeByExtension(filepath.Ext(routes[route])); ct != "" {
+			w.Header().Set("Content-Type", ct)
+		}
+		if r.Method != "HEAD" {
+			w.Write(data)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	options = append([]apiclient.ClientOption{apiclient.WithBaseURLs([]string{srv.URL}, apiclient.FailoverPolicy{})}, options...)
+	c, err := apiclient.NewClient(options...)
+	if err != nil {
+		srv.Close()
+		t.Fatalf("apiclienttest: %v", err)
+	}
+	return srv, c
+}
@@ -0,0 +1,252 @@
+// Package apiclienttest provides utilities for testing code built on apiclient without a live server.
+package apiclienttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// MockTransport is an http.RoundTripper answering requests with the canned responses of the first registered route
+// they match, and recording them for assertions:
+//
+//	mock := apiclienttest.NewMockTransport()
+//	mock.On("GET", "/v1/users/{id}").Query("fields", "name").RespondJSON(200, user)
+//	c, _ := apiclient.NewClient(apiclient.WithHTTPClient(mock.Client()))
+//	...
+//	mock.AssertCalled(t, "GET", "/v1/users/42")
+//
+// A request matching no route fails with an error naming it.
+type MockTransport struct {
+	mu     sync.Mutex
+	routes []*Route
+	calls  []Call
+}
+
+// NewMockTransport returns a MockTransport without routes.
+func NewMockTransport() *MockTransport {
+	return &MockTransport{}
+}
+
+// Client returns an http.Client sending its requests to m, for WithHTTPClient.
+func (m *MockTransport) Client() *http.Client {
+	return &http.Client{Transport: m}
+}
+
+// Call is a request received by a MockTransport.
+type Call struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+}
+
+// Route is a matcher of requests with its canned response, see MockTransport.On.
+type Route struct {
+	method string
+	path   []string
+	query  url.Values
+
+	status  int
+	header  http.Header
+	body    []byte
+	latency time.Duration
+	err     error
+}
+
+// On registers a route matching requests with method and path. Segments of path written as {name} match any
+// segment. The route answers 200 with an empty body until told otherwise.
+func (m *MockTransport) On(method, path string) *Route {
+	r := &Route{method: method, path: strings.Split(path, "/"), query: url.Values{}, status: http.StatusOK, header: http.Header{}}
+	m.mu.Lock()
+	m.routes = append(m.routes, r)
+	m.mu.Unlock()
+	return r
+}
+
+// Query restricts the route to requests with the query parameter key set to value, among others.
+func (r *Route) Query(key, value string) *Route {
+	r.query.Add(key, value)
+	return r
+}
+
+// Respond sets the status and body of the route's response.
+func (r *Route) Respond(status int, body string) *Route {
+	r.status, r.body = status, []byte(body)
+	return r
+}
+
+// RespondJSON sets the status of the route's response, and its body to v encoded as JSON.
+func (r *Route) RespondJSON(status int, v interface{}) *Route {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("apiclienttest: cannot encode response: %v", err))
+	}
+	r.status, r.body = status, body
+	if r.header.Get("Content-Type") == "" {
+		r.header.Set("Content-Type", "application/json")
+	}
+	return r
+}
+
+// Header adds a header to the route's response.
+func (r *Route) Header(key, value string) *Route {
+	r.header.Add(key, value)
+	return r
+}
+
+// Latency delays the route's response by d, or until the request is canceled.
+func (r *Route) Latency(d time.Duration) *Route {
+	r.latency = d
+	return r
+}
+
+// Fail makes the route fail with err instead of responding, as a network failure would.
+func (r *Route) Fail(err error) *Route {
+	r.err = err
+	return r
+}
+
+func (r *Route) matches(req *http.Request) bool {
+	if r.method != req.Method {
+		return false
+	}
+	path := strings.Split(req.URL.Path, "/")
+	if len(path) != len(r.path) {
+		return false
+	}
+	for i, seg := range r.path {
+		if seg != path[i] && !(strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}")) {
+			return false
+		}
+	}
+	got := req.URL.Query()
+	for key, values := range r.query {
+		for _, v := range values {
+			if !contains(got[key], v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// RoundTrip records req and answers it with the response of the first route it matches.
+func (m *MockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	m.mu.Lock()
+	m.calls = append(m.calls, Call{Method: req.Method, URL: req.URL, Header: req.Header.Clone(), Body: body})
+	var route *Route
+	for _, r := range m.routes {
+		if r.matches(req) {
+			route = r
+			break
+		}
+	}
+	m.mu.Unlock()
+	if route == nil {
+		return nil, fmt.Errorf("apiclienttest: no route for %s %s", req.Method, req.URL)
+	}
+	if route.latency > 0 {
+		t := time.NewTimer(route.latency)
+		select {
+		case <-t.C:
+		case <-req.Context().Done():
+			t.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+	if route.err != nil {
+		return nil, route.err
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", route.status, http.StatusText(route.status)),
+		StatusCode:    route.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        route.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(route.body)),
+		ContentLength: int64(len(route.body)),
+		Request:       req,
+	}, nil
+}
+
+// Calls returns the requests received so far, in order.
+func (m *MockTransport) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// Called returns how many requests with method and path were received. path may contain {name} segments, as for On.
+func (m *MockTransport) Called(method, path string) int {
+	matcher := &Route{method: method, path: strings.Split(path, "/")}
+	n := 0
+	for _, call := range m.Calls() {
+		if matcher.matches(&http.Request{Method: call.Method, URL: call.URL}) {
+			n++
+		}
+	}
+	return n
+}
+
+// AssertCalled fails t unless a request with method and path was received.
+func (m *MockTransport) AssertCalled(t testing.TB, method, path string) {
+	t.Helper()
+	if m.Called(method, path) == 0 {
+		t.Errorf("apiclienttest: %s %s was not called; calls: %s", method, path, m.describeCalls())
+	}
+}
+
+// AssertNotCalled fails t if a request with method and path was received.
+func (m *MockTransport) AssertNotCalled(t testing.TB, method, path string) {
+	t.Helper()
+	if n := m.Called(method, path); n > 0 {
+		t.Errorf("apiclienttest: %s %s was called %d times", method, path, n)
+	}
+}
+
+// AssertCallCount fails t unless n requests were received in all.
+func (m *MockTransport) AssertCallCount(t testing.TB, n int) {
+	t.Helper()
+	if got := len(m.Calls()); got != n {
+		t.Errorf("apiclienttest: got %d calls, want %d; calls: %s", got, n, m.describeCalls())
+	}
+}
+
+func (m *MockTransport) describeCalls() string {
+	calls := m.Calls()
+	if len(calls) == 0 {
+		return "none"
+	}
+	descs := make([]string, len(calls))
+	for i, call := range calls {
+		descs[i] = call.Method + " " + call.URL.String()
+	}
+	return strings.Join(descs, ", ")
+}
+
+func contains(values []string, v string) bool {
+	for _, candidate := range values {
+		if candidate == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package apiclient
+
+import (
+	"golang.org/x/net/context"
+)
+
+// Future is the pending outcome of an asynchronous call.
+type Future struct {
+	done chan struct{}
+	err  error
+}
+
+// Done returns a channel that is closed once the call has completed.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the call has completed and returns its error, or returns ctx.Err() if ctx is done first. The call
+// itself keeps running in the latter case; it is bound to the context it was started with.
+func (f *Future) Wait(ctx context.Context) error {
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// GetJSONAsync starts GetJSON in the background and returns a Future for its outcome. resp must not be used until
+// the Future is done.
+func (c *Client) GetJSONAsync(ctx context.Context, config *APIConfig, apiReq APIRequest, resp interface{}, opts ...RequestOption) *Future {
+	f := &Future{done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+		f.err = c.GetJSON(ctx, config, apiReq, resp, opts...)
+	}()
+	return f
+}
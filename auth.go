@@ -0,0 +1,179 @@
+package apiclient
+
+import (
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/context"
+)
+
+// CredentialLocation says where a Credential is attached to a request.
+type CredentialLocation int
+
+const (
+	// InQuery sends the credential as a query parameter.
+	InQuery CredentialLocation = iota
+	// InHeader sends the credential as a request header.
+	InHeader
+)
+
+// Credential is a secret attached to requests as a named query parameter or header.
+type Credential struct {
+	Name  string
+	Value string
+	In    CredentialLocation
+}
+
+// BearerToken returns a Credential sending token in an "Authorization: Bearer" header.
+func BearerToken(token string) Credential {
+	return Credential{Name: "Authorization", Value: "Bearer " + token, In: InHeader}
+}
+
+// WithCredentials configures the client to attach creds to every request, in addition to the API key configured with
+// WithAPIKey. This is how APIs that require, e.g., both an app key and a partner token are served.
+//
+// Credentials are applied in order: the API key, then the client's credentials, then those of its credential
+// providers (see WithCredentialProvider), then any credentials carried by the request context (see
+// ContextWithCredentials). A credential replaces an earlier one with the same name and location,
+// so per-request credentials take precedence over client-wide ones.
+func WithCredentials(creds ...Credential) ClientOption {
+	return func(c *Client) error {
+		c.credentials = append(c.credentials, creds...)
+		return nil
+	}
+}
+
+type credentialsKey struct{}
+
+// ContextWithCredentials returns a copy of ctx carrying creds, to be attached to the requests made with it, e.g. the
+// OAuth token of the user a request is made for.
+func ContextWithCredentials(ctx context.Context, creds ...Credential) context.Context {
+	prev, _ := ctx.Value(credentialsKey{}).([]Credential)
+	all := append(append([]Credential(nil), prev...), creds...)
+	return context.WithValue(ctx, credentialsKey{}, all)
+}
+
+// requestCredentials returns the API key and all credentials that apply to a request made with ctx, in the order
+// they are applied.
+func (c *Client) requestCredentials(ctx context.Context) []Credential {
+	return append(c.clientCredentials(), contextCredentials(ctx)...)
+}
+
+// clientCredentials returns the API key and the static credentials of the client.
+func (c *Client) clientCredentials() []Credential {
+	var creds []Credential
+	c.authMu.RLock()
+	if c.apiKeyValue != "" {
+		creds = append(creds, Credential{Name: c.apiKeyName, Value: c.apiKeyValue, In: InQuery})
+	}
+	c.authMu.RUnlock()
+	return append(creds, c.credentials...)
+}
+
+// SetAPIKey replaces the API key of the client, e.g. to rotate it, without losing the state of its rate limiter or
+// cache. Requests already sent keep the previous key, which stays redacted from diagnostics. An empty apiKeyValue
+// removes the API key. It is safe to call while requests are made.
+func (c *Client) SetAPIKey(apiKeyName, apiKeyValue string) {
+	c.reconfigure(func() {
+		c.authMu.Lock()
+		defer c.authMu.Unlock()
+		if c.apiKeyValue != "" {
+			c.provided.add(Credential{Name: c.apiKeyName, Value: c.apiKeyValue, In: InQuery})
+		}
+		c.apiKeyName, c.apiKeyValue = apiKeyName, apiKeyValue
+	})
+}
+
+func contextCredentials(ctx context.Context) []Credential {
+	creds, _ := ctx.Value(credentialsKey{}).([]Credential)
+	return creds
+}
+
+// authenticate attaches all credentials that apply to a request made with ctx to its header and query, including
+// those of the client's credential providers. It returns the key picked from the client's key pool, if it has one,
+// to be told about the response.
+func (c *Client) authenticate(ctx context.Context, header http.Header, q url.Values) (*pooledKey, error) {
+	provided, err := c.providedCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if bearer, err := c.bearerToken(ctx); err != nil {
+		return nil, err
+	} else if bearer != nil {
+		provided = append(provided, *bearer)
+	}
+	var key *pooledKey
+	if c.apiKeys != nil {
+		key = c.apiKeys.pick(c.clock.Now())
+		key.cred.apply(header, q)
+	}
+	for _, creds := range [][]Credential{c.clientCredentials(), provided, contextCredentials(ctx)} {
+		for _, cred := range creds {
+			cred.apply(header, q)
+		}
+	}
+	return key, nil
+}
+
+// secrets returns the credentials to redact from requests made with ctx.
+func (c *Client) secrets(ctx context.Context) []Credential {
+	return append(append(c.requestCredentials(ctx), c.apiKeys.credentials()...), c.provided.list()...)
+}
+
+func (cred Credential) apply(header http.Header, q url.Values) {
+	switch cred.In {
+	case InHeader:
+		header.Set(cred.Name, cred.Value)
+	default:
+		q.Set(cred.Name, cred.Value)
+	}
+}
+
+// redacted replaces credential values in diagnostics output.
+const redacted = "REDACTED"
+
+// redactURL returns rawURL with the values of credential query parameters replaced.
+func (c *Client) redactURL(ctx context.Context, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	changed := false
+	for _, cred := range c.secrets(ctx) {
+		if cred.In == InQuery && q.Get(cred.Name) != "" {
+			q.Set(cred.Name, redacted)
+			changed = true
+		}
+	}
+	if changed {
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+// redactHeader returns a copy of header with the values of credential headers replaced.
+func (c *Client) redactHeader(ctx context.Context, header http.Header) http.Header {
+	h := header.Clone()
+	for _, cred := range c.secrets(ctx) {
+		if cred.In == InHeader && h.Get(cred.Name) != "" {
+			h.Set(cred.Name, redacted)
+		}
+	}
+	return h
+}
+
+// exportedSecrets are the headers redacted, along with the client's credentials, from requests exported to be
+// shared.
+var exportedSecrets = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// redactExport returns a copy of header with the values of credential and other secret headers replaced.
+func (c *Client) redactExport(ctx context.Context, header http.Header) http.Header {
+	h := c.redactHeader(ctx, header)
+	for _, name := range exportedSecrets {
+		if h.Get(name) != "" {
+			h.Set(name, redacted)
+		}
+	}
+	return h
+}
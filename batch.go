@@ -0,0 +1,58 @@
+package apiclient
+
+import (
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// Batch collects requests to one endpoint and runs them with bounded parallelism, sharing the client's rate limiter.
+type Batch struct {
+	client      *Client
+	config      *APIConfig
+	parallelism int
+	requests    []APIRequest
+	responses   []interface{}
+}
+
+// NewBatch returns an empty Batch of requests to config, running at most parallelism of them at once.
+func (c *Client) NewBatch(config *APIConfig, parallelism int) *Batch {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	return &Batch{client: c, config: config, parallelism: parallelism}
+}
+
+// Add queues apiReq, whose JSON response is to be decoded into resp.
+func (b *Batch) Add(apiReq APIRequest, resp interface{}) {
+	b.requests = append(b.requests, apiReq)
+	b.responses = append(b.responses, resp)
+}
+
+// Len returns the number of queued requests.
+func (b *Batch) Len() int {
+	return len(b.requests)
+}
+
+// GetJSON runs every queued request with GetJSON, and returns their errors in the order the requests were added: the
+// error of the i-th request, or nil if it succeeded, is at index i.
+func (b *Batch) GetJSON(ctx context.Context, opts ...RequestOption) []error {
+	errs := make([]error, len(b.requests))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < b.parallelism && w < len(b.requests); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				errs[i] = b.client.GetJSON(ctx, b.config, b.requests[i], b.responses[i], opts...)
+			}
+		}()
+	}
+	for i := range b.requests {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,65 @@
+package apiclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// readCloser combines a Reader with the Closer of the stream it reads from.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// closerFunc adapts a function to io.Closer.
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+// requestBody is the body of a request, opened anew for every attempt to send it.
+type requestBody struct {
+	contentType string
+	// size is the length of the body, or 0 if it is unknown.
+	size int64
+	open func() (io.ReadCloser, error)
+	// replayable is false for bodies that can be opened only once, such as uploads streamed from a caller's reader.
+	replayable bool
+	// identity is set for bodies that must be sent as is, never compressed.
+	identity bool
+}
+
+// attach opens b as the body of req, compressed by rc if it applies.
+func (b *requestBody) attach(req *http.Request, rc *requestCompression) error {
+	open, size := b.open, b.size
+	if rc.applies(b) {
+		open, size = rc.wrap(open), 0
+		req.Header.Set("Content-Encoding", rc.coding)
+	}
+	r, err := open()
+	if err != nil {
+		return err
+	}
+	req.Body = r
+	// A zero ContentLength with a body means its length is unknown, so it is sent chunked.
+	req.ContentLength = size
+	if b.replayable {
+		req.GetBody = open
+	}
+	if b.contentType != "" {
+		req.Header.Set("Content-Type", b.contentType)
+	}
+	return nil
+}
+
+// bytesBody returns a replayable body holding data.
+func bytesBody(contentType string, data []byte) *requestBody {
+	return &requestBody{
+		contentType: contentType,
+		size:        int64(len(data)),
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		},
+		replayable: true,
+	}
+}
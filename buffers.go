@@ -0,0 +1,84 @@
+package apiclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+)
+
+const (
+	copyBufferSize = 32 << 10
+	// maxPooledBuffer bounds the buffers kept for reuse, so that one huge body does not stay in memory.
+	maxPooledBuffer = 1 << 20
+)
+
+// copyBuffers holds the buffers of copyBuffer.
+var copyBuffers = sync.Pool{New: func() interface{} {
+	b := make([]byte, copyBufferSize)
+	return &b
+}}
+
+// copyBuffer is io.Copy with a pooled buffer, for streaming bodies to their destination.
+func copyBuffer(dst io.Writer, src io.Reader) (int64, error) {
+	buf := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buf)
+	return io.CopyBuffer(dst, src, *buf)
+}
+
+// readBuffers holds the buffers of readAll.
+var readBuffers = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+// readAll is io.ReadAll reading into a pooled buffer, so that the returned slice, sized to fit, is the only
+// allocation for bodies no larger than the buffers that were used before.
+func readAll(r io.Reader) ([]byte, error) {
+	buf := readBuffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			readBuffers.Put(buf)
+		}
+	}()
+	_, err := buf.ReadFrom(r)
+	return append([]byte{}, buf.Bytes()...), err
+}
+
+// WithPooledGzipReaders configures the client to reuse the gzip readers decompressing response bodies, saving their
+// sizable allocation on every response at high request rates. A reader returns to the pool when its body is closed,
+// so a body must not be read once it has been closed.
+func WithPooledGzipReaders() ClientOption {
+	return func(c *Client) error {
+		c.pooledGzip = true
+		return nil
+	}
+}
+
+var gzipReaders sync.Pool
+
+// gzipBody returns body decompressed from src, which reads from it, releasing a pooled gzip reader on Close.
+func gzipBody(body io.Closer, src io.Reader, pooled bool) (io.ReadCloser, error) {
+	if !pooled {
+		zr, err := gzip.NewReader(src)
+		if err != nil {
+			return nil, err
+		}
+		return readCloser{zr, body}, nil
+	}
+	zr, _ := gzipReaders.Get().(*gzip.Reader)
+	var err error
+	if zr != nil {
+		if err = zr.Reset(src); err != nil {
+			gzipReaders.Put(zr)
+		}
+	} else {
+		zr, err = gzip.NewReader(src)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var once sync.Once
+	return readCloser{zr, closerFunc(func() error {
+		once.Do(func() { gzipReaders.Put(zr) })
+		return body.Close()
+	})}, nil
+}
@@ -0,0 +1,88 @@
+package apiclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// benchBody returns a JSON-like body of about size bytes.
+func benchBody(size int) []byte {
+	return []byte(`[` + strings.Repeat(`{"id":1234,"name":"item"},`, size/26) + `{}]`)
+}
+
+func gzipped(data []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func BenchmarkReadAll(b *testing.B) {
+	benchmarks := []struct {
+		name    string
+		readAll func(io.Reader) ([]byte, error)
+	}{
+		{"pooled", readAll},
+		{"unpooled", io.ReadAll},
+	}
+	for _, size := range []int{1 << 10, 64 << 10} {
+		body := benchBody(size)
+		for _, bm := range benchmarks {
+			b.Run(bm.name+"/"+byteSize(size), func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(len(body)))
+				for i := 0; i < b.N; i++ {
+					if _, err := bm.readAll(bytes.NewReader(body)); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkDecompressGzip(b *testing.B) {
+	benchmarks := []struct {
+		name   string
+		pooled bool
+	}{
+		{"pooled", true},
+		{"unpooled", false},
+	}
+	for _, size := range []int{1 << 10, 64 << 10} {
+		raw := benchBody(size)
+		body := gzipped(raw)
+		for _, bm := range benchmarks {
+			b.Run(bm.name+"/"+byteSize(size), func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(len(raw)))
+				for i := 0; i < b.N; i++ {
+					resp := &http.Response{
+						Header: http.Header{"Content-Encoding": {"gzip"}},
+						Body:   io.NopCloser(bytes.NewReader(body)),
+					}
+					if err := decompress(resp, bm.pooled); err != nil {
+						b.Fatal(err)
+					}
+					if _, err := readAll(resp.Body); err != nil {
+						b.Fatal(err)
+					}
+					resp.Body.Close()
+				}
+			})
+		}
+	}
+}
+
+func byteSize(n int) string {
+	if n >= 1<<10 {
+		return strconv.Itoa(n>>10) + "KiB"
+	}
+	return strconv.Itoa(n) + "B"
+}
@@ -0,0 +1,80 @@
+package apiclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestReadAll(t *testing.T) {
+	tests := []struct {
+		name string
+		body []byte
+	}{
+		{name: "empty", body: []byte{}},
+		{name: "small", body: benchBody(100)},
+		{name: "larger than a copy buffer", body: benchBody(copyBufferSize * 2)},
+		{name: "larger than the pooled buffers", body: benchBody(maxPooledBuffer + 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Twice, so the second read may reuse the buffer of the first.
+			for i := 0; i < 2; i++ {
+				got, err := readAll(bytes.NewReader(tt.body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, tt.body) {
+					t.Fatalf("readAll returned %d bytes, want %d", len(got), len(tt.body))
+				}
+			}
+		})
+	}
+}
+
+func TestDecompressGzip(t *testing.T) {
+	body := benchBody(4 << 10)
+	tests := []struct {
+		name    string
+		pooled  bool
+		data    []byte
+		want    []byte
+		wantErr bool
+	}{
+		{name: "pooled", pooled: true, data: gzipped(body), want: body},
+		{name: "unpooled", pooled: false, data: gzipped(body), want: body},
+		{name: "pooled empty", pooled: true, data: gzipped(nil), want: []byte{}},
+		{name: "pooled invalid", pooled: true, data: []byte("not gzip"), wantErr: true},
+		{name: "unpooled invalid", pooled: false, data: []byte("not gzip"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Several times, so pooled readers are reused after being reset, including after a failed reset.
+			for i := 0; i < 3; i++ {
+				resp := &http.Response{
+					Header: http.Header{"Content-Encoding": {"gzip"}},
+					Body:   io.NopCloser(bytes.NewReader(tt.data)),
+				}
+				err := decompress(resp, tt.pooled)
+				if tt.wantErr {
+					if err == nil {
+						t.Fatal("decompress succeeded")
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := readAll(resp.Body)
+				resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, tt.want) {
+					t.Fatalf("decompressed %d bytes, want %d", len(got), len(tt.want))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package apiclient
+
+import (
+	"net/url"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// RequestBuilder builds a one-off request without defining a type for it, see NewRequest.
+type RequestBuilder struct {
+	client *Client
+	config APIConfig
+	query  url.Values
+	opts   []RequestOption
+}
+
+// NewRequest starts building a one-off request:
+//
+//	err := c.NewRequest().Path("/v1/users").Query("q", q).Header("X-Foo", "bar").Timeout(2*time.Second).GetJSON(ctx, &out)
+//
+// Requests are sent to the client's base URLs (see WithBaseURLs) if it has any, or else to the host set with Host.
+func (c *Client) NewRequest() *RequestBuilder {
+	return &RequestBuilder{client: c, query: url.Values{}}
+}
+
+// Host sets the scheme and host the request is sent to, such as "https://api.example.com".
+func (b *RequestBuilder) Host(host string) *RequestBuilder {
+	b.config.Host = host
+	return b
+}
+
+// Path sets the path of the request.
+func (b *RequestBuilder) Path(path string) *RequestBuilder {
+	b.config.Path = path
+	return b
+}
+
+// PathParam supplies the value of the {name} placeholder of the path.
+func (b *RequestBuilder) PathParam(name, value string) *RequestBuilder {
+	b.opts = append(b.opts, WithPathParam(name, value))
+	return b
+}
+
+// Query adds a query parameter.
+func (b *RequestBuilder) Query(key, value string) *RequestBuilder {
+	b.query.Add(key, value)
+	return b
+}
+
+// Header adds a request header.
+func (b *RequestBuilder) Header(key, value string) *RequestBuilder {
+	b.opts = append(b.opts, WithHeader(key, value))
+	return b
+}
+
+// Timeout bounds the call to d.
+func (b *RequestBuilder) Timeout(d time.Duration) *RequestBuilder {
+	b.config.Timeout = d
+	return b
+}
+
+// Options adds call options, such as WithPriority or WithResponseMeta.
+func (b *RequestBuilder) Options(opts ...RequestOption) *RequestBuilder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// GetJSON sends the request and decodes its JSON response into resp, see Client.GetJSON.
+func (b *RequestBuilder) GetJSON(ctx context.Context, resp interface{}) error {
+	return b.client.GetJSON(ctx, &b.config, queryParams(b.query), resp, b.opts...)
+}
+
+// GetXML sends the request and decodes its XML response into resp, see Client.GetXML.
+func (b *RequestBuilder) GetXML(ctx context.Context, resp interface{}) error {
+	return b.client.GetXML(ctx, &b.config, queryParams(b.query), resp, b.opts...)
+}
+
+// GetBinary sends the request and returns its response data, see Client.GetBinary.
+func (b *RequestBuilder) GetBinary(ctx context.Context) (BinaryResponse, error) {
+	return b.client.GetBinary(ctx, &b.config, queryParams(b.query), b.opts...)
+}
@@ -0,0 +1,121 @@
+package apiclient
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/net/context"
+)
+
+// ErrBulkheadFull is returned when a request's bulkhead has no free slot and its queue is full.
+var ErrBulkheadFull = errors.New("apiclient: bulkhead full")
+
+// EndpointMatcher selects the requests a bulkhead applies to.
+type EndpointMatcher func(method string, config *APIConfig) bool
+
+// PathPrefix matches requests whose path starts with prefix.
+func PathPrefix(prefix string) EndpointMatcher {
+	return func(method string, config *APIConfig) bool {
+		return strings.HasPrefix(config.Path, prefix)
+	}
+}
+
+// WithBulkhead isolates the requests selected by match, e.g. a slow report endpoint, so they can't take all of the
+// client's capacity: at most maxConcurrent of them are outstanding at once, and at most maxQueue more wait for a
+// slot. Requests beyond that fail with ErrBulkheadFull. A request uses the first bulkhead that matches it.
+func WithBulkhead(match EndpointMatcher, maxConcurrent, maxQueue int) ClientOption {
+	return func(c *Client) error {
+		if maxConcurrent <= 0 {
+			return errors.New("apiclient: bulkhead needs a positive max concurrency")
+		}
+		c.bulkheads = append(c.bulkheads, &bulkhead{
+			match:    match,
+			slots:    make(chan struct{}, maxConcurrent),
+			maxQueue: int32(maxQueue),
+		})
+		return nil
+	}
+}
+
+// WithNamedBulkhead adds a bulkhead named name, which isolates the calls made with WithBulkheadName or
+// ContextWithBulkhead naming it, like those matched by a bulkhead of WithBulkhead: at most maxConcurrent of them are
+// outstanding at once and at most maxQueue more wait, so that an endpoint group exhausting its bulkhead, such as a
+// slow search, cannot starve the others. Naming a bulkhead takes precedence over matching one.
+func WithNamedBulkhead(name string, maxConcurrent, maxQueue int) ClientOption {
+	return func(c *Client) error {
+		if name == "" {
+			return errors.New("apiclient: bulkhead needs a name")
+		}
+		for _, b := range c.bulkheads {
+			if b.name == name {
+				return fmt.Errorf("apiclient: bulkhead %q already exists", name)
+			}
+		}
+		if err := WithBulkhead(nil, maxConcurrent, maxQueue)(c); err != nil {
+			return err
+		}
+		c.bulkheads[len(c.bulkheads)-1].name = name
+		return nil
+	}
+}
+
+type bulkheadKey struct{}
+
+// ContextWithBulkhead returns a copy of ctx whose requests are isolated in the bulkhead named name, see
+// WithNamedBulkhead.
+func ContextWithBulkhead(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, bulkheadKey{}, name)
+}
+
+// WithBulkheadName isolates a call in the bulkhead named name, see WithNamedBulkhead.
+func WithBulkheadName(name string) RequestOption {
+	return func(o *requestOptions) {
+		o.bulkhead = name
+	}
+}
+
+type bulkhead struct {
+	// name is set for the bulkheads of WithNamedBulkhead, which have no matcher.
+	name     string
+	match    EndpointMatcher
+	slots    chan struct{}
+	maxQueue int32
+	queued   int32
+}
+
+// enterBulkhead takes a slot in the bulkhead matching the request, if any, and returns the func releasing it.
+func (c *Client) enterBulkhead(ctx context.Context, method string, config *APIConfig) (func(), error) {
+	if name, _ := ctx.Value(bulkheadKey{}).(string); name != "" {
+		for _, b := range c.bulkheads {
+			if b.name == name {
+				return b.enter(ctx)
+			}
+		}
+		return nil, fmt.Errorf("apiclient: unknown bulkhead %q", name)
+	}
+	for _, b := range c.bulkheads {
+		if b.match != nil && b.match(method, config) {
+			return b.enter(ctx)
+		}
+	}
+	return func() {}, nil
+}
+
+func (b *bulkhead) enter(ctx context.Context) (func(), error) {
+	select {
+	case b.slots <- struct{}{}:
+	default:
+		if atomic.AddInt32(&b.queued, 1) > b.maxQueue {
+			atomic.AddInt32(&b.queued, -1)
+			return nil, ErrBulkheadFull
+		}
+		release, err := acquire(ctx, b.slots)
+		atomic.AddInt32(&b.queued, -1)
+		return release, err
+	}
+	var once sync.Once
+	return func() { once.Do(func() { <-b.slots }) }, nil
+}
@@ -0,0 +1,353 @@
+package apiclient
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// CachedResponse is a response stored in a Cache.
+type CachedResponse struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte
+	// Expires is when the response stops being fresh. Caches may keep it around for longer.
+	Expires time.Time
+}
+
+// Cache stores responses for WithCache. Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse)
+	Delete(key string)
+}
+
+// TTLPolicy decides how long a response may be served from cache. A zero or negative TTL means the response is not
+// cached.
+type TTLPolicy func(resp *http.Response) time.Duration
+
+// FixedTTL caches every 2xx response for ttl.
+func FixedTTL(ttl time.Duration) TTLPolicy {
+	return func(resp *http.Response) time.Duration {
+		if resp.StatusCode/100 != 2 {
+			return 0
+		}
+		return ttl
+	}
+}
+
+// CacheControlTTL caches 2xx responses for as long as their Cache-Control max-age allows, or for fallback if the
+// header has no max-age. Responses marked no-store or no-cache are not cached.
+func CacheControlTTL(fallback time.Duration) TTLPolicy {
+	return func(resp *http.Response) time.Duration {
+		if resp.StatusCode/100 != 2 {
+			return 0
+		}
+		ttl := fallback
+		for _, directive := range strings.Split(resp.Header.Get("Cache-Control"), ",") {
+			directive = strings.ToLower(strings.TrimSpace(directive))
+			switch {
+			case directive == "no-store" || directive == "no-cache":
+				return 0
+			case strings.HasPrefix(directive, "max-age="):
+				if secs, err := strconv.Atoi(directive[len("max-age="):]); err == nil {
+					ttl = time.Duration(secs) * time.Second
+				}
+			}
+		}
+		return ttl
+	}
+}
+
+// WithCache configures the client to serve repeated GETs for the same URL and parameters from cache, for as long as
+// policy allows.
+func WithCache(cache Cache, policy TTLPolicy) ClientOption {
+	return func(c *Client) error {
+		c.cache = cache
+		c.cachePolicy = policy
+		return nil
+	}
+}
+
+// InvalidateCache removes any cached response, positive or negative, for the given request made with ctx.
+func (c *Client) InvalidateCache(ctx context.Context, config *APIConfig, apiReq APIRequest) {
+	key := c.cacheKey(c.defaultsContext(ctx), "GET", config, apiReq)
+	if c.cache != nil {
+		c.cache.Delete(key)
+	}
+	if c.negativeCache != nil {
+		c.negativeCache.delete(key)
+	}
+}
+
+// WithCacheBypass makes a single GET skip the cache, positive and negative, and go to the server. The response still
+// replaces the cached one as the cache policy allows, so the call also refreshes the cache.
+func WithCacheBypass() RequestOption {
+	return func(o *requestOptions) {
+		o.cacheBypass = true
+	}
+}
+
+type cacheBypassKey struct{}
+
+// cacheBypassed reports whether requests made with ctx skip the cache.
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// WithStaleWhileRevalidate configures the cache to keep serving a response for up to window after it went stale,
+// while a fresh copy is fetched in the background.
+func WithStaleWhileRevalidate(window time.Duration) ClientOption {
+	return func(c *Client) error {
+		c.staleWhileRevalidate = window
+		return nil
+	}
+}
+
+// WithStaleIfError configures the cache to fall back to a response that went stale less than window ago when the
+// upstream fails or answers with a 5xx status.
+func WithStaleIfError(window time.Duration) ClientOption {
+	return func(c *Client) error {
+		c.staleIfError = window
+		return nil
+	}
+}
+
+// cachedGet answers a GET from cache if possible, and otherwise sends it and caches the response as policy allows.
+func (c *Client) cachedGet(ctx context.Context, key string, config *APIConfig, apiReq APIRequest) (*http.Response, error) {
+	cached, ok := c.cache.Get(key)
+	if !ok {
+		return c.fetch(ctx, key, nil, config, apiReq)
+	}
+	now := c.clock.Now()
+	if now.Before(cached.Expires) {
+		return c.cacheHit(cached.response()), nil
+	}
+	if now.Before(cached.Expires.Add(c.staleWhileRevalidate)) {
+		c.refresh(ctx, key, cached, config, apiReq)
+		return c.cacheHit(cached.response()), nil
+	}
+	resp, err := c.fetch(ctx, key, cached, config, apiReq)
+	if c.clock.Now().Before(cached.Expires.Add(c.staleIfError)) && ctx.Err() == nil {
+		if err != nil {
+			return c.cacheHit(cached.response()), nil
+		}
+		if resp.StatusCode/100 == 5 {
+			resp.Body.Close()
+			return c.cacheHit(cached.response()), nil
+		}
+	}
+	return resp, err
+}
+
+// refresh fetches key in the background, unless a refresh of it is already running. The refresh is made with the
+// values of the caller's ctx, since key is that caller's: its credentials, impersonated subject and headers.
+func (c *Client) refresh(ctx context.Context, key string, cached *CachedResponse, config *APIConfig, apiReq APIRequest) {
+	c.cacheMu.Lock()
+	if c.refreshing == nil {
+		c.refreshing = make(map[string]bool)
+	}
+	if c.refreshing[key] {
+		c.cacheMu.Unlock()
+		return
+	}
+	c.refreshing[key] = true
+	c.cacheMu.Unlock()
+
+	ctx, cancel := c.detach(ctx, config)
+	go func() {
+		defer func() {
+			cancel()
+			c.cacheMu.Lock()
+			delete(c.refreshing, key)
+			c.cacheMu.Unlock()
+		}()
+		if resp, err := c.fetch(ctx, key, cached, config, apiReq); err == nil {
+			resp.Body.Close()
+		}
+	}()
+}
+
+// detach returns a context carrying the values of ctx but not its cancellation or deadline, for work that outlives
+// the call it was started for. The work is bounded by the timeout of config, or else the client's default timeout.
+func (c *Client) detach(ctx context.Context, config *APIConfig) (context.Context, context.CancelFunc) {
+	detached := context.Context(detachedContext{ctx})
+	timeout := config.Timeout
+	if timeout <= 0 {
+		c.callMu.RLock()
+		timeout = c.defaultTimeout
+		c.callMu.RUnlock()
+	}
+	if timeout > 0 {
+		return context.WithTimeout(detached, timeout)
+	}
+	return context.WithCancel(detached)
+}
+
+// detachedContext forwards the values of its parent, except those recording the outcome of the parent's call, which
+// the detached work must not write to once the call returned.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (d detachedContext) Value(key interface{}) interface{} {
+	switch key.(type) {
+	case metaKey, curlKey:
+		return nil
+	}
+	return d.parent.Value(key)
+}
+
+// fetch sends a GET and caches the response as policy allows. If a previously cached response carries an ETag or
+// Last-Modified validator, the request is made conditional and a 304 Not Modified answer is served from cache.
+func (c *Client) fetch(ctx context.Context, key string, cached *CachedResponse, config *APIConfig, apiReq APIRequest) (*http.Response, error) {
+	var header http.Header
+	if cached != nil {
+		header = cached.validators()
+	}
+	resp, err := c.send(ctx, "GET", config, apiReq, header, nil)
+	if err != nil {
+		return nil, err
+	}
+	if header != nil && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return c.cacheHit(c.revalidated(key, cached, resp.Header)), nil
+	}
+	ttl := c.cachePolicy(resp)
+	if ttl <= 0 || c.dryRun {
+		return resp, nil
+	}
+	body, err := readAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	cached = &CachedResponse{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       body,
+		Expires:    c.clock.Now().Add(ttl),
+	}
+	c.cache.Set(key, cached)
+	return cached.response(), nil
+}
+
+// cacheHit counts resp as answered from the cache.
+func (c *Client) cacheHit(resp *http.Response) *http.Response {
+	atomic.AddInt64(&c.stats.cacheHits, 1)
+	return resp
+}
+
+// revalidated refreshes cached with the headers of a 304 Not Modified response and returns it.
+func (c *Client) revalidated(key string, cached *CachedResponse, header http.Header) *http.Response {
+	updated := *cached
+	updated.Header = cached.Header.Clone()
+	for k, v := range header {
+		updated.Header[k] = v
+	}
+	updated.Expires = c.clock.Now()
+	if ttl := c.cachePolicy(updated.response()); ttl > 0 {
+		updated.Expires = updated.Expires.Add(ttl)
+	}
+	c.cache.Set(key, &updated)
+	return updated.response()
+}
+
+// validators returns the conditional request headers matching the validators of cr, or nil if it has none.
+func (cr *CachedResponse) validators() http.Header {
+	var h http.Header
+	if etag := cr.Header.Get("ETag"); etag != "" {
+		h = http.Header{}
+		h.Set("If-None-Match", etag)
+	}
+	if lm := cr.Header.Get("Last-Modified"); lm != "" {
+		if h == nil {
+			h = http.Header{}
+		}
+		h.Set("If-Modified-Since", lm)
+	}
+	return h
+}
+
+// response returns an *http.Response replaying cr.
+func (cr *CachedResponse) response() *http.Response {
+	return &http.Response{
+		StatusCode:    cr.StatusCode,
+		Status:        cr.Status,
+		Header:        cr.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(cr.Body)),
+		ContentLength: int64(len(cr.Body)),
+	}
+}
+
+// MemoryCache is an in-memory, least-recently-used Cache.
+type MemoryCache struct {
+	maxEntries int
+	mu         sync.Mutex
+	lru        *list.List
+	entries    map[string]*list.Element
+}
+
+type memoryCacheEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+// NewMemoryCache returns a MemoryCache holding at most maxEntries responses, or any number if maxEntries is 0.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{maxEntries: maxEntries, lru: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Get returns the response cached under key.
+func (m *MemoryCache) Get(key string) (*CachedResponse, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	m.lru.MoveToFront(e)
+	return e.Value.(*memoryCacheEntry).resp, true
+}
+
+// Set caches resp under key, evicting the least recently used response if the cache is full.
+func (m *MemoryCache) Set(key string, resp *CachedResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[key]; ok {
+		e.Value.(*memoryCacheEntry).resp = resp
+		m.lru.MoveToFront(e)
+		return
+	}
+	m.entries[key] = m.lru.PushFront(&memoryCacheEntry{key: key, resp: resp})
+	if m.maxEntries > 0 && m.lru.Len() > m.maxEntries {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
+
+// Delete removes the response cached under key.
+func (m *MemoryCache) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[key]; ok {
+		m.lru.Remove(e)
+		delete(m.entries, key)
+	}
+}
@@ -0,0 +1,129 @@
+package apiclient
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+type subjectKey struct{}
+
+func TestStaleWhileRevalidateRefreshesAsCaller(t *testing.T) {
+	var hits int32
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte(`{"tenant":"` + r.Header.Get("X-Tenant") + `","header":"` + r.Header.Get("X-Call") +
+			`","subject":"` + r.Header.Get("X-On-Behalf-Of") + `"}`))
+	})
+	tests := []struct {
+		name string
+		ctx  func(context.Context) context.Context
+		opts []RequestOption
+		want string
+	}{
+		{
+			name: "credentials",
+			ctx: func(ctx context.Context) context.Context {
+				return ContextWithCredentials(ctx, Credential{Name: "X-Tenant", Value: "acme", In: InHeader})
+			},
+			want: `"tenant":"acme"`,
+		},
+		{
+			name: "headers",
+			ctx:  func(ctx context.Context) context.Context { return ctx },
+			opts: []RequestOption{WithHeader("X-Call", "mine")},
+			want: `"header":"mine"`,
+		},
+		{
+			name: "subject",
+			ctx:  func(ctx context.Context) context.Context { return context.WithValue(ctx, subjectKey{}, "alice") },
+			want: `"subject":"alice"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&hits, 0)
+			cache := NewMemoryCache(10)
+			subject := func(ctx context.Context) string { s, _ := ctx.Value(subjectKey{}).(string); return s }
+			c := newTestClient(t, WithCache(cache, FixedTTL(10*time.Millisecond)), WithStaleWhileRevalidate(time.Hour),
+				WithImpersonation("X-On-Behalf-Of", subject, []string{"alice"}))
+			config := &APIConfig{Host: srv.URL}
+			ctx, cancel := context.WithCancel(tt.ctx(context.Background()))
+			var resp map[string]string
+			if err := c.GetJSON(ctx, config, testParams{}, &resp, tt.opts...); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(20 * time.Millisecond)
+			// The stale response is served, and the refresh must not be cancelled with the call.
+			if err := c.GetJSON(ctx, config, testParams{}, &resp, tt.opts...); err != nil {
+				t.Fatal(err)
+			}
+			cancel()
+			eventually(t, func() bool { return atomic.LoadInt32(&hits) == 2 })
+			eventually(t, func() bool {
+				c.cacheMu.Lock()
+				defer c.cacheMu.Unlock()
+				return len(c.refreshing) == 0
+			})
+			cache.mu.Lock()
+			defer cache.mu.Unlock()
+			if len(cache.entries) != 1 {
+				t.Fatalf("%d cache entries, want 1", len(cache.entries))
+			}
+			for _, e := range cache.entries {
+				if body := e.Value.(*memoryCacheEntry).resp.Body; !strings.Contains(string(body), tt.want) {
+					t.Errorf("cached %s, want it to contain %s", body, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidateCacheFindsGetJSON(t *testing.T) {
+	var hits int32
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+	tests := []struct {
+		name    string
+		options []ClientOption
+		path    string
+	}{
+		{name: "response", options: []ClientOption{WithCache(NewMemoryCache(10), FixedTTL(time.Hour))}, path: "/found"},
+		{
+			name: "response with codecs",
+			options: []ClientOption{WithCache(NewMemoryCache(10), FixedTTL(time.Hour)),
+				WithCodec("application/x-test", JSONCodec)},
+			path: "/found",
+		},
+		{name: "negative result", options: []ClientOption{WithNegativeCache(time.Hour)}, path: "/missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&hits, 0)
+			c := newTestClient(t, tt.options...)
+			config := &APIConfig{Host: srv.URL, Path: tt.path}
+			ctx := context.Background()
+			var resp interface{}
+			c.GetJSON(ctx, config, testParams{}, &resp)
+			c.GetJSON(ctx, config, testParams{}, &resp)
+			if n := atomic.LoadInt32(&hits); n != 1 {
+				t.Fatalf("server hit %d times before invalidation, want 1", n)
+			}
+			c.InvalidateCache(ctx, config, testParams{})
+			c.GetJSON(ctx, config, testParams{}, &resp)
+			if n := atomic.LoadInt32(&hits); n != 2 {
+				t.Errorf("server hit %d times after invalidation, want 2", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !apiclient_slim
+
+package apiclient
+
+import (
+	"github.com/MaTriXy/api-client/codec/cbor"
+)
+
+// CBORCodec is the Codec of application/cbor (RFC 8949). It honors cbor or else json struct tags. It is a cbor.Codec,
+// which binaries built with the apiclient_slim tag register themselves.
+var CBORCodec Codec = cbor.Codec{}
+
+// WithCBOR configures GetJSON to ask for CBOR instead of JSON, for APIs that speak application/cbor. CBOR responses
+// are decoded with CBORCodec into the same values as JSON would be; any other response is decoded as usual. Key
+// normalization does not apply to CBOR responses.
+func WithCBOR() ClientOption {
+	return func(c *Client) error {
+		c.registerCodec(cbor.MediaType, CBORCodec)
+		return nil
+	}
+}
@@ -0,0 +1,28 @@
+package apiclient
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// transcode returns body converted to UTF-8 from the charset declared by contentType. Bodies without a declared
+// charset, or declared as UTF-8, are returned unchanged.
+func transcode(body io.ReadCloser, contentType string) (io.ReadCloser, error) {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return body, nil
+	}
+	label := strings.ToLower(params["charset"])
+	if label == "" || label == "utf-8" || label == "utf8" {
+		return body, nil
+	}
+	r, err := charset.NewReaderLabel(label, body)
+	if err != nil {
+		return nil, fmt.Errorf("apiclient: unsupported response charset %q", label)
+	}
+	return readCloser{r, body}, nil
+}
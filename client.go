@@ -1,10 +1,17 @@
 package apiclient
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/json"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/net/context"
@@ -14,12 +21,110 @@ import (
 // Client may be used to make requests to the designated API. When implementing your actual API client, include
 // an instance of *Client inside your own client struct.
 type Client struct {
+	*clientState
+	// defaults are the options of WithDefaults, applied to every call before its own.
+	defaults []RequestOption
+}
+
+// clientState is the state of a client, shared with the clients derived from it by WithDefaults.
+type clientState struct {
 	httpClient        *http.Client
 	apiKeyValue       string
 	apiKeyName        string
 	baseURL           string
 	requestsPerSecond int
-	rateLimiter       chan int
+	rateLimitBurst    int
+	rateLimiter       *limiter
+	clock             Clock
+	scheduler         *scheduler
+	traceHook         TraceHook
+	requestIDHeader   string
+	xssiPrefixes      []string
+	negativeCache     *negativeCache
+	gzipSniffing      bool
+	cache             Cache
+	cachePolicy       TTLPolicy
+	// staleWhileRevalidate and staleIfError extend how long cached responses may be served after going stale.
+	staleWhileRevalidate time.Duration
+	staleIfError         time.Duration
+	cacheMu              sync.Mutex
+	refreshing           map[string]bool
+	keyNormalizer        func(string) string
+	credentials          []Credential
+	coalescer            *coalescer
+	impersonation        *impersonation
+	endpoints            *endpointSet
+	regions              *regionSet
+	discovery            ServiceResolver
+	discoveryRefresh     time.Duration
+	discovering          int32
+	limiterStore         LimiterStore
+	limiterSaveInterval  time.Duration
+	limiterSaving        int32
+	trafficShares        *[2]float64
+	shedQueue            int
+	shedWait             time.Duration
+	verifyProbe          *VerifyProbe
+	healthProbe          *HealthProbe
+	healthMu             sync.Mutex
+	health               []HealthStatus
+	healthProbing        int32
+	environmentGuard     *environmentGuard
+	inFlight             chan struct{}
+	readOnly             int32
+	diagnostics          *diagnostics
+	logHook              LogHook
+	bulkheads            []*bulkhead
+	downloadThrottle     *throttle
+	uploadThrottle       *throttle
+	decompression        bool
+	pooledGzip           bool
+	requestCompression   *requestCompression
+	maxResponseBytes     int64
+	retryPolicy          RetryPolicy
+	retryBudget          *retryBudget
+	idempotencyHeader    string
+	deadlineHeader       string
+	deadlineHintHeader   string
+	dryRun               bool
+	dryRunHandler        DryRunHandler
+	curlOnError          bool
+	curlRedact           bool
+	har                  *HARRecorder
+	urlSigner            URLSigner
+	fallbacks            map[string]fallbackFunc
+	digest               *digestAuth
+	dnsResolver          *net.Resolver
+	dnsCache             *dnsCache
+	quotaMu              sync.Mutex
+	quotas               []*quota
+	stats                *clientStats
+	apiKeys              *keyPool
+	credentialProviders  []CredentialProvider
+	provided             provided
+	decompressBinary     bool
+	codecs               []registeredCodec
+	// codecAccept is the Accept header of GetJSON, negotiating the formats of codecs.
+	codecAccept string
+	// configMu serializes runtime configuration changes, so each one can be diffed.
+	configMu sync.Mutex
+	// registry holds the endpoints registered by name.
+	registryMu sync.Mutex
+	registry   map[string]*Endpoint
+	// authMu guards the credentials that can be replaced on a live client: the API key and the token source.
+	authMu      sync.RWMutex
+	tokenSource TokenSource
+	// refill is the scheduled task refilling the rate limiter, replaced when the rate limit changes.
+	refill *task
+	// callMu guards the call settings that can be replaced on a live client: the base URL, the retry policy and the
+	// default timeout, which bounds the calls whose APIConfig and options set no timeout.
+	callMu         sync.RWMutex
+	defaultTimeout time.Duration
+	// calls tracks the outstanding calls for Shutdown and CancelAll. Shutdown tears the client down once.
+	calls        calls
+	shutdownOnce sync.Once
+	// ownTransport is set once the base transport has been cloned for the client to configure.
+	ownTransport bool
 }
 
 // ClientOption is the type of constructor options for NewClient(...).
@@ -30,7 +135,7 @@ var defaultRequestsPerSecond = 10
 
 // NewClient constructs a new Client which can make requests to the designated API.
 func NewClient(options ...ClientOption) (*Client, error) {
-	c := &Client{requestsPerSecond: defaultRequestsPerSecond}
+	c := &Client{clientState: &clientState{requestsPerSecond: defaultRequestsPerSecond, clock: systemClock{}, codecAccept: "application/json", stats: &clientStats{}}}
 	WithHTTPClient(&http.Client{})(c)
 	for _, option := range options {
 		err := option(c)
@@ -39,37 +144,39 @@ func NewClient(options ...ClientOption) (*Client, error) {
 		}
 	}
 
-	// Implement a bursty rate limiter.
-	// Allow up to 1 second worth of requests to be made at once.
-	c.rateLimiter = make(chan int, c.requestsPerSecond)
-	// Prefill rateLimiter with 1 seconds worth of requests.
-	for i := 0; i < c.requestsPerSecond; i++ {
-		c.rateLimiter <- 1
+	c.scheduler = newScheduler(c.clock, schedulerResolution)
+
+	if err := c.startRateLimiter(); err != nil {
+		return nil, err
 	}
-	go func() {
-		// Wait a second for pre-filled quota to drain
-		time.Sleep(time.Second)
-		// Then, refill rateLimiter continuously
-		for _ = range time.Tick(time.Second / time.Duration(c.requestsPerSecond)) {
-			c.rateLimiter <- 1
+	if c.discovery != nil {
+		if err := c.startDiscovery(); err != nil {
+			return nil, err
 		}
-	}()
+	}
+	if c.healthProbe != nil && c.healthProbe.Interval > 0 {
+		c.startHealthProber()
+	}
 
 	return c, nil
 }
 
-// WithHTTPClient configures a Maps API client with a http.Client to make requests over.
+// WithHTTPClient configures a Maps API client with a http.Client to make requests over. The client makes its requests
+// over a copy of c with a transport of its own, so that options configuring them, such as WithProxy, change neither c
+// nor other clients made with it.
 func WithHTTPClient(c *http.Client) ClientOption {
 	return func(client *Client) error {
-		if _, ok := c.Transport.(*transport); !ok {
-			t := c.Transport
-			if t != nil {
-				c.Transport = &transport{Base: t}
-			} else {
-				c.Transport = &transport{Base: http.DefaultTransport}
-			}
-		}
-		client.httpClient = c
+		hc := *c
+		base := hc.Transport
+		if t, ok := base.(*transport); ok {
+			base = t.Base
+		}
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		hc.Transport = &transport{Base: base}
+		client.httpClient = &hc
+		client.ownTransport = false
 		return nil
 	}
 }
@@ -84,56 +191,398 @@ func WithAPIKey(apiKeyName, apiKeyValue string) ClientOption {
 }
 
 // WithRateLimit configures the rate limit for back end requests.
-// Default is to limit to 10 requests per second.
+// Default is to limit to 10 requests per second. The rate must be between 1 and one per nanosecond.
 func WithRateLimit(requestsPerSecond int) ClientOption {
 	return func(c *Client) error {
+		if err := checkRate(requestsPerSecond); err != nil {
+			return err
+		}
 		c.requestsPerSecond = requestsPerSecond
 		return nil
 	}
 }
 
+// WithRateLimitBurst configures how many requests may be made at once when the rate limiter has been idle, which is
+// one second worth of requests by default. A burst of 1 paces requests strictly at the configured rate.
+func WithRateLimitBurst(burst int) ClientOption {
+	return func(c *Client) error {
+		if burst < 1 {
+			return fmt.Errorf("apiclient: invalid rate limit burst %d", burst)
+		}
+		c.rateLimitBurst = burst
+		return nil
+	}
+}
+
 // APIConfig configures the URL for the API endpoint
 type APIConfig struct {
 	Host string
+	// Path may contain placeholders such as {id}, replaced per call by the values of WithPathParam or of the
+	// request's PathParams.
 	Path string
+	// Timeout, if set, bounds every call to the endpoint. It can be overridden per call with WithTimeout.
+	Timeout time.Duration
 }
 
-type apiRequest interface {
+// APIRequest is a request to an API endpoint, supplying the query parameters it is sent with.
+type APIRequest interface {
 	Params() url.Values
 }
 
-func (c *Client) get(ctx context.Context, config *APIConfig, apiReq apiRequest) (*http.Response, error) {
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case <-c.rateLimiter:
-		// Execute request.
+func (c *Client) get(ctx context.Context, config *APIConfig, apiReq APIRequest) (*http.Response, error) {
+	return c.getWith(ctx, config, apiReq, c.coalescer)
+}
+
+// getStreamed is get without request coalescing, which reads whole responses into memory, for responses streamed to
+// the caller such as downloads.
+func (c *Client) getStreamed(ctx context.Context, config *APIConfig, apiReq APIRequest) (*http.Response, error) {
+	return c.getWith(ctx, config, apiReq, nil)
+}
+
+// getWith sends a GET through the negative cache, coalescer, if not nil, and cache of the client.
+func (c *Client) getWith(ctx context.Context, config *APIConfig, apiReq APIRequest, coalescer *coalescer) (*http.Response, error) {
+	var key string
+	if c.cache != nil || c.negativeCache != nil || coalescer != nil {
+		key = c.cacheKey(ctx, "GET", config, apiReq)
+	}
+	bypass := cacheBypassed(ctx)
+	if c.negativeCache != nil && !bypass {
+		if err := c.negativeCache.get(key, c.clock.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	fetch := func(ctx context.Context) (*http.Response, error) {
+		if c.cache != nil && bypass {
+			return c.fetch(ctx, key, nil, config, apiReq)
+		}
+		if c.cache != nil {
+			return c.cachedGet(ctx, key, config, apiReq)
+		}
+		return c.send(ctx, "GET", config, apiReq, nil, nil)
+	}
+	var resp *http.Response
+	var err error
+	if coalescer != nil {
+		resp, err = coalescer.do(ctx, key, func() (context.Context, context.CancelFunc) { return c.detach(ctx, config) }, fetch)
+	} else {
+		resp, err = fetch(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.negativeCache != nil && isNegative(resp.StatusCode) {
+		resp.Body.Close()
+		c.negativeCache.put(c.scheduler, key, resp, c.clock.Now())
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return resp, nil
+}
+
+// send builds a single request, adding header to the request headers and body if not nil, and performs it once the
+// rate limiter allows. When several base URLs are configured, the request fails over between them, unless its body
+// cannot be replayed; with a retry policy, it is retried likewise.
+func (c *Client) send(ctx context.Context, method string, config *APIConfig, apiReq APIRequest, header http.Header, body *requestBody) (*http.Response, error) {
+	ctx, done, err := c.calls.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkRequest(ctx, method, config, apiReq); err != nil {
+		done()
+		return nil, err
+	}
+	header = c.withIdempotencyKey(ctx, method, header)
+	var resp *http.Response
+	if policy := c.currentRetryPolicy(ctx); policy != nil && (body == nil || body.replayable) {
+		resp, err = c.retry(ctx, policy, func() (*http.Response, error) {
+			return c.sendOnce(ctx, method, config, apiReq, header, body)
+		})
+	} else {
+		resp, err = c.sendOnce(ctx, method, config, apiReq, header, body)
+	}
+	if err == nil {
+		if oerr := outageError(ctx, resp); oerr != nil {
+			resp.Body.Close()
+			resp, err = nil, oerr
+		}
+	}
+	if resp == nil {
+		done()
+		return nil, err
+	}
+	// The call is outstanding until its response body is closed.
+	resp.Body = releaseOnClose(resp.Body, done)
+	return resp, err
+}
+
+// sendDirect sends a single request to host, whatever the client's base URLs, for requests whose host is fixed such
+// as upload chunks and health probes. Like send, it is an outstanding call until its response body is closed.
+func (c *Client) sendDirect(ctx context.Context, host, method string, config *APIConfig, apiReq APIRequest, header http.Header, body *requestBody) (*http.Response, error) {
+	ctx, done, err := c.calls.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkRequest(ctx, method, config, apiReq); err != nil {
+		done()
+		return nil, err
+	}
+	resp, err := c.sendTo(ctx, host, method, config, apiReq, header, body)
+	if resp == nil {
+		done()
+		return nil, err
+	}
+	resp.Body = releaseOnClose(resp.Body, done)
+	return resp, err
+}
+
+// sendOnce sends the request of send, failing over between base URLs or regions if there are several.
+func (c *Client) sendOnce(ctx context.Context, method string, config *APIConfig, apiReq APIRequest, header http.Header, body *requestBody) (*http.Response, error) {
+	try := func(host string) (*http.Response, error) {
+		return c.sendTo(ctx, host, method, config, apiReq, header, body)
+	}
+	replayable := body == nil || body.replayable
+	switch {
+	case baseURLFromContext(ctx) != "":
+	case c.regions != nil:
+		return c.regions.do(ctx, c.clock, replayable, try)
+	case c.endpoints != nil:
+		return c.endpoints.do(ctx, c.clock, nil, replayable, try)
+	}
+	return c.sendTo(ctx, c.host(ctx, config), method, config, apiReq, header, body)
+}
+
+// checkRequest returns the error of a request the client refuses to send whatever its host, such as a write in
+// read-only mode. It is checked once per call, before picking a host, so that endpoints are never blamed for it.
+func (c *Client) checkRequest(ctx context.Context, method string, config *APIConfig, apiReq APIRequest) error {
+	if err := c.checkReadOnly(method); err != nil {
+		return err
+	}
+	if err := c.checkEnvironment(ctx, method); err != nil {
+		return err
+	}
+	if _, err := expandPath(ctx, config, apiReq); err != nil {
+		return err
+	}
+	if c.impersonation != nil {
+		if err := c.impersonation.apply(ctx, http.Header{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendTo performs a single request against host, whose call passed checkRequest.
+func (c *Client) sendTo(ctx context.Context, host, method string, config *APIConfig, apiReq APIRequest, header http.Header, body *requestBody) (*http.Response, error) {
+	path, err := expandPath(ctx, config, apiReq)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, host+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headerFromContext(ctx) {
+		req.Header[k] = append([]string(nil), v...)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	stampUserAgent(req.Header)
+	if accept := acceptFromContext(ctx); accept != "" && req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", accept)
+	}
+	if c.wantsDecompression(ctx) && req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	if c.impersonation != nil {
+		if err := c.impersonation.apply(ctx, req.Header); err != nil {
+			return nil, err
+		}
+	}
+	q := apiReq.Params()
+	key, err := c.authenticate(ctx, req.Header, q)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = q.Encode()
+	if id := RequestIDFromContext(ctx); id != "" && c.requestIDHeader != "" {
+		req.Header.Set(c.requestIDHeader, id)
 	}
 
-	host := config.Host
-	if c.baseURL != "" {
-		host = c.baseURL
+	leave, err := c.enterBulkhead(ctx, method, config)
+	if err != nil {
+		return nil, err
 	}
-	req, err := http.NewRequest("GET", host+config.Path, nil)
+	slot, err := acquire(ctx, c.inFlight)
 	if err != nil {
+		leave()
+		return nil, err
+	}
+	release := func() {
+		slot()
+		leave()
+	}
+	if err := c.takeQuota(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	if err := c.rateLimiter.wait(ctx, classOf(method), priorityFromContext(ctx)); err != nil {
+		release()
 		return nil, err
 	}
-	q := c.generateAuthQuery(config.Path, apiReq.Params())
-	req.URL.RawQuery = q
-	return ctxhttp.Do(ctx, c.httpClient, req)
+	// The body is opened last, so that nothing streams into it unless the request is actually sent.
+	if body != nil {
+		if err := body.attach(req, c.requestCompression); err != nil {
+			release()
+			return nil, err
+		}
+		req.Body = c.throttledBody(ctx, c.uploadThrottle, req.Body)
+		req.Body = countedBody{req.Body, &c.stats.bytesSent}
+	}
+	// The deadline is told last, once the request no longer waits for the limiter.
+	c.propagateDeadline(ctx, req.Header)
+	var nonce string
+	if c.digest != nil {
+		nonce = c.digest.authorize(req)
+	}
+	c.captureCurl(req)
+
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if key != nil {
+		c.observeKey(key, resp)
+	}
+	if c.digest != nil && c.answerDigest(ctx, resp, nonce, body) {
+		resp.Body.Close()
+		release()
+		return c.sendTo(context.WithValue(ctx, digestAnsweredKey{}, true), host, method, config, apiReq, header, body)
+	}
+	resp.Body = releaseOnClose(resp.Body, release)
+	return resp, nil
+}
+
+// do performs req, reporting its timings to the trace hook and its outcome to diagnostics and the HAR recorder, if
+// configured. Responses to requests asking for compression are decompressed. In dry-run mode, req is handed to the
+// dry-run handler instead.
+func (c *Client) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	countAttempt(ctx)
+	start := time.Now()
+	var resp *http.Response
+	var timings Timings
+	var err error
+	switch {
+	case c.dryRun:
+		resp = c.dryRunResponse(req)
+	case c.traceHook == nil && c.har == nil:
+		resp, err = ctxhttp.Do(ctx, c.httpClient, req)
+	default:
+		tracer := newRequestTracer()
+		resp, err = ctxhttp.Do(httptrace.WithClientTrace(ctx, tracer.clientTrace()), c.httpClient, req)
+		if timings = tracer.result(); c.traceHook != nil {
+			c.traceHook(req, timings)
+		}
+	}
+	if resp != nil {
+		c.stats.countResponse(resp.StatusCode, err, time.Since(start))
+		resp.Body = countedBody{resp.Body, &c.stats.bytesReceived}
+	} else {
+		c.stats.countResponse(0, err, time.Since(start))
+	}
+	if err == nil && req.Header.Get("Accept-Encoding") == acceptEncoding && hasBody(req.Method, resp.StatusCode) {
+		if err = decompress(resp, c.pooledGzip); err != nil {
+			resp.Body.Close()
+			resp = nil
+		}
+	}
+	if err == nil && c.maxResponseBytes > 0 {
+		if err = limitBody(resp, c.maxResponseBytes); err != nil {
+			resp = nil
+		}
+	}
+	if c.diagnostics != nil {
+		c.observe(ctx, req, start, resp, err)
+	}
+	if c.har != nil {
+		c.recordHAR(ctx, req, start, timings, resp, err)
+	}
+	return resp, err
 }
 
 // GetBinary returns JSON data from the API endpoint
-func (c *Client) GetJSON(ctx context.Context, config *APIConfig, apiReq apiRequest, resp interface{}) error {
+func (c *Client) GetJSON(ctx context.Context, config *APIConfig, apiReq APIRequest, resp interface{}, opts ...RequestOption) error {
+	o := c.newRequestOptions(opts)
+	ctx, cancel := o.context(ctx, config)
+	defer cancel()
+	ctx = c.withRequestID(defaultAccept(ctx, c.codecAccept))
 	httpResp, err := c.get(ctx, config, apiReq)
 	if err != nil {
-		return err
+		return c.requestError(ctx, err)
 	}
 	defer httpResp.Body.Close()
+	if o.meta != nil {
+		o.meta.fill(httpResp)
+	}
+	return c.requestError(ctx, c.decodeJSON(httpResp, resp, o))
+}
 
+// decodeJSON decodes the JSON body of httpResp into resp, with the call's decoder if it has one, after validating it
+// against the call's schema.
+func (c *Client) decodeJSON(httpResp *http.Response, resp interface{}, o *requestOptions) error {
+	codec := c.codecFor(httpResp.Header.Get("Content-Type"))
+	if codec != nil && o.decoder == nil && (o.schema == nil || codec != JSONCodec) {
+		return decodeWith(codec, httpResp.Body, resp)
+	}
+	body, err := c.jsonBody(httpResp)
+	if err != nil {
+		return err
+	}
+	if o.schema != nil {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		if err := o.schema.ValidateJSON(data); err != nil {
+			return err
+		}
+		body = io.NopCloser(bytes.NewReader(data))
+	}
+	httpResp.Body = body
+	if o.decoder != nil {
+		return o.decoder(httpResp, resp)
+	}
+	if c.keyNormalizer != nil {
+		return decodeNormalized(httpResp.Body, resp, c.keyNormalizer)
+	}
 	return json.NewDecoder(httpResp.Body).Decode(resp)
 }
 
+// jsonBody returns the body of a JSON response, decoded by textBody and with any configured anti-XSSI prefix removed.
+func (c *Client) jsonBody(httpResp *http.Response) (io.ReadCloser, error) {
+	body, err := c.textBody(httpResp)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.xssiPrefixes) > 0 {
+		body = stripPrefix(body, c.xssiPrefixes)
+	}
+	return body, nil
+}
+
+// textBody returns the body of a textual response, decompressed if it was sniffed as gzip and transcoded to UTF-8.
+func (c *Client) textBody(httpResp *http.Response) (io.ReadCloser, error) {
+	body := httpResp.Body
+	var err error
+	if c.gzipSniffing && httpResp.Header.Get("Content-Encoding") == "" {
+		if body, err = sniffGzip(body, c.pooledGzip); err != nil {
+			return nil, err
+		}
+	}
+	return transcode(body, httpResp.Header.Get("Content-Type"))
+}
+
 type BinaryResponse struct {
 	StatusCode  int
 	ContentType string
@@ -141,19 +590,77 @@ type BinaryResponse struct {
 }
 
 // GetBinary returns binary data from the API endpoint
-func (c *Client) GetBinary(ctx context.Context, config *APIConfig, apiReq apiRequest) (BinaryResponse, error) {
-	httpResp, err := c.get(ctx, config, apiReq)
+func (c *Client) GetBinary(ctx context.Context, config *APIConfig, apiReq APIRequest, opts ...RequestOption) (BinaryResponse, error) {
+	o := c.newRequestOptions(opts)
+	ctx, cancel := o.context(ctx, config)
+	ctx = c.withRequestID(c.binaryBody(ctx))
+	httpResp, err := c.getStreamed(ctx, config, apiReq)
 	if err != nil {
-		return BinaryResponse{}, err
+		cancel()
+		return BinaryResponse{}, c.requestError(ctx, err)
+	}
+	if o.meta != nil {
+		o.meta.fill(httpResp)
 	}
 
-	return BinaryResponse{httpResp.StatusCode, httpResp.Header.Get("Content-Type"), httpResp.Body}, nil
+	// The timeout keeps running while Data is read, until it is closed.
+	data := readCloser{c.throttled(ctx, c.downloadThrottle, httpResp.Body), closerFunc(func() error {
+		defer cancel()
+		return httpResp.Body.Close()
+	})}
+	return BinaryResponse{httpResp.StatusCode, httpResp.Header.Get("Content-Type"), data}, nil
 }
 
-func (c *Client) generateAuthQuery(path string, q url.Values) string {
-	if c.apiKeyValue != "" {
-		q.Set(c.apiKeyName, c.apiKeyValue)
-		return q.Encode()
+// host returns the host config's requests made with ctx are sent to. With several base URLs, this is the primary
+// one.
+func (c *Client) host(ctx context.Context, config *APIConfig) string {
+	if base := baseURLFromContext(ctx); base != "" {
+		return base
+	}
+	if c.regions != nil {
+		if ep, err := c.regions.lookup(regionFromContext(ctx)); err == nil {
+			return ep.base
+		}
+	}
+	if c.endpoints != nil {
+		return c.endpoints.primary()
+	}
+	if base := c.currentBaseURL(); base != "" {
+		return base
+	}
+	return config.Host
+}
+
+// cacheKey identifies a request by method, URL, parameters, requested media type and call headers. Client-wide credentials are left out, while the
+// impersonated subject and credentials carried by ctx are fingerprinted into the key so responses are never shared
+// between them.
+func (c *Client) cacheKey(ctx context.Context, method string, config *APIConfig, apiReq APIRequest) string {
+	path, err := expandPath(ctx, config, apiReq)
+	if err != nil {
+		// The request fails when it is sent.
+		path = config.Path
+	}
+	key := method + " " + c.host(ctx, config) + path + "?" + apiReq.Params().Encode()
+	// The Accept of GetJSON is left out, so that InvalidateCache, which sends none, finds its responses.
+	if accept := acceptFromContext(ctx); accept != "" && accept != c.codecAccept {
+		key += " accept " + accept
+	}
+	if h := headerFromContext(ctx); len(h) > 0 {
+		var b strings.Builder
+		h.Write(&b)
+		key += " headers " + b.String()
+	}
+	if c.impersonation != nil {
+		if subject := c.impersonation.subject(ctx); subject != "" {
+			key += " as " + subject
+		}
+	}
+	if creds, _ := ctx.Value(credentialsKey{}).([]Credential); len(creds) > 0 {
+		h := sha256.New()
+		for _, cred := range creds {
+			fmt.Fprintf(h, "%d:%q=%q;", cred.In, cred.Name, cred.Value)
+		}
+		key += fmt.Sprintf(" %x", h.Sum(nil))
 	}
-	return q.Encode()
+	return key
 }
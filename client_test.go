@@ -0,0 +1,44 @@
+package apiclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// testParams is an APIRequest sending its values as the query.
+type testParams url.Values
+
+func (p testParams) Params() url.Values {
+	return url.Values(p)
+}
+
+// newTestClient returns a client with a rate limit high enough not to slow tests down.
+func newTestClient(t *testing.T, options ...ClientOption) *Client {
+	t.Helper()
+	c, err := NewClient(append([]ClientOption{WithRateLimit(1000)}, options...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// newTestServer starts a server with handler, closed when the test ends.
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// eventually fails the test if cond does not hold within a second.
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+	}
+}
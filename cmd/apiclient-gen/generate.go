@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// methods are the operations of a path item, in the order they are generated.
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// initialisms are the words spelled in upper case in Go names.
+var initialisms = map[string]bool{"ID": true, "URL": true, "URI": true, "API": true, "HTTP": true, "JSON": true, "XML": true, "UUID": true, "IP": true}
+
+// generator accumulates the declarations of the generated file.
+type generator struct {
+	spec    *spec
+	imports map[string]bool
+	// types holds the declarations of the response and component types, and defined their names.
+	types   bytes.Buffer
+	defined map[string]bool
+	skipped []string
+}
+
+// generate returns the source of the client for s, in package pkg.
+func generate(s *spec, pkg, source string) ([]byte, error) {
+	g := &generator{spec: s, imports: map[string]bool{}, defined: map[string]bool{}}
+	host, prefix := s.location()
+
+	var ops bytes.Buffer
+	paths := make([]string, 0, len(s.Paths))
+	for path := range s.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		item := s.Paths[path]
+		var shared []*parameter
+		if raw, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(raw, &shared); err != nil {
+				return nil, fmt.Errorf("parameters of %s: %w", path, err)
+			}
+		}
+		for _, method := range methods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+			var op operation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+			if err := g.operation(&ops, method, path, prefix, shared, &op); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	names := make([]string, 0, len(s.schemas()))
+	for name := range s.schemas() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.component(name, s.schemas()[name])
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by apiclient-gen from %s; DO NOT EDIT.\n\npackage %s\n\nimport (\n", source, pkg)
+	for _, imp := range []string{"fmt", "net/url", "time"} {
+		if g.imports[imp] || imp == "net/url" {
+			fmt.Fprintf(&b, "%q\n", imp)
+		}
+	}
+	b.WriteString("\napiclient \"github.com/MaTriXy/api-client\"\n\"golang.org/x/net/context\"\n)\n\n")
+	if len(g.skipped) > 0 {
+		b.WriteString("// Operations without a generated method, as the client has no generic call for them:\n//\n")
+		for _, op := range g.skipped {
+			fmt.Fprintf(&b, "//   - %s\n", op)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("// DefaultHost is where the API is served according to its document. If it is empty, configure the client\n")
+	b.WriteString("// WithBaseURLs.\n")
+	fmt.Fprintf(&b, "const DefaultHost = %q\n\n", host)
+	title := s.Info.Title
+	if title == "" {
+		title = "the"
+	}
+	fmt.Fprintf(&b, "// Client is a client of the %s API.\ntype Client struct {\n*apiclient.Client\n}\n\n", title)
+	b.WriteString("// NewClient returns a Client configured with options.\n")
+	b.WriteString("func NewClient(options ...apiclient.ClientOption) (*Client, error) {\n")
+	b.WriteString("c, err := apiclient.NewClient(options...)\nif err != nil {\nreturn nil, err\n}\nreturn &Client{c}, nil\n}\n\n")
+	b.Write(ops.Bytes())
+	b.Write(g.types.Bytes())
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return b.Bytes(), fmt.Errorf("formatting generated code: %w", err)
+	}
+	return src, nil
+}
+
+// location returns the scheme and host the API is served from, and the prefix of its paths.
+func (s *spec) location() (host, prefix string) {
+	raw := ""
+	if len(s.Servers) > 0 {
+		raw = s.Servers[0].URL
+	} else if s.Host != "" {
+		scheme := "https"
+		if len(s.Schemes) > 0 {
+			scheme = s.Schemes[0]
+		}
+		raw = scheme + "://" + s.Host + s.BasePath
+	} else {
+		raw = s.BasePath
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", ""
+	}
+	if u.Scheme != "" && u.Host != "" {
+		host = u.Scheme + "://" + u.Host
+	}
+	return host, strings.TrimSuffix(u.Path, "/")
+}
+
+// operation generates the request type, config and method of op, or records why it is skipped.
+func (g *generator) operation(b *bytes.Buffer, method, path, prefix string, shared []*parameter, op *operation) error {
+	desc := strings.ToUpper(method) + " " + path
+	name := goName(op.OperationID)
+	if op.OperationID == "" {
+		name = goName(method + " " + path)
+	} else {
+		desc += " (" + op.OperationID + ")"
+	}
+	if method != "get" {
+		g.skipped = append(g.skipped, desc)
+		return nil
+	}
+	resp := g.successResponse(op)
+	var respSchema *schema
+	if resp != nil {
+		respSchema = resp.jsonSchema()
+	}
+	if respSchema == nil {
+		g.skipped = append(g.skipped, desc+", which has no JSON response")
+		return nil
+	}
+
+	params := g.parameters(shared, op.Parameters)
+	reqName := name + "Request"
+	fmt.Fprintf(b, "// %s holds the parameters of %s.\ntype %s struct {\n", reqName, name, reqName)
+	var pathParams []*parameter
+	for _, p := range params {
+		typ := g.goType(p.valueSchema(), reqName+goName(p.Name))
+		tag := p.Name
+		switch {
+		case p.In == "path":
+			tag = "-"
+			pathParams = append(pathParams, p)
+		case !p.Required:
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(b, "%s %s `url:%q`\n", goName(p.Name), typ, tag)
+	}
+	b.WriteString("}\n\n")
+	fmt.Fprintf(b, "// Params returns the query parameters of the request.\nfunc (r *%s) Params() url.Values {\n", reqName)
+	b.WriteString("return apiclient.StructRequest{V: r}.Params()\n}\n\n")
+	if len(pathParams) > 0 {
+		fmt.Fprintf(b, "// PathParams returns the values of the placeholders of the request's path.\n")
+		fmt.Fprintf(b, "func (r *%s) PathParams() map[string]string {\nreturn map[string]string{\n", reqName)
+		for _, p := range pathParams {
+			value := "r." + goName(p.Name)
+			if p.valueSchema().Type != "string" || p.valueSchema().Format == "date-time" {
+				value = "fmt.Sprint(" + value + ")"
+				g.imports["fmt"] = true
+			}
+			fmt.Fprintf(b, "%q: %s,\n", p.Name, value)
+		}
+		b.WriteString("}\n}\n\n")
+	}
+
+	respType := g.goType(respSchema, name+"Response")
+	configName := lowerFirst(name) + "Config"
+	fmt.Fprintf(b, "var %s = &apiclient.APIConfig{Host: DefaultHost, Path: %q}\n\n", configName, prefix+path)
+	fmt.Fprintf(b, "// %s sends %s.\n", name, strings.ToUpper(method)+" "+path)
+	if op.Summary != "" {
+		fmt.Fprintf(b, "//\n// %s\n", strings.TrimSpace(op.Summary))
+	}
+	if g.isObject(respSchema) {
+		fmt.Fprintf(b, "func (c *Client) %s(ctx context.Context, req *%s, opts ...apiclient.RequestOption) (*%s, error) {\n", name, reqName, respType)
+		fmt.Fprintf(b, "var resp %s\nif err := c.GetJSON(ctx, %s, req, &resp, opts...); err != nil {\nreturn nil, err\n}\nreturn &resp, nil\n}\n\n", respType, configName)
+	} else {
+		fmt.Fprintf(b, "func (c *Client) %s(ctx context.Context, req *%s, opts ...apiclient.RequestOption) (%s, error) {\n", name, reqName, respType)
+		fmt.Fprintf(b, "var resp %s\nerr := c.GetJSON(ctx, %s, req, &resp, opts...)\nreturn resp, err\n}\n\n", respType, configName)
+	}
+	return nil
+}
+
+// successResponse returns the response of op on success: 200, or else its first 2xx or default response.
+func (g *generator) successResponse(op *operation) *response {
+	if r := op.Responses["200"]; r != nil {
+		return g.spec.response(r)
+	}
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			return g.spec.response(op.Responses[code])
+		}
+	}
+	if r := op.Responses["default"]; r != nil {
+		return g.spec.response(r)
+	}
+	return nil
+}
+
+// parameters returns the query and path parameters of an operation, those of the operation overriding those shared
+// by its path.
+func (g *generator) parameters(shared, own []*parameter) []*parameter {
+	var params []*parameter
+	index := map[string]int{}
+	for _, p := range append(append([]*parameter(nil), shared...), own...) {
+		p = g.spec.parameter(p)
+		if p.In != "query" && p.In != "path" {
+			continue
+		}
+		key := p.In + " " + p.Name
+		if i, ok := index[key]; ok {
+			params[i] = p
+			continue
+		}
+		index[key] = len(params)
+		params = append(params, p)
+	}
+	return params
+}
+
+// valueSchema returns the schema of the parameter's value.
+func (p *parameter) valueSchema() *schema {
+	if p.Schema != nil {
+		return p.Schema
+	}
+	return &schema{Type: p.Type, Format: p.Format, Items: p.Items}
+}
+
+// component declares the named schema name.
+func (g *generator) component(name string, sc *schema) {
+	typeName := goName(name)
+	if g.defined[typeName] {
+		return
+	}
+	if g.isObject(sc) {
+		g.defineStruct(typeName, sc)
+		return
+	}
+	g.defined[typeName] = true
+	typ := g.goType(sc, typeName+"Item")
+	if sc.Description != "" {
+		fmt.Fprintf(&g.types, "// %s\n", comment(sc.Description))
+	}
+	fmt.Fprintf(&g.types, "type %s %s\n\n", typeName, typ)
+}
+
+// isObject reports whether sc is generated as a struct.
+func (g *generator) isObject(sc *schema) bool {
+	if sc.Ref != "" {
+		if named := g.spec.schemas()[refName(sc.Ref)]; named != nil {
+			return g.isObject(named)
+		}
+		return false
+	}
+	return len(sc.AllOf) > 0 || len(sc.Properties) > 0
+}
+
+// goType returns the Go type of values of sc, declaring the types it needs under names derived from hint.
+func (g *generator) goType(sc *schema, hint string) string {
+	if sc == nil {
+		return "interface{}"
+	}
+	if sc.Ref != "" {
+		return goName(refName(sc.Ref))
+	}
+	if g.isObject(sc) {
+		g.defineStruct(hint, sc)
+		return hint
+	}
+	switch sc.Type {
+	case "string":
+		if sc.Format == "date-time" {
+			g.imports["time"] = true
+			return "time.Time"
+		}
+		return "string"
+	case "integer":
+		if sc.Format == "int32" {
+			return "int32"
+		}
+		return "int64"
+	case "number":
+		if sc.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.goType(sc.Items, hint+"Item")
+	case "object":
+		var additional schema
+		if len(sc.AdditionalProperties) > 0 && json.Unmarshal(sc.AdditionalProperties, &additional) == nil {
+			return "map[string]" + g.goType(&additional, hint+"Value")
+		}
+		return "map[string]interface{}"
+	}
+	return "interface{}"
+}
+
+// defineStruct declares the struct name for the properties of sc, including those of the schemas it is composed of.
+func (g *generator) defineStruct(name string, sc *schema) {
+	if g.defined[name] {
+		return
+	}
+	g.defined[name] = true
+	props := map[string]*schema{}
+	required := map[string]bool{}
+	g.collect(sc, props, required)
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	if sc.Description != "" {
+		fmt.Fprintf(&b, "// %s\n", comment(sc.Description))
+	}
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+	for _, k := range keys {
+		prop := props[k]
+		typ := g.goType(prop, name+goName(k))
+		// Referenced structs are pointers, so that the types may refer to each other recursively.
+		if prop.Ref != "" && g.isObject(prop) {
+			typ = "*" + typ
+		}
+		tag := k
+		if !required[k] {
+			tag += ",omitempty"
+		}
+		if prop.Description != "" {
+			fmt.Fprintf(&b, "// %s\n", comment(prop.Description))
+		}
+		fmt.Fprintf(&b, "%s %s `json:%q`\n", goName(k), typ, tag)
+	}
+	b.WriteString("}\n\n")
+	g.types.Write(b.Bytes())
+}
+
+// collect adds the properties of sc, and of the schemas it is composed of, to props.
+func (g *generator) collect(sc *schema, props map[string]*schema, required map[string]bool) {
+	if sc.Ref != "" {
+		if named := g.spec.schemas()[refName(sc.Ref)]; named != nil {
+			g.collect(named, props, required)
+		}
+		return
+	}
+	for _, part := range sc.AllOf {
+		g.collect(part, props, required)
+	}
+	for k, v := range sc.Properties {
+		props[k] = v
+	}
+	for _, k := range sc.Required {
+		required[k] = true
+	}
+}
+
+// goName returns the exported Go name for s, such as PetID for "pet_id" or "petId".
+func goName(s string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+	var b strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		rs := []rune(w)
+		b.WriteRune(unicode.ToUpper(rs[0]))
+		b.WriteString(string(rs[1:]))
+	}
+	name := b.String()
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
+
+func lowerFirst(s string) string {
+	rs := []rune(s)
+	i := 0
+	for i < len(rs) && unicode.IsUpper(rs[i]) && (i == 0 || i+1 == len(rs) || unicode.IsUpper(rs[i+1])) {
+		rs[i] = unicode.ToLower(rs[i])
+		i++
+	}
+	return string(rs)
+}
+
+// comment returns a description from the document as the text of a single line comment.
+func comment(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
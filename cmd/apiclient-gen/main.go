@@ -0,0 +1,53 @@
+// Command apiclient-gen generates a typed client for an API from its OpenAPI 3 or Swagger 2 document, in JSON.
+//
+// For every GET operation with a JSON response it emits a request struct, whose query parameters are encoded with
+// apiclient.EncodeQuery and whose path parameters fill the placeholders of the operation's path, the response types
+// and a method on a generated Client embedding *apiclient.Client. Component schemas become named types. Header
+// parameters are left to call options such as apiclient.WithHeader. It is meant to be run by go generate:
+//
+//	//go:generate go run github.com/MaTriXy/api-client/cmd/apiclient-gen -spec openapi.json -package petstore -o client_gen.go
+//
+// Operations the client has no generic call for, such as POSTs, are listed in a comment of the generated file.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	specPath := flag.String("spec", "", "path of the OpenAPI or Swagger document, in JSON")
+	pkg := flag.String("package", "", "package name of the generated code")
+	out := flag.String("o", "", "path of the generated file (default standard output)")
+	flag.Parse()
+	if *specPath == "" || *pkg == "" {
+		fmt.Fprintln(os.Stderr, "usage: apiclient-gen -spec openapi.json -package name [-o file.go]")
+		os.Exit(2)
+	}
+	if err := run(*specPath, *pkg, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "apiclient-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(specPath, pkg, out string) error {
+	data, err := os.ReadFile(specPath)
+	if err != nil {
+		return err
+	}
+	var s spec
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("%s: %w", specPath, err)
+	}
+	src, err := generate(&s, pkg, specPath)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
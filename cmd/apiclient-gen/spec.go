@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// spec holds the parts of an OpenAPI 3 or Swagger 2 document the generator uses.
+type spec struct {
+	Info struct {
+		Title string `json:"title"`
+	} `json:"info"`
+	Servers []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	// Host, BasePath and Schemes locate a Swagger 2 API.
+	Host       string                                `json:"host"`
+	BasePath   string                                `json:"basePath"`
+	Schemes    []string                              `json:"schemes"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas    map[string]*schema    `json:"schemas"`
+		Parameters map[string]*parameter `json:"parameters"`
+		Responses  map[string]*response  `json:"responses"`
+	} `json:"components"`
+	Definitions map[string]*schema    `json:"definitions"`
+	Parameters  map[string]*parameter `json:"parameters"`
+	Responses   map[string]*response  `json:"responses"`
+}
+
+type operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Parameters  []*parameter         `json:"parameters"`
+	Responses   map[string]*response `json:"responses"`
+}
+
+type parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *schema `json:"schema"`
+	// Type, Format and Items describe a Swagger 2 parameter.
+	Type   schemaType `json:"type"`
+	Format string     `json:"format"`
+	Items  *schema    `json:"items"`
+}
+
+type response struct {
+	Ref     string `json:"$ref"`
+	Content map[string]struct {
+		Schema *schema `json:"schema"`
+	} `json:"content"`
+	// Schema describes the body of a Swagger 2 response.
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 schemaType         `json:"type"`
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+	Items                *schema            `json:"items"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	AllOf                []*schema          `json:"allOf"`
+}
+
+// schemaType is the type of a schema, given either as a string or, in OpenAPI 3.1, as a list of types that may
+// include "null".
+type schemaType string
+
+func (t *schemaType) UnmarshalJSON(data []byte) error {
+	var types []string
+	if err := json.Unmarshal(data, &types); err != nil {
+		return json.Unmarshal(data, (*string)(t))
+	}
+	for _, typ := range types {
+		if typ != "null" {
+			*t = schemaType(typ)
+		}
+	}
+	return nil
+}
+
+// refName returns the name of the component a reference such as "#/components/schemas/Pet" points to.
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// schemas returns the named schemas of the document.
+func (s *spec) schemas() map[string]*schema {
+	if len(s.Components.Schemas) > 0 {
+		return s.Components.Schemas
+	}
+	return s.Definitions
+}
+
+// parameter resolves a reference to a shared parameter.
+func (s *spec) parameter(p *parameter) *parameter {
+	if p.Ref == "" {
+		return p
+	}
+	if shared := s.Components.Parameters[refName(p.Ref)]; shared != nil {
+		return shared
+	}
+	if shared := s.Parameters[refName(p.Ref)]; shared != nil {
+		return shared
+	}
+	return p
+}
+
+// response resolves a reference to a shared response.
+func (s *spec) response(r *response) *response {
+	if r.Ref == "" {
+		return r
+	}
+	if shared := s.Components.Responses[refName(r.Ref)]; shared != nil {
+		return shared
+	}
+	if shared := s.Responses[refName(r.Ref)]; shared != nil {
+		return shared
+	}
+	return r
+}
+
+// jsonSchema returns the schema of the JSON body of r, or nil if it has none.
+func (r *response) jsonSchema() *schema {
+	if r.Schema != nil {
+		return r.Schema
+	}
+	for mediaType, content := range r.Content {
+		if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+			return content.Schema
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package apiclient
+
+import (
+	"net/http"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// WithRequestCoalescing configures the client to merge identical GETs that are in flight at the same time into a
+// single upstream call, whose response is shared by every caller. Binary and download responses, which are streamed
+// to the caller, are not coalesced.
+func WithRequestCoalescing() ClientOption {
+	return func(c *Client) error {
+		c.coalescer = &coalescer{calls: make(map[string]*coalescedCall)}
+		return nil
+	}
+}
+
+// coalescer is a singleflight group of in-flight requests, keyed by cacheKey.
+type coalescer struct {
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+type coalescedCall struct {
+	done chan struct{}
+	resp *CachedResponse
+	err  error
+	// waiters counts the callers waiting for the call, which is cancelled if they all give up.
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// do calls fn unless a call for key is already in flight, in which case it waits for that call's response instead.
+// fn runs with the context returned by detach, which carries the values of the first caller's context but not its
+// cancellation, so that a caller giving up does not fail the others; it is cancelled once every caller gave up.
+// Every caller receives its own copy of the response.
+func (g *coalescer) do(ctx context.Context, key string, detach func() (context.Context, context.CancelFunc), fn func(context.Context) (*http.Response, error)) (*http.Response, error) {
+	g.mu.Lock()
+	call, ok := g.calls[key]
+	if !ok {
+		callCtx, cancel := detach()
+		call = &coalescedCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = call
+		go func() {
+			call.resp, call.err = snapshot(fn(callCtx))
+			g.mu.Lock()
+			g.forget(key, call)
+			g.mu.Unlock()
+			cancel()
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		g.mu.Lock()
+		if call.waiters--; call.waiters == 0 {
+			g.forget(key, call)
+			call.cancel()
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+	if call.err != nil {
+		return nil, call.err
+	}
+	return call.resp.response(), nil
+}
+
+// forget removes call from the calls in flight, so later callers start a call of their own.
+func (g *coalescer) forget(key string, call *coalescedCall) {
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+}
+
+// snapshot reads resp into a CachedResponse that can be replayed any number of times.
+func snapshot(resp *http.Response, err error) (*CachedResponse, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := readAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &CachedResponse{StatusCode: resp.StatusCode, Status: resp.Status, Header: resp.Header, Body: body}, nil
+}
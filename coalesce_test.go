@@ -0,0 +1,198 @@
+package apiclient
+
+import (
+	"io"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// waiters returns how many callers wait for the coalesced call in flight, if any.
+func (g *coalescer) waiters() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	total := 0
+	for _, call := range g.calls {
+		total += call.waiters
+	}
+	return total
+}
+
+func TestCoalescingSurvivesCancelledCaller(t *testing.T) {
+	tests := []struct {
+		name   string
+		cancel int // index of the caller giving up; the first one leads the call
+	}{
+		{name: "leader", cancel: 0},
+		{name: "follower", cancel: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int32
+			release := make(chan struct{})
+			srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&hits, 1)
+				<-release
+				w.Write([]byte(`{"ok":true}`))
+			})
+			c := newTestClient(t, WithRequestCoalescing())
+			config := &APIConfig{Host: srv.URL}
+
+			var cancels [2]context.CancelFunc
+			var errs [2]chan error
+			for i := range errs {
+				var ctx context.Context
+				ctx, cancels[i] = context.WithCancel(context.Background())
+				defer cancels[i]()
+				errs[i] = make(chan error, 1)
+				go func(i int) {
+					var resp struct{ OK bool }
+					err := c.GetJSON(ctx, config, testParams{}, &resp)
+					if err == nil && !resp.OK {
+						err = io.ErrUnexpectedEOF
+					}
+					errs[i] <- err
+				}(i)
+				n := i + 1
+				eventually(t, func() bool { return c.coalescer.waiters() == n })
+			}
+
+			cancels[tt.cancel]()
+			if err := <-errs[tt.cancel]; err == nil {
+				t.Error("cancelled caller succeeded")
+			}
+			close(release)
+			if err := <-errs[1-tt.cancel]; err != nil {
+				t.Errorf("other caller failed: %v", err)
+			}
+			if n := atomic.LoadInt32(&hits); n != 1 {
+				t.Errorf("server hit %d times, want 1", n)
+			}
+		})
+	}
+}
+
+func TestCoalescingCancelsAbandonedCall(t *testing.T) {
+	tests := []struct {
+		name    string
+		callers int
+	}{
+		{name: "single caller", callers: 1},
+		{name: "several callers", callers: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aborted := make(chan struct{}, 1)
+			srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+				aborted <- struct{}{}
+			})
+			c := newTestClient(t, WithRequestCoalescing())
+			config := &APIConfig{Host: srv.URL}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			errs := make(chan error, tt.callers)
+			for i := 0; i < tt.callers; i++ {
+				go func() {
+					var resp interface{}
+					errs <- c.GetJSON(ctx, config, testParams{}, &resp)
+				}()
+			}
+			eventually(t, func() bool { return c.coalescer.waiters() == tt.callers })
+			cancel()
+			for i := 0; i < tt.callers; i++ {
+				if err := <-errs; err == nil {
+					t.Error("cancelled caller succeeded")
+				}
+			}
+			<-aborted
+			eventually(t, func() bool { return c.coalescer.waiters() == 0 })
+		})
+	}
+}
+
+func TestStreamedCallsNotCoalesced(t *testing.T) {
+	tests := []struct {
+		name string
+		// stream calls c and returns once the first chunk of the response arrived, with a func finishing the call.
+		stream func(c *Client, config *APIConfig) (finish func() error, err error)
+	}{
+		{
+			name: "GetBinary",
+			stream: func(c *Client, config *APIConfig) (func() error, error) {
+				resp, err := c.GetBinary(context.Background(), config, testParams{})
+				if err != nil {
+					return nil, err
+				}
+				if _, err := io.ReadFull(resp.Data, make([]byte, 5)); err != nil {
+					return nil, err
+				}
+				return func() error {
+					defer resp.Data.Close()
+					_, err := io.ReadAll(resp.Data)
+					return err
+				}, nil
+			},
+		},
+		{
+			name: "DownloadFile",
+			stream: func(c *Client, config *APIConfig) (func() error, error) {
+				started := make(chan struct{})
+				done := make(chan error, 1)
+				var once int32
+				path := filepath.Join(t.TempDir(), "file")
+				go func() {
+					done <- c.DownloadFile(context.Background(), config, testParams{}, path, func(Progress) {
+						if atomic.CompareAndSwapInt32(&once, 0, 1) {
+							close(started)
+						}
+					})
+				}()
+				select {
+				case <-started:
+					return func() error { return <-done }, nil
+				case err := <-done:
+					return nil, err
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int32
+			release := make(chan struct{})
+			srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&hits, 1)
+				w.Write([]byte("first"))
+				w.(http.Flusher).Flush()
+				<-release
+				w.Write([]byte("second"))
+			})
+			c := newTestClient(t, WithRequestCoalescing())
+			config := &APIConfig{Host: srv.URL}
+
+			// Both calls stream their first chunk while the server holds back the rest, which a coalesced call,
+			// reading the whole response before returning, could not.
+			var finish []func() error
+			for i := 0; i < 2; i++ {
+				f, err := tt.stream(c, config)
+				if err != nil {
+					t.Fatal(err)
+				}
+				finish = append(finish, f)
+			}
+			close(release)
+			for _, f := range finish {
+				if err := f(); err != nil {
+					t.Error(err)
+				}
+			}
+			if n := atomic.LoadInt32(&hits); n != 2 {
+				t.Errorf("server hit %d times, want 2", n)
+			}
+		})
+	}
+}
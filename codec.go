@@ -0,0 +1,106 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"strings"
+)
+
+// Codec marshals and unmarshals values in one media type.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec is the Codec of application/json, using encoding/json.
+var JSONCodec Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// WithCodec registers codec for responses of mediaType, such as a vendor's application/vnd.example+json variant.
+// GetJSON lists the registered media types in its Accept header, in order of registration and ahead of JSON, then
+// inspects the Content-Type of each response and decodes it with the codec registered for its media type.
+// Responses of application/json, of any other +json type without a codec of their own, or of an unknown type are
+// decoded as JSON, with key normalization and XSSI prefix stripping if configured; registering a codec for
+// application/json replaces that. Registering a media type again replaces its codec.
+func WithCodec(mediaType string, codec Codec) ClientOption {
+	return func(c *Client) error {
+		mediaType = strings.ToLower(mediaType)
+		if mediaType == "" || codec == nil {
+			return errors.New("apiclient: codec needs a media type")
+		}
+		c.registerCodec(mediaType, codec)
+		return nil
+	}
+}
+
+// registeredCodec is a codec and the media type it is registered for.
+type registeredCodec struct {
+	mediaType string
+	codec     Codec
+}
+
+// registerCodec adds codec for mediaType, after previously registered ones, or replaces the codec of mediaType.
+func (c *Client) registerCodec(mediaType string, codec Codec) {
+	for i := range c.codecs {
+		if c.codecs[i].mediaType == mediaType {
+			c.codecs[i].codec = codec
+			return
+		}
+	}
+	c.codecs = append(c.codecs, registeredCodec{mediaType: mediaType, codec: codec})
+	c.codecAccept = c.negotiate()
+}
+
+// negotiate returns the Accept header GetJSON sends: the registered media types in order of registration, followed
+// by JSON at a lower quality unless a codec is registered for it.
+func (c *Client) negotiate() string {
+	accept := make([]string, 0, len(c.codecs)+1)
+	json := false
+	for _, rc := range c.codecs {
+		accept = append(accept, rc.mediaType)
+		json = json || rc.mediaType == "application/json"
+	}
+	switch {
+	case len(accept) == 0:
+		return "application/json"
+	case !json:
+		accept = append(accept, "application/json;q=0.9")
+	}
+	return strings.Join(accept, ", ")
+}
+
+// codecFor returns the codec registered for the media type of contentType, or nil if responses of that type are
+// decoded as JSON.
+func (c *Client) codecFor(contentType string) Codec {
+	if len(c.codecs) == 0 {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+	for _, rc := range c.codecs {
+		if rc.mediaType == mediaType {
+			return rc.codec
+		}
+	}
+	if strings.HasSuffix(mediaType, "+json") {
+		return c.codecFor("application/json")
+	}
+	return nil
+}
+
+// decodeWith reads all of r and unmarshals it into v with codec.
+func decodeWith(codec Codec, r io.Reader, v interface{}) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(data, v)
+}
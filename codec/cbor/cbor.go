@@ -0,0 +1,28 @@
+// Package cbor provides the Codec of application/cbor (RFC 8949), to be registered on a client with
+// apiclient.WithCodec:
+//
+//	c, err := apiclient.NewClient(apiclient.WithCodec(cbor.MediaType, cbor.Codec{}))
+//
+// Binaries built with the apiclient_slim tag leave apiclient.WithCBOR out, and only link the CBOR library if they
+// import this package.
+package cbor
+
+import (
+	fxcbor "github.com/fxamacker/cbor/v2"
+)
+
+// MediaType is the media type of CBOR.
+const MediaType = "application/cbor"
+
+// Codec marshals and unmarshals CBOR, implementing apiclient.Codec. It honors cbor or else json struct tags, and
+// encodes deterministically as defined in RFC 8949 section 4.2.1: shortest forms and sorted map keys, so equal values
+// encode to equal bytes.
+type Codec struct{}
+
+var encMode, _ = fxcbor.CoreDetEncOptions().EncMode()
+
+// Marshal returns the CBOR encoding of v.
+func (Codec) Marshal(v interface{}) ([]byte, error) { return encMode.Marshal(v) }
+
+// Unmarshal decodes the CBOR data into v.
+func (Codec) Unmarshal(data []byte, v interface{}) error { return fxcbor.Unmarshal(data, v) }
@@ -0,0 +1,167 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func bigInt(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 10)
+	return n
+}
+
+// TestCodec checks the examples of RFC 8949 Appendix A that have a Go equivalent.
+func TestCodec(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		cbor  string
+		// decodeOnly marks encodings that decode to value but are not how it encodes, such as indefinite lengths.
+		decodeOnly bool
+	}{
+		{value: uint64(0), cbor: "00"},
+		{value: uint64(1), cbor: "01"},
+		{value: uint64(10), cbor: "0a"},
+		{value: uint64(23), cbor: "17"},
+		{value: uint64(24), cbor: "1818"},
+		{value: uint64(25), cbor: "1819"},
+		{value: uint64(100), cbor: "1864"},
+		{value: uint64(1000), cbor: "1903e8"},
+		{value: uint64(1000000), cbor: "1a000f4240"},
+		{value: uint64(1000000000000), cbor: "1b000000e8d4a51000"},
+		{value: uint64(18446744073709551615), cbor: "1bffffffffffffffff"},
+		{value: bigInt("18446744073709551616"), cbor: "c249010000000000000000"},
+		{value: bigInt("-18446744073709551616"), cbor: "3bffffffffffffffff"},
+		{value: bigInt("-18446744073709551617"), cbor: "c349010000000000000000"},
+		{value: int64(-1), cbor: "20"},
+		{value: int64(-10), cbor: "29"},
+		{value: int64(-100), cbor: "3863"},
+		{value: int64(-1000), cbor: "3903e7"},
+		{value: 0.0, cbor: "f90000"},
+		{value: math.Copysign(0, -1), cbor: "f98000"},
+		{value: 1.0, cbor: "f93c00"},
+		{value: 1.1, cbor: "fb3ff199999999999a"},
+		{value: 1.5, cbor: "f93e00"},
+		{value: 65504.0, cbor: "f97bff"},
+		{value: 100000.0, cbor: "fa47c35000"},
+		{value: 3.4028234663852886e+38, cbor: "fa7f7fffff"},
+		{value: 1.0e+300, cbor: "fb7e37e43c8800759c"},
+		{value: 5.960464477539063e-8, cbor: "f90001"},
+		{value: 0.00006103515625, cbor: "f90400"},
+		{value: -4.0, cbor: "f9c400"},
+		{value: -4.1, cbor: "fbc010666666666666"},
+		{value: math.Inf(1), cbor: "f97c00"},
+		{value: math.NaN(), cbor: "f97e00"},
+		{value: math.Inf(-1), cbor: "f9fc00"},
+		{value: math.Inf(1), cbor: "fa7f800000", decodeOnly: true},
+		{value: math.NaN(), cbor: "fa7fc00000", decodeOnly: true},
+		{value: math.Inf(-1), cbor: "faff800000", decodeOnly: true},
+		{value: math.Inf(1), cbor: "fb7ff0000000000000", decodeOnly: true},
+		{value: math.NaN(), cbor: "fb7ff8000000000000", decodeOnly: true},
+		{value: math.Inf(-1), cbor: "fbfff0000000000000", decodeOnly: true},
+		{value: false, cbor: "f4"},
+		{value: true, cbor: "f5"},
+		{value: nil, cbor: "f6"},
+		{value: []byte{}, cbor: "40"},
+		{value: []byte{1, 2, 3, 4}, cbor: "4401020304"},
+		{value: "", cbor: "60"},
+		{value: "a", cbor: "6161"},
+		{value: "IETF", cbor: "6449455446"},
+		{value: "\"\\", cbor: "62225c"},
+		{value: "ü", cbor: "62c3bc"},
+		{value: "水", cbor: "63e6b0b4"},
+		{value: "\U00010151", cbor: "64f0908591"},
+		{value: []interface{}{}, cbor: "80"},
+		{value: []uint64{1, 2, 3}, cbor: "83010203"},
+		{
+			value: []interface{}{uint64(1), []interface{}{uint64(2), uint64(3)}, []interface{}{uint64(4), uint64(5)}},
+			cbor:  "8301820203820405",
+		},
+		{
+			value: []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25},
+			cbor:  "98190102030405060708090a0b0c0d0e0f101112131415161718181819",
+		},
+		{value: map[string]interface{}{}, cbor: "a0"},
+		{value: map[uint64]uint64{1: 2, 3: 4}, cbor: "a201020304"},
+		{
+			value: map[string]interface{}{"a": uint64(1), "b": []interface{}{uint64(2), uint64(3)}},
+			cbor:  "a26161016162820203",
+		},
+		{value: []interface{}{"a", map[interface{}]interface{}{"b": "c"}}, cbor: "826161a161626163"},
+		{
+			value: map[string]string{"a": "A", "b": "B", "c": "C", "d": "D", "e": "E"},
+			cbor:  "a56161614161626142616361436164614461656145",
+		},
+		{value: []byte{1, 2, 3, 4, 5}, cbor: "5f42010243030405ff", decodeOnly: true},
+		{value: "streaming", cbor: "7f657374726561646d696e67ff", decodeOnly: true},
+		{value: []interface{}{}, cbor: "9fff", decodeOnly: true},
+		{
+			value:      []interface{}{uint64(1), []interface{}{uint64(2), uint64(3)}, []interface{}{uint64(4), uint64(5)}},
+			cbor:       "9f018202039f0405ffff",
+			decodeOnly: true,
+		},
+		{
+			value:      []interface{}{uint64(1), []interface{}{uint64(2), uint64(3)}, []interface{}{uint64(4), uint64(5)}},
+			cbor:       "9f01820203820405ff",
+			decodeOnly: true,
+		},
+		{
+			value:      []interface{}{uint64(1), []interface{}{uint64(2), uint64(3)}, []interface{}{uint64(4), uint64(5)}},
+			cbor:       "83018202039f0405ff",
+			decodeOnly: true,
+		},
+		{
+			value:      []interface{}{uint64(1), []interface{}{uint64(2), uint64(3)}, []interface{}{uint64(4), uint64(5)}},
+			cbor:       "83019f0203ff820405",
+			decodeOnly: true,
+		},
+		{
+			value:      []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25},
+			cbor:       "9f0102030405060708090a0b0c0d0e0f101112131415161718181819ff",
+			decodeOnly: true,
+		},
+		{
+			value:      map[string]interface{}{"a": uint64(1), "b": []interface{}{uint64(2), uint64(3)}},
+			cbor:       "bf61610161629f0203ffff",
+			decodeOnly: true,
+		},
+		{value: []interface{}{"a", map[interface{}]interface{}{"b": "c"}}, cbor: "826161bf61626163ff", decodeOnly: true},
+		{value: map[string]interface{}{"Fun": true, "Amt": int64(-2)}, cbor: "bf6346756ef563416d7421ff", decodeOnly: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cbor, func(t *testing.T) {
+			data, err := hex.DecodeString(tt.cbor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.decodeOnly {
+				got, err := Codec{}.Marshal(tt.value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if hex.EncodeToString(got) != tt.cbor {
+					t.Errorf("Marshal(%#v) = %x", tt.value, got)
+				}
+			}
+
+			typ := reflect.TypeOf(tt.value)
+			if typ == nil {
+				typ = reflect.TypeOf((*interface{})(nil)).Elem()
+			}
+			out := reflect.New(typ)
+			if err := (Codec{}).Unmarshal(data, out.Interface()); err != nil {
+				t.Fatal(err)
+			}
+			got := out.Elem().Interface()
+			if f, ok := tt.value.(float64); ok && math.IsNaN(f) {
+				if !math.IsNaN(got.(float64)) {
+					t.Errorf("Unmarshal = %v, want NaN", got)
+				}
+			} else if !reflect.DeepEqual(got, tt.value) {
+				t.Errorf("Unmarshal = %#v, want %#v", got, tt.value)
+			}
+		})
+	}
+}
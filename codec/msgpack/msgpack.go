@@ -0,0 +1,46 @@
+// Package msgpack provides the Codec of application/msgpack, to be registered on a client with apiclient.WithCodec,
+// for both of the media types MessagePack is sent as:
+//
+//	c, err := apiclient.NewClient(
+//		apiclient.WithCodec(msgpack.MediaType, msgpack.Codec{}),
+//		apiclient.WithCodec(msgpack.LegacyMediaType, msgpack.Codec{}),
+//	)
+//
+// Binaries built with the apiclient_slim tag leave apiclient.WithMessagePack out, and only link the MessagePack
+// library if they import this package.
+package msgpack
+
+import (
+	"bytes"
+
+	vmsgpack "github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	// MediaType is the media type of MessagePack.
+	MediaType = "application/msgpack"
+	// LegacyMediaType is the media type some servers still send MessagePack as.
+	LegacyMediaType = "application/x-msgpack"
+)
+
+// Codec marshals and unmarshals MessagePack, implementing apiclient.Codec. It honors json struct tags, so the same
+// values can be decoded from JSON and MessagePack.
+type Codec struct{}
+
+// Marshal returns the MessagePack encoding of v.
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := vmsgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes the MessagePack data into v.
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	dec := vmsgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
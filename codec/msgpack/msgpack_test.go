@@ -0,0 +1,43 @@
+package msgpack
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCodec(t *testing.T) {
+	type tagged struct {
+		Name string `json:"name"`
+		N    int    `json:"n,omitempty"`
+	}
+	tests := []struct {
+		name string
+		in   interface{}
+		// want is the encoding of in, nil to only check the round trip.
+		want []byte
+		out  interface{}
+	}{
+		{name: "int", in: 1, want: []byte{0x01}, out: new(int)},
+		{name: "string", in: "a", want: []byte{0xa1, 'a'}, out: new(string)},
+		{name: "json tags", in: tagged{Name: "x"}, want: []byte{0x81, 0xa4, 'n', 'a', 'm', 'e', 0xa1, 'x'}, out: new(tagged)},
+		{name: "map", in: map[string]interface{}{"a": []interface{}{int8(1), "b"}}, out: new(map[string]interface{})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Codec{}.Marshal(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want != nil && !bytes.Equal(data, tt.want) {
+				t.Errorf("Marshal = %x, want %x", data, tt.want)
+			}
+			if err := (Codec{}).Unmarshal(data, tt.out); err != nil {
+				t.Fatal(err)
+			}
+			if got := reflect.ValueOf(tt.out).Elem().Interface(); !reflect.DeepEqual(got, tt.in) {
+				t.Errorf("round trip = %#v, want %#v", got, tt.in)
+			}
+		})
+	}
+}
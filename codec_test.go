@@ -0,0 +1,59 @@
+//go:build !apiclient_slim
+
+package apiclient
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/MaTriXy/api-client/codec/cbor"
+	"github.com/MaTriXy/api-client/codec/msgpack"
+	"golang.org/x/net/context"
+)
+
+type codecPlace struct {
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+}
+
+func TestCodecSubpackages(t *testing.T) {
+	want := codecPlace{Name: "Seattle", Score: 97.5}
+	tests := []struct {
+		name      string
+		mediaType string
+		codec     Codec
+		// facade is the top-level option registering the same codec.
+		facade ClientOption
+	}{
+		{"cbor", cbor.MediaType, cbor.Codec{}, WithCBOR()},
+		{"msgpack", msgpack.MediaType, msgpack.Codec{}, WithMessagePack()},
+		{"legacy msgpack", msgpack.LegacyMediaType, msgpack.Codec{}, WithMessagePack()},
+	}
+	for _, tt := range tests {
+		body, err := tt.codec.Marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var accepted string
+		srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			accepted = r.Header.Get("Accept")
+			w.Header().Set("Content-Type", tt.mediaType)
+			w.Write(body)
+		})
+		for option, opt := range map[string]ClientOption{"WithCodec": WithCodec(tt.mediaType, tt.codec), "facade": tt.facade} {
+			t.Run(tt.name+"/"+option, func(t *testing.T) {
+				c := newTestClient(t, opt)
+				var got codecPlace
+				if err := c.GetJSON(context.Background(), &APIConfig{Host: srv.URL}, testParams{}, &got); err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("got %+v, want %+v", got, want)
+				}
+				if accepted == "application/json" {
+					t.Errorf("Accept %q does not ask for %s", accepted, tt.mediaType)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,45 @@
+//go:build !apiclient_slim
+
+package apiclient
+
+import (
+	"io"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// acceptEncoding lists the content codings decompressed by WithDecompression, most preferred first.
+const acceptEncoding = "zstd, br, gzip"
+
+// codingWriter returns the compressor of coding, other than gzip, or nil if it is not supported.
+func codingWriter(coding string) func(io.Writer) (io.WriteCloser, error) {
+	switch coding {
+	case "br":
+		return func(w io.Writer) (io.WriteCloser, error) { return brotli.NewWriter(w), nil }
+	case "zstd":
+		return func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) }
+	}
+	return nil
+}
+
+// codingReader returns the decompressor of coding, other than gzip, or nil if it is not supported. Closing the body
+// it returns closes the body it reads from.
+func codingReader(coding string) func(io.ReadCloser) (io.ReadCloser, error) {
+	switch coding {
+	case "br":
+		return func(body io.ReadCloser) (io.ReadCloser, error) { return readCloser{brotli.NewReader(body), body}, nil }
+	case "zstd":
+		return func(body io.ReadCloser) (io.ReadCloser, error) {
+			zr, err := zstd.NewReader(body)
+			if err != nil {
+				return nil, err
+			}
+			return readCloser{zr, closerFunc(func() error {
+				zr.Close()
+				return body.Close()
+			})}, nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build apiclient_slim
+
+package apiclient
+
+import "io"
+
+// acceptEncoding lists the content codings decompressed by WithDecompression. Binaries built with the apiclient_slim
+// tag leave brotli and zstd out.
+const acceptEncoding = "gzip"
+
+func codingWriter(coding string) func(io.Writer) (io.WriteCloser, error) { return nil }
+
+func codingReader(coding string) func(io.ReadCloser) (io.ReadCloser, error) { return nil }
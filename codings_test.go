@@ -0,0 +1,54 @@
+package apiclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// compressed returns data compressed with the request compression of c.
+func compressed(t *testing.T, c *Client, data []byte) []byte {
+	t.Helper()
+	open := c.requestCompression.wrap(func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil })
+	r, err := open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestContentCodings(t *testing.T) {
+	body := benchBody(4 << 10)
+	for _, coding := range strings.Split(acceptEncoding, ", ") {
+		t.Run(coding, func(t *testing.T) {
+			c := newTestClient(t, WithRequestCompression(coding, 0))
+			data := compressed(t, c, body)
+			if bytes.Equal(data, body) {
+				t.Fatal("body not compressed")
+			}
+			resp := &http.Response{
+				Header: http.Header{"Content-Encoding": {coding}},
+				Body:   io.NopCloser(bytes.NewReader(data)),
+			}
+			if err := decompress(resp, false); err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, body) || resp.Header.Get("Content-Encoding") != "" {
+				t.Errorf("decompressed %d bytes with Content-Encoding %q, want %d bytes", len(got),
+					resp.Header.Get("Content-Encoding"), len(body))
+			}
+		})
+	}
+}
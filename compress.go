@@ -0,0 +1,60 @@
+package apiclient
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// WithRequestCompression configures the client to compress request bodies of at least minSize bytes with coding
+// ("gzip", "br" or "zstd", but only gzip in binaries built with the apiclient_slim tag), setting their Content-Encoding. Smaller bodies, for which compression rarely pays off,
+// are sent as is; bodies of unknown length, such as streamed multipart uploads, are always compressed. Only use it
+// with servers that accept compressed requests.
+func WithRequestCompression(coding string, minSize int64) ClientOption {
+	return func(c *Client) error {
+		newWriter := codingWriter(coding)
+		if coding == "gzip" {
+			newWriter = func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil }
+		}
+		if newWriter == nil {
+			return fmt.Errorf("apiclient: unsupported request compression %q", coding)
+		}
+		c.requestCompression = &requestCompression{coding: coding, minSize: minSize, newWriter: newWriter}
+		return nil
+	}
+}
+
+// requestCompression compresses request bodies.
+type requestCompression struct {
+	coding    string
+	minSize   int64
+	newWriter func(io.Writer) (io.WriteCloser, error)
+}
+
+// applies reports whether b should be compressed.
+func (rc *requestCompression) applies(b *requestBody) bool {
+	return rc != nil && !b.identity && (b.size == 0 || b.size >= rc.minSize)
+}
+
+// wrap returns open with the bodies it opens compressed as they are read.
+func (rc *requestCompression) wrap(open func() (io.ReadCloser, error)) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		src, err := open()
+		if err != nil {
+			return nil, err
+		}
+		pr, pw := io.Pipe()
+		go func() {
+			defer src.Close()
+			zw, err := rc.newWriter(pw)
+			if err == nil {
+				_, err = io.Copy(zw, src)
+				if cerr := zw.Close(); err == nil {
+					err = cerr
+				}
+			}
+			pw.CloseWithError(err)
+		}()
+		return pr, nil
+	}
+}
@@ -0,0 +1,44 @@
+package apiclient
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// WithMaxConcurrentRequests configures the client to have at most n requests outstanding at once, independently of
+// the rate limit. A request counts as outstanding until its response body is closed; further requests wait for a
+// slot to free up.
+func WithMaxConcurrentRequests(n int) ClientOption {
+	return func(c *Client) error {
+		if n <= 0 {
+			return errors.New("apiclient: max concurrent requests must be positive")
+		}
+		c.inFlight = make(chan struct{}, n)
+		return nil
+	}
+}
+
+// acquire takes a slot from sem, waiting until one is free or ctx is done. It returns the func releasing the slot.
+func acquire(ctx context.Context, sem chan struct{}) (func(), error) {
+	if sem == nil {
+		return func() {}, nil
+	}
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	return func() { once.Do(func() { <-sem }) }, nil
+}
+
+// releaseOnClose returns body, calling release once it is closed.
+func releaseOnClose(body io.ReadCloser, release func()) io.ReadCloser {
+	return readCloser{body, closerFunc(func() error {
+		defer release()
+		return body.Close()
+	})}
+}
@@ -0,0 +1,297 @@
+package apiclient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultEnvPrefix prefixes the environment variables read by NewClientFromEnv when no prefix is given.
+const DefaultEnvPrefix = "APICLIENT"
+
+// Config holds the client settings that can be given by environment variables or a config file, so deployments can
+// reconfigure a client without code changes. Zero values leave the defaults in place.
+//
+// In a config file the settings are named base_url, api_key_name, api_key, rate_limit, rate_limit_burst, timeout,
+// tls_handshake_timeout, idle_conn_timeout and proxy; as environment variables, they are upper-cased and prefixed,
+// as in APICLIENT_BASE_URL. Durations are given as "30s" or "1m30s", or as a number of seconds. The proxy is a URL,
+// or "environment" to pick it from HTTPS_PROXY and related variables.
+type Config struct {
+	BaseURL        string
+	APIKeyName     string
+	APIKey         string
+	RateLimit      int
+	RateLimitBurst int
+	// Timeout bounds every request, including reading its response body.
+	Timeout             time.Duration
+	TLSHandshakeTimeout time.Duration
+	IdleConnTimeout     time.Duration
+	Proxy               string
+}
+
+// configSettings maps the names of the settings to the fields of Config they set.
+var configSettings = map[string]func(cfg *Config, value string) error{
+	"base_url":              func(cfg *Config, v string) error { cfg.BaseURL = v; return nil },
+	"api_key_name":          func(cfg *Config, v string) error { cfg.APIKeyName = v; return nil },
+	"api_key":               func(cfg *Config, v string) error { cfg.APIKey = v; return nil },
+	"rate_limit":            func(cfg *Config, v string) error { return parseSetting(v, &cfg.RateLimit) },
+	"rate_limit_burst":      func(cfg *Config, v string) error { return parseSetting(v, &cfg.RateLimitBurst) },
+	"timeout":               func(cfg *Config, v string) error { return parseSetting(v, &cfg.Timeout) },
+	"tls_handshake_timeout": func(cfg *Config, v string) error { return parseSetting(v, &cfg.TLSHandshakeTimeout) },
+	"idle_conn_timeout":     func(cfg *Config, v string) error { return parseSetting(v, &cfg.IdleConnTimeout) },
+	"proxy":                 func(cfg *Config, v string) error { cfg.Proxy = v; return nil },
+}
+
+func parseSetting(v string, out interface{}) error {
+	switch out := out.(type) {
+	case *int:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*out = n
+	case *time.Duration:
+		if secs, err := strconv.ParseFloat(v, 64); err == nil {
+			*out = time.Duration(secs * float64(time.Second))
+			return nil
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*out = d
+	}
+	return nil
+}
+
+// ConfigFromEnv reads a Config from the environment variables named after its settings, prefixed by prefix and an
+// underscore (DefaultEnvPrefix if empty).
+func ConfigFromEnv(prefix string) (Config, error) {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+	var cfg Config
+	for name, set := range configSettings {
+		key := prefix + "_" + strings.ToUpper(name)
+		if v, ok := os.LookupEnv(key); ok && v != "" {
+			if err := set(&cfg, v); err != nil {
+				return cfg, fmt.Errorf("apiclient: %s: %w", key, err)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// LoadConfig reads a Config from the file at path: a JSON object, or a YAML mapping if path ends in .yaml or .yml.
+// Only flat YAML is understood, one "name: value" pair per line, with # comments and quoted values. Unknown
+// settings are an error, so that misspelled ones are not silently ignored.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	var settings map[string]string
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		settings, err = parseFlatYAML(data)
+	default:
+		settings, err = parseFlatJSON(data)
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("apiclient: %s: %w", path, err)
+	}
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		set, ok := configSettings[name]
+		if !ok {
+			return cfg, fmt.Errorf("apiclient: %s: unknown setting %q", path, name)
+		}
+		if err := set(&cfg, settings[name]); err != nil {
+			return cfg, fmt.Errorf("apiclient: %s: %s: %w", path, name, err)
+		}
+	}
+	return cfg, nil
+}
+
+func parseFlatJSON(data []byte) (map[string]string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	settings := make(map[string]string, len(raw))
+	for name, v := range raw {
+		var s string
+		if err := json.Unmarshal(v, &s); err != nil {
+			// Numbers are taken as they are written.
+			s = string(bytes.TrimSpace(v))
+		}
+		settings[name] = s
+	}
+	return settings, nil
+}
+
+func parseFlatYAML(data []byte) (map[string]string, error) {
+	settings := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || text == "---" {
+			continue
+		}
+		name, value, ok := strings.Cut(text, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: want name: value", line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+			end := strings.IndexByte(value[1:], value[0])
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quote", line)
+			}
+			value = value[1 : end+1]
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		settings[strings.TrimSpace(name)] = value
+	}
+	return settings, scanner.Err()
+}
+
+// Options returns the client options applying cfg. Like WithProxy, some of them configure the transport of the HTTP
+// client, so an HTTP client of their own should be given with WithHTTPClient before them.
+func (cfg Config) Options() []ClientOption {
+	var options []ClientOption
+	if cfg.BaseURL != "" {
+		options = append(options, func(c *Client) error {
+			c.baseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+			return nil
+		})
+	}
+	if cfg.APIKey != "" {
+		options = append(options, func(c *Client) error {
+			if cfg.APIKeyName == "" {
+				return errors.New("apiclient: api_key is set without api_key_name")
+			}
+			return WithAPIKey(cfg.APIKeyName, cfg.APIKey)(c)
+		})
+	}
+	if cfg.RateLimit > 0 {
+		options = append(options, WithRateLimit(cfg.RateLimit))
+	}
+	if cfg.RateLimitBurst > 0 {
+		options = append(options, WithRateLimitBurst(cfg.RateLimitBurst))
+	}
+	if cfg.Timeout > 0 {
+		options = append(options, func(c *Client) error {
+			c.httpClient.Timeout = cfg.Timeout
+			return nil
+		})
+	}
+	if cfg.TLSHandshakeTimeout > 0 {
+		options = append(options, WithTLSHandshakeTimeout(cfg.TLSHandshakeTimeout))
+	}
+	if cfg.IdleConnTimeout > 0 {
+		options = append(options, WithIdleConnTimeout(cfg.IdleConnTimeout))
+	}
+	switch cfg.Proxy {
+	case "":
+	case "environment":
+		options = append(options, WithProxyFromEnvironment())
+	default:
+		options = append(options, WithProxy(cfg.Proxy))
+	}
+	return options
+}
+
+// ApplyConfig applies a reloaded cfg to a live client, reporting the settings it changed through the log hook as a
+// single "config changed" event: its base URL, API key, rate limit, burst and timeout replace those of the client,
+// while its zero values leave the client's settings in place. The timeout is applied as with SetTimeout. The transport
+// settings, TLSHandshakeTimeout, IdleConnTimeout and Proxy, only take effect when a client is constructed and are
+// ignored. cfg is checked before any of it is applied, so an invalid configuration leaves the client unchanged.
+func (c *Client) ApplyConfig(cfg Config) error {
+	if cfg.APIKey != "" && cfg.APIKeyName == "" {
+		return errors.New("apiclient: api_key is set without api_key_name")
+	}
+	if cfg.RateLimit != 0 {
+		if err := checkRate(cfg.RateLimit); err != nil {
+			return err
+		}
+	}
+	if cfg.RateLimitBurst < 0 {
+		return fmt.Errorf("apiclient: invalid rate limit burst %d", cfg.RateLimitBurst)
+	}
+	if cfg.Timeout < 0 {
+		return fmt.Errorf("apiclient: invalid timeout %s", cfg.Timeout)
+	}
+	c.reconfigure(func() {
+		if cfg.BaseURL != "" {
+			c.callMu.Lock()
+			c.baseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+			c.callMu.Unlock()
+		}
+		if cfg.APIKey != "" {
+			c.authMu.Lock()
+			if c.apiKeyValue != "" && c.apiKeyValue != cfg.APIKey {
+				c.provided.add(Credential{Name: c.apiKeyName, Value: c.apiKeyValue, In: InQuery})
+			}
+			c.apiKeyName, c.apiKeyValue = cfg.APIKeyName, cfg.APIKey
+			c.authMu.Unlock()
+		}
+		if cfg.Timeout > 0 {
+			c.callMu.Lock()
+			c.defaultTimeout = cfg.Timeout
+			c.callMu.Unlock()
+		}
+		if cfg.RateLimit > 0 || cfg.RateLimitBurst > 0 {
+			if cfg.RateLimit > 0 {
+				c.requestsPerSecond = cfg.RateLimit
+			}
+			if cfg.RateLimitBurst > 0 {
+				c.rateLimitBurst = cfg.RateLimitBurst
+			}
+			c.resizeRateLimiter()
+		}
+	})
+	return nil
+}
+
+// currentBaseURL returns the base URL the client sends its requests to, if it has one.
+func (c *Client) currentBaseURL() string {
+	c.callMu.RLock()
+	defer c.callMu.RUnlock()
+	return c.baseURL
+}
+
+// NewClientFromEnv constructs a Client configured by the environment variables prefixed by prefix, see
+// ConfigFromEnv, and then by options. A WithHTTPClient among options would discard the transport settings of the
+// configuration; pass the HTTP client to NewClient before Config.Options instead.
+func NewClientFromEnv(prefix string, options ...ClientOption) (*Client, error) {
+	cfg, err := ConfigFromEnv(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(append(cfg.Options(), options...)...)
+}
+
+// NewClientFromConfig constructs a Client configured by the file at path, see LoadConfig, and then by options. As
+// with NewClientFromEnv, an HTTP client of its own is given to NewClient before the options of the configuration.
+func NewClientFromConfig(path string, options ...ClientOption) (*Client, error) {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(append(cfg.Options(), options...)...)
+}
@@ -0,0 +1,130 @@
+package apiclient
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestApplyConfig(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Query().Get("key")))
+	})
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+		// want holds the changes of the "config changed" event, nil if none is expected.
+		want []ConfigChange
+	}{
+		{
+			name: "every live setting",
+			cfg: Config{BaseURL: srv.URL + "/", APIKeyName: "key", APIKey: "new", RateLimit: 20, RateLimitBurst: 5,
+				Timeout: 30 * time.Second},
+			want: []ConfigChange{
+				{Setting: "api_key", Old: fingerprint("old"), New: fingerprint("new")},
+				{Setting: "base_url", New: srv.URL},
+				{Setting: "rate_limit_burst", Old: "0", New: "5"},
+				{Setting: "requests_per_second", Old: "10", New: "20"},
+				{Setting: "timeout", New: "30s"},
+			},
+		},
+		{
+			name: "burst alone",
+			cfg:  Config{RateLimitBurst: 1},
+			want: []ConfigChange{{Setting: "rate_limit_burst", Old: "0", New: "1"}},
+		},
+		{
+			name: "unchanged settings",
+			cfg:  Config{APIKeyName: "key", APIKey: "old", RateLimit: 10},
+		},
+		{
+			name: "zero config",
+			cfg:  Config{},
+		},
+		{
+			name: "transport settings only",
+			cfg:  Config{TLSHandshakeTimeout: time.Second, IdleConnTimeout: time.Second, Proxy: "environment"},
+		},
+		{
+			name:    "api key without name",
+			cfg:     Config{APIKey: "new", RateLimit: 20},
+			wantErr: true,
+		},
+		{
+			name:    "invalid rate limit",
+			cfg:     Config{BaseURL: srv.URL, RateLimit: -1},
+			wantErr: true,
+		},
+		{
+			name:    "invalid burst",
+			cfg:     Config{BaseURL: srv.URL, RateLimitBurst: -1},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events [][]ConfigChange
+			c := newTestClient(t, WithRateLimit(10), WithAPIKey("key", "old"),
+				WithLogHook(func(event string, fields map[string]interface{}) {
+					if event == "config changed" {
+						events = append(events, fields["changes"].([]ConfigChange))
+					}
+				}))
+
+			err := c.ApplyConfig(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyConfig = %v, want error %v", err, tt.wantErr)
+			}
+			switch {
+			case tt.want == nil && len(events) > 0:
+				t.Errorf("changes reported: %+v", events)
+			case tt.want != nil && len(events) != 1:
+				t.Fatalf("got %d config changed events, want 1", len(events))
+			case tt.want != nil && !reflect.DeepEqual(events[0], tt.want):
+				t.Errorf("changes = %+v, want %+v", events[0], tt.want)
+			}
+			if tt.cfg.BaseURL == "" || tt.wantErr {
+				return
+			}
+			// The reloaded base URL and API key are used by the next calls.
+			resp, err := c.GetBinary(context.Background(), &APIConfig{Host: "http://unused.invalid"}, testParams{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Data.Close()
+			key, err := ioutil.ReadAll(resp.Data)
+			if err != nil || string(key) != tt.cfg.APIKey {
+				t.Errorf("request sent with key %q, want %q", key, tt.cfg.APIKey)
+			}
+		})
+	}
+}
+
+func TestFingerprintIsKeyed(t *testing.T) {
+	tests := []string{"k3y!", "key1", "a-much-longer-api-key-value"}
+	for _, secret := range tests {
+		t.Run(secret, func(t *testing.T) {
+			fp := fingerprint(secret)
+			if fp != fingerprint(secret) {
+				t.Errorf("fingerprint of %q not stable", secret)
+			}
+			sum := sha256.Sum256([]byte(secret))
+			if strings.Contains(fp, secret) || strings.Contains(fp, fmt.Sprintf("%x", sum[:4])) {
+				t.Errorf("fingerprint %q can be checked against guesses of the secret", fp)
+			}
+			for _, other := range tests {
+				if other != secret && fingerprint(other) == fp {
+					t.Errorf("%q and %q share a fingerprint", secret, other)
+				}
+			}
+		})
+	}
+}
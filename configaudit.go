@@ -0,0 +1,111 @@
+package apiclient
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// LogHook receives the client's log events, with their structured details in fields.
+type LogHook func(event string, fields map[string]interface{})
+
+// WithLogHook configures the client to report log events to hook.
+func WithLogHook(hook LogHook) ClientOption {
+	return func(c *Client) error {
+		c.logHook = hook
+		return nil
+	}
+}
+
+// log reports event to the log hook, if there is one.
+func (c *Client) log(event string, fields map[string]interface{}) {
+	if c.logHook != nil {
+		c.logHook(event, fields)
+	}
+}
+
+// ConfigChange describes one setting changed on a live client. Secret values are redacted to a short fingerprint, so
+// that a change is visible without revealing the secret. Fingerprints are keyed by a secret of the process, so they
+// can only be compared between the changes of one process.
+type ConfigChange struct {
+	Setting string
+	Old     string
+	New     string
+}
+
+// reconfigure applies change to the client and reports the settings it changed through the log hook, as a
+// "config changed" event whose "changes" field holds a []ConfigChange.
+func (c *Client) reconfigure(change func()) {
+	c.configMu.Lock()
+	defer c.configMu.Unlock()
+	before := c.configSnapshot()
+	change()
+	if changes := diffConfig(before, c.configSnapshot()); len(changes) > 0 {
+		c.log("config changed", map[string]interface{}{"changes": changes})
+	}
+}
+
+// configSnapshot returns the client's current settings, with secrets redacted.
+func (c *Client) configSnapshot() map[string]string {
+	s := map[string]string{
+		"api_key_name":        c.apiKeyName,
+		"api_key":             fingerprint(c.apiKeyValue),
+		"base_url":            c.currentBaseURL(),
+		"requests_per_second": strconv.Itoa(c.requestsPerSecond),
+		"rate_limit_burst":    strconv.Itoa(c.rateLimitBurst),
+		"read_only":           strconv.FormatBool(c.isReadOnly()),
+	}
+	if c.defaultTimeout > 0 {
+		s["timeout"] = c.defaultTimeout.String()
+	}
+	if c.retryPolicy != nil {
+		s["retry_policy"] = fmt.Sprintf("%T", c.retryPolicy)
+	}
+	if c.tokenSource != nil {
+		s["token_source"] = fmt.Sprintf("%T", c.tokenSource)
+	}
+	for i, cred := range c.credentials {
+		s[fmt.Sprintf("credentials[%d]", i)] = fmt.Sprintf("%s=%s", cred.Name, fingerprint(cred.Value))
+	}
+	return s
+}
+
+// fingerprintKey keys the fingerprints of secrets. It is drawn anew by each process, so that fingerprints tell apart
+// the secrets of one process without letting whoever reads them test guesses of a secret offline.
+var fingerprintKey = func() []byte {
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		panic("apiclient: cannot draw the fingerprint key: " + err.Error())
+	}
+	return key
+}()
+
+// fingerprint redacts a secret to a short keyed hash of it.
+func fingerprint(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, fingerprintKey)
+	mac.Write([]byte(secret))
+	return fmt.Sprintf("%s(hmac:%x)", redacted, mac.Sum(nil)[:8])
+}
+
+// diffConfig returns the settings that differ between two snapshots, sorted by name.
+func diffConfig(before, after map[string]string) []ConfigChange {
+	var changes []ConfigChange
+	for k, v := range after {
+		if before[k] != v {
+			changes = append(changes, ConfigChange{Setting: k, Old: before[k], New: v})
+		}
+	}
+	for k, v := range before {
+		if _, ok := after[k]; !ok {
+			changes = append(changes, ConfigChange{Setting: k, Old: v})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Setting < changes[j].Setting })
+	return changes
+}
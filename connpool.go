@@ -0,0 +1,51 @@
+package apiclient
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// The connection pool options apply to the *http.Transport of the HTTP client, like WithProxy. Zero means no limit.
+
+// WithMaxIdleConns limits the idle connections kept open across all hosts.
+func WithMaxIdleConns(n int) ClientOption {
+	return transportOption("MaxIdleConns", n, func(t *http.Transport) { t.MaxIdleConns = n })
+}
+
+// WithMaxIdleConnsPerHost limits the idle connections kept open to each host. Zero means
+// http.DefaultMaxIdleConnsPerHost, only 2: a client making many concurrent requests to one host should raise it, or
+// most of its connections are closed after each request.
+func WithMaxIdleConnsPerHost(n int) ClientOption {
+	return transportOption("MaxIdleConnsPerHost", n, func(t *http.Transport) { t.MaxIdleConnsPerHost = n })
+}
+
+// WithMaxConnsPerHost limits the connections to each host, idle or not. Requests beyond it wait for a connection.
+func WithMaxConnsPerHost(n int) ClientOption {
+	return transportOption("MaxConnsPerHost", n, func(t *http.Transport) { t.MaxConnsPerHost = n })
+}
+
+// WithIdleConnTimeout closes connections that have been idle for d.
+func WithIdleConnTimeout(d time.Duration) ClientOption {
+	return transportOption("IdleConnTimeout", d, func(t *http.Transport) { t.IdleConnTimeout = d })
+}
+
+// WithTLSHandshakeTimeout bounds the TLS handshake of new connections to d.
+func WithTLSHandshakeTimeout(d time.Duration) ClientOption {
+	return transportOption("TLSHandshakeTimeout", d, func(t *http.Transport) { t.TLSHandshakeTimeout = d })
+}
+
+// transportOption returns an option applying set to the base transport, after checking that value is not negative.
+func transportOption[T int | time.Duration](name string, value T, set func(*http.Transport)) ClientOption {
+	return func(c *Client) error {
+		if value < 0 {
+			return fmt.Errorf("apiclient: invalid %s %v", name, value)
+		}
+		t, err := c.baseTransport()
+		if err != nil {
+			return err
+		}
+		set(t)
+		return nil
+	}
+}
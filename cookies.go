@@ -0,0 +1,167 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// WithCookieJar configures the client to keep the cookies set by the API in jar and to send them back with its
+// requests, for APIs holding a session in a cookie after a login call. It applies to the HTTP client, so like
+// WithProxy it must come after WithHTTPClient if both are used.
+func WithCookieJar(jar http.CookieJar) ClientOption {
+	return func(c *Client) error {
+		if jar == nil {
+			return errors.New("apiclient: nil cookie jar")
+		}
+		c.httpClient.Jar = jar
+		return nil
+	}
+}
+
+// WithSessionCookies configures the client with a new in-memory CookieJar, see WithCookieJar. The jar is returned by
+// Client.CookieJar, to be saved and loaded between runs.
+func WithSessionCookies() ClientOption {
+	return func(c *Client) error {
+		return WithCookieJar(NewCookieJar())(c)
+	}
+}
+
+// CookieJar returns the cookie jar of the client, or nil if it has none.
+func (c *Client) CookieJar() http.CookieJar {
+	return c.httpClient.Jar
+}
+
+// CookieJar is an in-memory http.CookieJar, following the rules of net/http/cookiejar, whose cookies can be saved
+// and loaded again, so that a session outlives the process. No public suffix list is used, so a cookie set for a
+// domain is sent to all of its subdomains.
+type CookieJar struct {
+	jar *cookiejar.Jar
+
+	mu sync.Mutex
+	// saved holds the cookies set, with the URL that set them, by name, domain and path.
+	saved map[cookieID]savedCookie
+}
+
+type cookieID struct {
+	name, domain, path string
+}
+
+// savedCookie is a cookie as Save writes it. A cookie without Expires lasts for the session, however many runs it
+// spans.
+type savedCookie struct {
+	URL      string        `json:"url"`
+	Name     string        `json:"name"`
+	Value    string        `json:"value"`
+	Domain   string        `json:"domain,omitempty"`
+	Path     string        `json:"path,omitempty"`
+	Expires  *time.Time    `json:"expires,omitempty"`
+	Secure   bool          `json:"secure,omitempty"`
+	HttpOnly bool          `json:"http_only,omitempty"`
+	SameSite http.SameSite `json:"same_site,omitempty"`
+}
+
+// NewCookieJar returns an empty CookieJar.
+func NewCookieJar() *CookieJar {
+	jar, _ := cookiejar.New(nil)
+	return &CookieJar{jar: jar, saved: map[cookieID]savedCookie{}}
+}
+
+// SetCookies stores the cookies set by a response from u.
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+	now := time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, cookie := range cookies {
+		id := cookieID{cookie.Name, cookie.Domain, cookie.Path}
+		if id.domain == "" {
+			id.domain = u.Hostname()
+		}
+		saved := savedCookie{
+			URL:      (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String(),
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   cookie.Domain,
+			Path:     cookie.Path,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+			SameSite: cookie.SameSite,
+		}
+		var expires time.Time
+		switch {
+		case cookie.MaxAge < 0:
+			expires = now
+		case cookie.MaxAge > 0:
+			expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		case !cookie.Expires.IsZero():
+			expires = cookie.Expires
+		}
+		if !expires.IsZero() && !expires.After(now) {
+			delete(j.saved, id)
+			continue
+		}
+		if !expires.IsZero() {
+			saved.Expires = &expires
+		}
+		j.saved[id] = saved
+	}
+}
+
+// Cookies returns the cookies to send in a request to u.
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// Save writes the cookies of the jar that have not expired to w, as JSON.
+func (j *CookieJar) Save(w io.Writer) error {
+	now := time.Now()
+	j.mu.Lock()
+	cookies := make([]savedCookie, 0, len(j.saved))
+	for id, saved := range j.saved {
+		if saved.Expires != nil && !saved.Expires.After(now) {
+			delete(j.saved, id)
+			continue
+		}
+		cookies = append(cookies, saved)
+	}
+	j.mu.Unlock()
+	return json.NewEncoder(w).Encode(cookies)
+}
+
+// Load adds the cookies written by Save to r to the jar, except those that have expired since.
+func (j *CookieJar) Load(r io.Reader) error {
+	var cookies []savedCookie
+	if err := json.NewDecoder(r).Decode(&cookies); err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, saved := range cookies {
+		if saved.Expires != nil && !saved.Expires.After(now) {
+			continue
+		}
+		u, err := url.Parse(saved.URL)
+		if err != nil {
+			return err
+		}
+		cookie := &http.Cookie{
+			Name:     saved.Name,
+			Value:    saved.Value,
+			Domain:   saved.Domain,
+			Path:     saved.Path,
+			Secure:   saved.Secure,
+			HttpOnly: saved.HttpOnly,
+			SameSite: saved.SameSite,
+		}
+		if saved.Expires != nil {
+			cookie.Expires = *saved.Expires
+		}
+		j.SetCookies(u, []*http.Cookie{cookie})
+	}
+	return nil
+}
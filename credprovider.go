@@ -0,0 +1,161 @@
+package apiclient
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// CredentialProvider supplies a credential for each request, such as a secret read from Vault, a cloud secret
+// manager or a file mounted by an operator, so that it can be rotated without restarting the process. Get is called
+// for every request, so implementations backed by a remote store should cache the secret. They must be safe for
+// concurrent use.
+type CredentialProvider interface {
+	Get(ctx context.Context) (Credential, error)
+}
+
+// StaticCredential is a CredentialProvider always supplying the same credential, like the API key of WithAPIKey,
+// which is the credential a client has by default.
+type StaticCredential Credential
+
+// Get returns the credential.
+func (s StaticCredential) Get(context.Context) (Credential, error) {
+	return Credential(s), nil
+}
+
+// WithCredentialProvider configures the client to attach the credential supplied by p to every request, after the
+// API key and the credentials of WithCredentials and before those carried by the request context. A request fails
+// if p fails.
+func WithCredentialProvider(p CredentialProvider) ClientOption {
+	return func(c *Client) error {
+		if p == nil {
+			return errors.New("apiclient: nil credential provider")
+		}
+		c.credentialProviders = append(c.credentialProviders, p)
+		return nil
+	}
+}
+
+// FileCredential is a CredentialProvider reading the value of a credential from a file, such as a Kubernetes secret
+// mounted in a volume. The file is read again whenever it changes; surrounding white space is trimmed.
+type FileCredential struct {
+	path string
+	name string
+	in   CredentialLocation
+
+	mu      sync.Mutex
+	modTime time.Time
+	cred    Credential
+}
+
+// NewFileCredential returns a FileCredential sending the content of the file at path as the credential name in.
+func NewFileCredential(path, name string, in CredentialLocation) *FileCredential {
+	return &FileCredential{path: path, name: name, in: in}
+}
+
+// Get returns the credential, reading the file if it changed since it was last read.
+func (f *FileCredential) Get(context.Context) (Credential, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return Credential{}, fmt.Errorf("apiclient: credential file: %w", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cred.Value != "" && info.ModTime().Equal(f.modTime) {
+		return f.cred, nil
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return Credential{}, fmt.Errorf("apiclient: credential file: %w", err)
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return Credential{}, fmt.Errorf("apiclient: credential file %s is empty", f.path)
+	}
+	f.cred = Credential{Name: f.name, Value: value, In: f.in}
+	f.modTime = info.ModTime()
+	return f.cred, nil
+}
+
+// provided remembers the credentials supplied by providers, to redact them from diagnostics. A rotated secret is
+// kept along with the one before, which requests still in flight may have been sent with.
+type provided struct {
+	mu    sync.Mutex
+	creds []Credential
+}
+
+// add records cred, keeping the two last values of each name and location.
+func (p *provided) add(cred Credential) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for i := len(p.creds) - 1; i >= 0; i-- {
+		if prev := p.creds[i]; prev.Name == cred.Name && prev.In == cred.In {
+			if prev.Value == cred.Value {
+				return
+			}
+			if n++; n == 2 {
+				p.creds = append(p.creds[:i], p.creds[i+1:]...)
+			}
+		}
+	}
+	p.creds = append(p.creds, cred)
+}
+
+func (p *provided) list() []Credential {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Credential(nil), p.creds...)
+}
+
+// providedCredentials returns the credentials supplied by the client's providers for a request made with ctx.
+func (c *Client) providedCredentials(ctx context.Context) ([]Credential, error) {
+	var creds []Credential
+	for _, p := range c.credentialProviders {
+		cred, err := p.Get(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.provided.add(cred)
+		creds = append(creds, cred)
+	}
+	return creds, nil
+}
+
+// TokenSource supplies the bearer token of requests, such as an OAuth2 access token it refreshes before it expires.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// SetTokenSource makes the client send the token of ts in an "Authorization: Bearer" header with every request, in
+// place of the token source set before, if any; nil removes it. Like SetAPIKey, it is meant for rotating credentials
+// on a live client, and is safe to call while requests are made.
+func (c *Client) SetTokenSource(ts TokenSource) {
+	c.reconfigure(func() {
+		c.authMu.Lock()
+		c.tokenSource = ts
+		c.authMu.Unlock()
+	})
+}
+
+// bearerToken returns the credential of the client's token source, or nil if it has none.
+func (c *Client) bearerToken(ctx context.Context) (*Credential, error) {
+	c.authMu.RLock()
+	ts := c.tokenSource
+	c.authMu.RUnlock()
+	if ts == nil {
+		return nil, nil
+	}
+	token, err := ts.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cred := BearerToken(token)
+	c.provided.add(cred)
+	return &cred, nil
+}
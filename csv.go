@@ -0,0 +1,267 @@
+package apiclient
+
+import (
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// CSVError reports a CSV response that is malformed, or has a value that does not fit the field it is decoded into.
+type CSVError struct {
+	// Line is the line of the record, counting the header as line 1.
+	Line int
+	// Column is the name of the column of the value, if the error is about one.
+	Column string
+	Err    error
+}
+
+func (e *CSVError) Error() string {
+	if e.Column != "" {
+		return fmt.Sprintf("apiclient: invalid CSV response: line %d, column %q: %v", e.Line, e.Column, e.Err)
+	}
+	return fmt.Sprintf("apiclient: invalid CSV response: line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *CSVError) Unwrap() error {
+	return e.Err
+}
+
+// WithCSVDelimiter makes a CSV call split fields on delim in place of a comma, as for semicolon-separated exports.
+func WithCSVDelimiter(delim rune) RequestOption {
+	return func(o *requestOptions) {
+		o.csvDelimiter = delim
+	}
+}
+
+// GetCSV makes a request to the API endpoint and decodes its CSV response into resp, a pointer to a slice of structs
+// or of pointers to structs, one per record. The first record is the header, naming the columns. A column is decoded
+// into the field tagged with its name, as in `csv:"created_at"`, or else the field of the same name, ignoring case;
+// columns without a field are skipped, and fields tagged "-" are never set. Fields may be strings, booleans, numbers,
+// time.Time (as RFC 3339), time.Duration, pointers to those, left nil for empty values, or implement
+// encoding.TextUnmarshaler. Records that do not decode fail the call with a *CSVError. See GetCSVStream and StreamCSV
+// for exports too large to be held in memory.
+func (c *Client) GetCSV(ctx context.Context, config *APIConfig, apiReq APIRequest, resp interface{}, opts ...RequestOption) error {
+	slice := reflect.ValueOf(resp)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("apiclient: GetCSV needs a pointer to a slice, not %T", resp)
+	}
+	slice = slice.Elem()
+	elem := slice.Type().Elem()
+	isPtr := elem.Kind() == reflect.Ptr
+	if isPtr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return fmt.Errorf("apiclient: GetCSV needs a slice of structs, not %T", resp)
+	}
+	slice.SetLen(0)
+	return c.csv(ctx, config, apiReq, opts, false, func(header []string) func([]string, int) error {
+		columns := csvColumns(elem, header)
+		return func(record []string, line int) error {
+			row := reflect.New(elem)
+			if err := decodeCSVRecord(row.Elem(), columns, header, record, line); err != nil {
+				return err
+			}
+			if !isPtr {
+				row = row.Elem()
+			}
+			slice.Set(reflect.Append(slice, row))
+			return nil
+		}
+	})
+}
+
+// GetCSVStream makes a request to the API endpoint and passes the records of its CSV response to handle as they are
+// read, with the header naming their columns. It stops at the first error returned by handle. Unlike GetCSV, the
+// response is never cached or shared between callers.
+func (c *Client) GetCSVStream(ctx context.Context, config *APIConfig, apiReq APIRequest, handle func(header, record []string) error, opts ...RequestOption) error {
+	return c.csv(ctx, config, apiReq, opts, true, func(header []string) func([]string, int) error {
+		return func(record []string, _ int) error {
+			return handle(header, record)
+		}
+	})
+}
+
+// csv makes a CSV call, handing its header to start and each following record, with its line, to the function start
+// returns. A streamed call bypasses the cache and coalescing, which would buffer the response.
+func (c *Client) csv(ctx context.Context, config *APIConfig, apiReq APIRequest, opts []RequestOption, stream bool, start func(header []string) func(record []string, line int) error) error {
+	o := c.newRequestOptions(opts)
+	ctx, cancel := o.context(ctx, config)
+	defer cancel()
+	ctx = c.withRequestID(defaultAccept(ctx, "text/csv"))
+	var httpResp *http.Response
+	var err error
+	if stream {
+		httpResp, err = c.send(ctx, "GET", config, apiReq, nil, nil)
+	} else {
+		httpResp, err = c.get(ctx, config, apiReq)
+	}
+	if err != nil {
+		return c.requestError(ctx, err)
+	}
+	defer httpResp.Body.Close()
+	if o.meta != nil {
+		o.meta.fill(httpResp)
+	}
+	body, err := c.textBody(httpResp)
+	if err != nil {
+		return c.requestError(ctx, err)
+	}
+	r := csv.NewReader(body)
+	if o.csvDelimiter != 0 {
+		r.Comma = o.csvDelimiter
+	}
+	r.ReuseRecord = true
+	header, err := r.Read()
+	if err == io.EOF {
+		// An empty export has no header.
+		return nil
+	}
+	if err != nil {
+		return c.requestError(ctx, csvError(err, 1))
+	}
+	header = append([]string(nil), header...)
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	handle := start(header)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		line, _ := r.FieldPos(0)
+		if err != nil {
+			return c.requestError(ctx, csvError(err, line))
+		}
+		if err := handle(record, line); err != nil {
+			return c.requestError(ctx, err)
+		}
+	}
+}
+
+func csvError(err error, line int) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &CSVError{Line: parseErr.Line, Err: parseErr.Err}
+	}
+	if err == io.ErrUnexpectedEOF {
+		return &CSVError{Line: line, Err: err}
+	}
+	return err
+}
+
+// csvColumns returns the index of the field of t each column of header is decoded into, nil for columns without one.
+func csvColumns(t reflect.Type, header []string) [][]int {
+	named := map[string][]int{}
+	folded := map[string][]int{}
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous && f.Type.Kind() == reflect.Struct {
+			continue
+		}
+		tag := f.Tag.Get("csv")
+		if tag == "-" {
+			continue
+		}
+		if name, _, _ := strings.Cut(tag, ","); name != "" {
+			named[name] = f.Index
+		} else if _, ok := folded[strings.ToLower(f.Name)]; !ok {
+			folded[strings.ToLower(f.Name)] = f.Index
+		}
+	}
+	columns := make([][]int, len(header))
+	for i, name := range header {
+		if index, ok := named[name]; ok {
+			columns[i] = index
+		} else {
+			columns[i] = folded[strings.ToLower(name)]
+		}
+	}
+	return columns
+}
+
+// decodeCSVRecord sets the fields of row to the values of record.
+func decodeCSVRecord(row reflect.Value, columns [][]int, header, record []string, line int) error {
+	for i, value := range record {
+		if i >= len(columns) || columns[i] == nil {
+			continue
+		}
+		if err := setCSVField(row.FieldByIndex(columns[i]), value); err != nil {
+			return &CSVError{Line: line, Column: header[i], Err: err}
+		}
+	}
+	return nil
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// setCSVField sets field to the CSV value s.
+func setCSVField(field reflect.Value, s string) error {
+	if field.Kind() == reflect.Ptr {
+		if s == "" {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+		field.Set(reflect.New(field.Type().Elem()))
+		field = field.Elem()
+	}
+	if s == "" && field.Kind() != reflect.String {
+		return nil
+	}
+	// time.Time is a TextUnmarshaler, parsing RFC 3339.
+	if field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package apiclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/context"
+)
+
+// CurlCommand renders req as an equivalent curl command line, to reproduce it outside Go. With redact, the client's
+// credentials and any Authorization, Proxy-Authorization or Cookie header are replaced by REDACTED. The body is
+// included if it can be read again without consuming it, which is the case for every body but streamed uploads.
+// Hooks such as a TraceHook can call it on the requests they receive.
+func (c *Client) CurlCommand(req *http.Request, redact bool) string {
+	ctx := req.Context()
+	u, header := req.URL.String(), req.Header
+	if redact {
+		u, header = c.redactURL(ctx, u), c.redactExport(ctx, header)
+	}
+	var b strings.Builder
+	b.WriteString("curl")
+	if req.Method != "GET" {
+		fmt.Fprintf(&b, " -X %s", req.Method)
+	}
+	fmt.Fprintf(&b, " %s", shellQuote(u))
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range header[k] {
+			fmt.Fprintf(&b, " -H %s", shellQuote(k+": "+v))
+		}
+	}
+	switch body, ok := replayBody(req); {
+	case !ok:
+		b.WriteString(" --data-binary @-  # the streamed request body is not included")
+	case len(body) == 0:
+	case !utf8.Valid(body):
+		b.WriteString(" --data-binary @body.bin  # the binary request body is not included")
+	default:
+		fmt.Fprintf(&b, " --data-binary %s", shellQuote(string(body)))
+	}
+	return b.String()
+}
+
+// replayBody returns the body of req, read from a new copy of it. It returns false if the body cannot be read
+// without consuming it.
+func replayBody(req *http.Request) ([]byte, bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	return data, err == nil
+}
+
+// shellQuote quotes s as a single word for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// CurlError is returned for a failed call when curl commands are attached to errors, see WithCurlOnError.
+type CurlError struct {
+	// Command is the last request of the call, rendered by Client.CurlCommand.
+	Command string
+	Err     error
+}
+
+func (e *CurlError) Error() string {
+	return fmt.Sprintf("%v (reproduce with: %s)", e.Err, e.Command)
+}
+
+// Unwrap returns the underlying error.
+func (e *CurlError) Unwrap() error {
+	return e.Err
+}
+
+// WithCurlOnError configures the client to attach the curl command of the last request of every failed call to its
+// error, as a *CurlError, with credentials redacted if redact is set.
+func WithCurlOnError(redact bool) ClientOption {
+	return func(c *Client) error {
+		c.curlOnError = true
+		c.curlRedact = redact
+		return nil
+	}
+}
+
+type curlKey struct{}
+
+// lastRequest holds the curl command of the last request of a call.
+type lastRequest struct {
+	command string
+}
+
+// withCurlCapture returns a copy of ctx whose requests are rendered as curl commands, if they are attached to errors.
+func (c *Client) withCurlCapture(ctx context.Context) context.Context {
+	if !c.curlOnError {
+		return ctx
+	}
+	if _, ok := ctx.Value(curlKey{}).(*lastRequest); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, curlKey{}, &lastRequest{})
+}
+
+// captureCurl records req as the last request of its call.
+func (c *Client) captureCurl(req *http.Request) {
+	if last, ok := req.Context().Value(curlKey{}).(*lastRequest); ok {
+		last.command = c.CurlCommand(req, c.curlRedact)
+	}
+}
+
+// curlError attaches the curl command of the last request of the call of ctx to err.
+func curlError(ctx context.Context, err error) error {
+	if last, ok := ctx.Value(curlKey{}).(*lastRequest); ok && last.command != "" {
+		return &CurlError{Command: last.command, Err: err}
+	}
+	return err
+}
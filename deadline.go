@@ -0,0 +1,79 @@
+package apiclient
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// WithDeadlineHint configures the client to read, from the header of the responses it gets, how much time the API
+// gives the operation, as some APIs do with X-Timeout-Remaining. The value is a number of seconds, a duration such as
+// "1.5s", or an HTTP date or RFC 3339 time at which the deadline falls. A failed request is not retried if the retry
+// could not be sent before that deadline; its outcome is returned as is.
+func WithDeadlineHint(header string) ClientOption {
+	return func(c *Client) error {
+		c.deadlineHintHeader = header
+		return nil
+	}
+}
+
+// WithDeadlinePropagation configures the client to tell deadline-aware backends how long they have to answer: every
+// request made with a context that has a deadline carries the time remaining until it, in milliseconds, in header.
+func WithDeadlinePropagation(header string) ClientOption {
+	return func(c *Client) error {
+		c.deadlineHeader = header
+		return nil
+	}
+}
+
+// propagateDeadline sets the deadline header of a request made with ctx.
+func (c *Client) propagateDeadline(ctx context.Context, header http.Header) {
+	if c.deadlineHeader == "" {
+		return
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		ms := time.Until(deadline).Milliseconds()
+		if ms < 0 {
+			ms = 0
+		}
+		header.Set(c.deadlineHeader, strconv.FormatInt(ms, 10))
+	}
+}
+
+// retryFits reports whether a retry sent after delay would be sent before the deadline of ctx and the one hinted by
+// resp, if any.
+func (c *Client) retryFits(ctx context.Context, resp *http.Response, delay time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return false
+	}
+	if resp != nil && c.deadlineHintHeader != "" {
+		if remaining, ok := c.deadlineHint(resp.Header.Get(c.deadlineHintHeader)); ok && remaining < delay {
+			return false
+		}
+	}
+	return true
+}
+
+// deadlineHint parses the value of a deadline hint header into the time remaining until the deadline.
+func (c *Client) deadlineHint(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), true
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return d, true
+	}
+	for _, parse := range []func(string) (time.Time, error){
+		http.ParseTime,
+		func(v string) (time.Time, error) { return time.Parse(time.RFC3339, v) },
+	} {
+		if t, err := parse(v); err == nil {
+			return t.Sub(c.clock.Now()), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,73 @@
+package apiclient
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// WithDecompression configures the client to advertise gzip, brotli and zstd (only gzip in binaries built with the
+// apiclient_slim tag) in Accept-Encoding and to decompress response bodies before they are decoded or cached. GetBinary, DownloadFile and GetBinaryRanges keep receiving the
+// body as sent, unless binary is true; ranged downloads never ask for a content coding, as ranges would then apply
+// to the compressed bytes.
+func WithDecompression(binary bool) ClientOption {
+	return func(c *Client) error {
+		c.decompression = true
+		c.decompressBinary = binary
+		return nil
+	}
+}
+
+type rawBodyKey struct{}
+
+// withRawBody returns a copy of ctx whose requests do not ask for compressed responses.
+func withRawBody(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rawBodyKey{}, true)
+}
+
+// binaryBody returns ctx for a call whose body is returned as is, unless binary bodies are decompressed too.
+func (c *Client) binaryBody(ctx context.Context) context.Context {
+	if !c.decompression || c.decompressBinary {
+		return ctx
+	}
+	return withRawBody(ctx)
+}
+
+// wantsDecompression reports whether requests made with ctx ask for compressed responses.
+func (c *Client) wantsDecompression(ctx context.Context) bool {
+	raw, _ := ctx.Value(rawBodyKey{}).(bool)
+	return c.decompression && !raw
+}
+
+// hasBody reports whether a response with status to a request with method may have a body.
+func hasBody(method string, status int) bool {
+	return method != "HEAD" && status != http.StatusNoContent && status != http.StatusNotModified && status/100 != 1
+}
+
+// decompress replaces the body of resp with its decompressed content, if it was sent in a supported coding. Gzip
+// readers are taken from the pool if pooledGzip is set.
+func decompress(resp *http.Response, pooledGzip bool) error {
+	var body io.ReadCloser
+	var err error
+	switch coding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); coding {
+	case "gzip", "x-gzip":
+		body, err = gzipBody(resp.Body, resp.Body, pooledGzip)
+	default:
+		newReader := codingReader(coding)
+		if newReader == nil {
+			return nil
+		}
+		body, err = newReader(resp.Body)
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body = body
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
@@ -0,0 +1,53 @@
+package apiclient
+
+import (
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// WithDefaults returns a client derived from c whose calls apply opts before their own options, such as the base URL,
+// headers and credentials of one tenant of a multi-tenant service:
+//
+//	tenant := c.WithDefaults(WithBaseURL(tenantURL), WithRequestCredentials(BearerToken(tenantToken)))
+//
+// The derived client is cheap to make: it shares everything else with c, from the transport and its connections to
+// the rate limiter, quotas, cache and statistics, and changes made with the setters of either apply to both. The
+// defaults apply to every call, including those taking no RequestOptions: StreamEvents, Dial, Upload, Poll, Pager,
+// Verify and HealthCheck, which leave out a default WithTimeout and are bounded by ctx alone. InvalidateCache and
+// InvalidateNegativeCache apply them too, to find the entries of the derived client's calls. Deriving from a derived
+// client keeps its defaults, followed by opts.
+func (c *Client) WithDefaults(opts ...RequestOption) *Client {
+	defaults := append(append([]RequestOption(nil), c.defaults...), opts...)
+	return &Client{clientState: c.clientState, defaults: defaults}
+}
+
+// WithBaseURL sends a call to base in place of the APIConfig's Host and the client's base URLs.
+func WithBaseURL(base string) RequestOption {
+	return func(o *requestOptions) {
+		o.baseURL = strings.TrimSuffix(base, "/")
+	}
+}
+
+// WithRequestCredentials attaches creds to a call, like ContextWithCredentials.
+func WithRequestCredentials(creds ...Credential) RequestOption {
+	return func(o *requestOptions) {
+		o.credentials = append(o.credentials, creds...)
+	}
+}
+
+// defaultsContext returns ctx carrying the defaults of c, for the calls that take no RequestOptions.
+func (c *Client) defaultsContext(ctx context.Context) context.Context {
+	if len(c.defaults) == 0 {
+		return ctx
+	}
+	return c.newRequestOptions(nil).values(ctx)
+}
+
+type baseURLKey struct{}
+
+// baseURLFromContext returns the base URL requests made with ctx are sent to, or "" for that of their APIConfig.
+func baseURLFromContext(ctx context.Context) string {
+	base, _ := ctx.Value(baseURLKey{}).(string)
+	return base
+}
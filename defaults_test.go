@@ -0,0 +1,196 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// testPages is a PagedRequest of a single page holding a JSON array.
+type testPages struct{}
+
+func (testPages) Params() url.Values { return url.Values{} }
+
+func (testPages) ParsePage(header http.Header, body []byte) ([]json.RawMessage, PagedRequest, error) {
+	var items []json.RawMessage
+	err := json.Unmarshal(body, &items)
+	return items, nil, err
+}
+
+func TestDefaultsApplyWithoutRequestOptions(t *testing.T) {
+	parent := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("%s %s sent to the parent's host", r.Method, r.URL.Path)
+	})
+	headers := make(chan http.Header, 16)
+	tenantSrv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Clone()
+		switch {
+		case r.Header.Get("Accept") == "text/event-stream":
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == "POST":
+			w.Header().Set("Location", "/uploads/1")
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == "PATCH":
+			w.Header().Set("Upload-Offset", "3")
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Write([]byte(`[1]`))
+		}
+	})
+	config := &APIConfig{Host: parent.URL}
+	c := newTestClient(t, WithVerifyProbe(VerifyProbe{Config: config, Request: testParams{}}),
+		WithHealthProbe(HealthProbe{Config: config, Request: testParams{}}))
+	tenant := c.WithDefaults(WithBaseURL(tenantSrv.URL), WithHeader("X-Call", "tenant"),
+		WithRequestCredentials(Credential{Name: "X-Tenant", Value: "acme", In: InHeader}))
+
+	tests := []struct {
+		name string
+		call func(ctx context.Context) error
+	}{
+		{
+			name: "StreamEvents",
+			call: func(ctx context.Context) error {
+				return tenant.StreamEvents(ctx, config, testParams{}, func(Event) error { return nil })
+			},
+		},
+		{
+			name: "CreateUpload",
+			call: func(ctx context.Context) error {
+				_, err := tenant.CreateUpload(ctx, config, testParams{}, 3, nil)
+				return err
+			},
+		},
+		{
+			name: "Upload",
+			call: func(ctx context.Context) error {
+				state := &UploadState{URL: tenantSrv.URL + "/uploads/1", Size: 3}
+				return tenant.Upload(ctx, state, strings.NewReader("abc"), 0)
+			},
+		},
+		{
+			name: "Poll",
+			call: func(ctx context.Context) error {
+				ctx, cancel := context.WithCancel(ctx)
+				defer cancel()
+				return (<-tenant.Poll(ctx, config, testPages{}, 0)).Err
+			},
+		},
+		{
+			name: "Pager",
+			call: func(ctx context.Context) error {
+				p := tenant.NewPager(config, testPages{})
+				for p.Next(ctx) {
+				}
+				return p.Err()
+			},
+		},
+		{
+			name: "GetAllJSON",
+			call: func(ctx context.Context) error {
+				return tenant.GetAllJSON(ctx, config, testPages{}, func(json.RawMessage) error { return nil })
+			},
+		},
+		{
+			name: "Verify",
+			call: func(ctx context.Context) error {
+				_, err := tenant.Verify(ctx)
+				return err
+			},
+		},
+		{
+			name: "HealthCheck",
+			call: func(ctx context.Context) error { return tenant.HealthCheck(ctx) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case h := <-headers:
+				if h.Get("X-Tenant") != "acme" || h.Get("X-Call") != "tenant" {
+					t.Errorf("request sent without the defaults' credentials and headers: %v", h)
+				}
+			default:
+				t.Fatal("no request reached the tenant's host")
+			}
+			for len(headers) > 0 {
+				<-headers
+			}
+		})
+	}
+}
+
+func TestDefaultsApplyToInvalidation(t *testing.T) {
+	var hits int32
+	parent := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("%s %s sent to the parent's host", r.Method, r.URL.Path)
+	})
+	tenantSrv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{}`))
+	})
+	tests := []struct {
+		name       string
+		options    []ClientOption
+		path       string
+		invalidate func(c *Client, ctx context.Context, config *APIConfig)
+	}{
+		{
+			name:    "InvalidateCache of a response",
+			options: []ClientOption{WithCache(NewMemoryCache(10), FixedTTL(time.Hour))},
+			path:    "/found",
+			invalidate: func(c *Client, ctx context.Context, config *APIConfig) {
+				c.InvalidateCache(ctx, config, testParams{})
+			},
+		},
+		{
+			name:    "InvalidateCache of a negative result",
+			options: []ClientOption{WithNegativeCache(time.Hour)},
+			path:    "/missing",
+			invalidate: func(c *Client, ctx context.Context, config *APIConfig) {
+				c.InvalidateCache(ctx, config, testParams{})
+			},
+		},
+		{
+			name:    "InvalidateNegativeCache",
+			options: []ClientOption{WithNegativeCache(time.Hour)},
+			path:    "/missing",
+			invalidate: func(c *Client, ctx context.Context, config *APIConfig) {
+				c.InvalidateNegativeCache(ctx, config, testParams{})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&hits, 0)
+			tenant := newTestClient(t, tt.options...).WithDefaults(WithBaseURL(tenantSrv.URL),
+				WithRequestCredentials(Credential{Name: "X-Tenant", Value: "acme", In: InHeader}))
+			config := &APIConfig{Host: parent.URL, Path: tt.path}
+			ctx := context.Background()
+			var resp interface{}
+			for i := 0; i < 2; i++ {
+				tenant.GetJSON(ctx, config, testParams{}, &resp)
+			}
+			if n := atomic.LoadInt32(&hits); n != 1 {
+				t.Fatalf("server hit %d times before invalidation, want 1", n)
+			}
+			tt.invalidate(tenant, ctx, config)
+			tenant.GetJSON(ctx, config, testParams{}, &resp)
+			if n := atomic.LoadInt32(&hits); n != 2 {
+				t.Errorf("server hit %d times after invalidation, want 2", n)
+			}
+		})
+	}
+}
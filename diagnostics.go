@@ -0,0 +1,122 @@
+package apiclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// maxCaptureBody is how much of a response body a Capture keeps.
+const maxCaptureBody = 64 << 10
+
+// Capture is a recorded request/response exchange. Credential values are redacted.
+type Capture struct {
+	Time           time.Time
+	Duration       time.Duration
+	Method         string
+	URL            string
+	RequestHeader  http.Header
+	StatusCode     int
+	ResponseHeader http.Header
+	// ResponseBody holds at most the first 64KiB of the body that was read.
+	ResponseBody []byte
+	Err          error
+}
+
+// WithDiagnostics configures the client to keep full captures of the last capacity requests that failed (with a
+// transport error or a 4xx/5xx status) or took longer than slow, measured until their response body was closed.
+// Successful fast requests are not recorded. See Captures.
+func WithDiagnostics(capacity int, slow time.Duration) ClientOption {
+	return func(c *Client) error {
+		c.diagnostics = &diagnostics{slow: slow, ring: make([]Capture, 0, capacity), capacity: capacity}
+		return nil
+	}
+}
+
+// Captures returns the retained captures, oldest first.
+func (c *Client) Captures() []Capture {
+	if c.diagnostics == nil {
+		return nil
+	}
+	d := c.diagnostics
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]Capture, 0, len(d.ring))
+	out = append(out, d.ring[d.next:]...)
+	return append(out, d.ring[:d.next]...)
+}
+
+type diagnostics struct {
+	slow     time.Duration
+	capacity int
+	mu       sync.Mutex
+	ring     []Capture
+	next     int
+}
+
+func (d *diagnostics) record(capture Capture) {
+	if d.capacity <= 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.ring) < d.capacity {
+		d.ring = append(d.ring, capture)
+		return
+	}
+	d.ring[d.next] = capture
+	d.next = (d.next + 1) % d.capacity
+}
+
+// observe arranges for the exchange of req to be recorded if it turns out failed or slow.
+func (c *Client) observe(ctx context.Context, req *http.Request, start time.Time, resp *http.Response, err error) {
+	d := c.diagnostics
+	capture := Capture{
+		Time:          start,
+		Method:        req.Method,
+		URL:           c.redactURL(ctx, req.URL.String()),
+		RequestHeader: c.redactHeader(ctx, req.Header),
+		Err:           err,
+	}
+	if err != nil {
+		capture.Duration = time.Since(start)
+		d.record(capture)
+		return
+	}
+	capture.StatusCode = resp.StatusCode
+	capture.ResponseHeader = resp.Header.Clone()
+	body := resp.Body
+	buf := &cappedBuffer{max: maxCaptureBody}
+	var once sync.Once
+	resp.Body = readCloser{io.TeeReader(body, buf), closerFunc(func() error {
+		once.Do(func() {
+			capture.Duration = time.Since(start)
+			if resp.StatusCode >= 400 || capture.Duration > d.slow {
+				capture.ResponseBody = buf.Bytes()
+				d.record(capture)
+			}
+		})
+		return body.Close()
+	})}
+}
+
+// cappedBuffer is a bytes.Buffer that silently drops writes beyond max bytes.
+type cappedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
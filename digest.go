@@ -0,0 +1,259 @@
+package apiclient
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// WithDigestAuth configures the client to authenticate as user with password by HTTP Digest authentication (RFC
+// 7616), for the legacy device and enterprise APIs supporting nothing else. A request answered 401 Unauthorized with
+// a Digest challenge is sent again once with its response, unless its body cannot be replayed; later requests answer
+// the same challenge right away, counting their uses of its nonce, until the server sends a new one. The MD5,
+// SHA-256 and SHA-512-256 algorithms and their -sess variants are supported, with qop=auth or without qop.
+func WithDigestAuth(user, password string) ClientOption {
+	return func(c *Client) error {
+		if user == "" {
+			return errors.New("apiclient: empty digest auth user")
+		}
+		c.digest = &digestAuth{user: user, password: password}
+		return nil
+	}
+}
+
+type digestAuth struct {
+	user, password string
+
+	mu        sync.Mutex
+	challenge *digestChallenge
+	// nc counts the requests sent with the nonce of the challenge.
+	nc uint32
+}
+
+type digestChallenge struct {
+	realm, nonce, opaque, algorithm string
+	// qop is "auth", or empty for servers answering RFC 2069 digests.
+	qop   string
+	stale bool
+}
+
+type digestAnsweredKey struct{}
+
+// authorize sets the Authorization header of req answering the last challenge, if any, and returns its nonce.
+func (d *digestAuth) authorize(req *http.Request) string {
+	d.mu.Lock()
+	ch := d.challenge
+	if ch == nil {
+		d.mu.Unlock()
+		return ""
+	}
+	d.nc++
+	nc := d.nc
+	d.mu.Unlock()
+
+	h := digestHash(ch.algorithm)
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	cnonce := hex.EncodeToString(b[:])
+	uri := req.URL.RequestURI()
+	ha1 := digestHex(h, d.user+":"+ch.realm+":"+d.password)
+	if strings.HasSuffix(strings.ToUpper(ch.algorithm), "-SESS") {
+		ha1 = digestHex(h, ha1+":"+ch.nonce+":"+cnonce)
+	}
+	ha2 := digestHex(h, req.Method+":"+uri)
+
+	var auth strings.Builder
+	fmt.Fprintf(&auth, `Digest username=%q, realm=%q, nonce=%q, uri=%q`, d.user, ch.realm, ch.nonce, uri)
+	if ch.algorithm != "" {
+		fmt.Fprintf(&auth, ", algorithm=%s", ch.algorithm)
+	}
+	if ch.qop == "" {
+		fmt.Fprintf(&auth, `, response="%s"`, digestHex(h, ha1+":"+ch.nonce+":"+ha2))
+	} else {
+		count := fmt.Sprintf("%08x", nc)
+		response := digestHex(h, ha1+":"+ch.nonce+":"+count+":"+cnonce+":"+ch.qop+":"+ha2)
+		fmt.Fprintf(&auth, `, response="%s", qop=%s, nc=%s, cnonce="%s"`, response, ch.qop, count, cnonce)
+	}
+	if ch.opaque != "" {
+		fmt.Fprintf(&auth, ", opaque=%q", ch.opaque)
+	}
+	req.Header.Set("Authorization", auth.String())
+	return ch.nonce
+}
+
+// observe takes the challenge or next nonce of resp, to a request sent answering nonce, and reports whether the
+// request should be sent again with a new response.
+func (d *digestAuth) observe(resp *http.Response, nonce string) bool {
+	if resp.StatusCode != http.StatusUnauthorized {
+		if next := authParams(resp.Header.Get("Authentication-Info"))["nextnonce"]; next != "" {
+			d.mu.Lock()
+			if d.challenge != nil && d.challenge.nonce == nonce {
+				ch := *d.challenge
+				ch.nonce = next
+				d.challenge, d.nc = &ch, 0
+			}
+			d.mu.Unlock()
+		}
+		return false
+	}
+	ch := parseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if ch == nil {
+		return false
+	}
+	d.mu.Lock()
+	d.challenge, d.nc = ch, 0
+	d.mu.Unlock()
+	// A response to the same nonce was rejected for the credentials, unless the server says the nonce went stale.
+	return ch.nonce != nonce || ch.stale
+}
+
+// answerDigest reports whether a request made with ctx and answered resp should be sent again with a digest
+// response, for a client configured with WithDigestAuth.
+func (c *Client) answerDigest(ctx context.Context, resp *http.Response, nonce string, body *requestBody) bool {
+	if !c.digest.observe(resp, nonce) || ctx.Value(digestAnsweredKey{}) != nil {
+		return false
+	}
+	return body == nil || body.replayable
+}
+
+// parseDigestChallenge returns the strongest supported Digest challenge of the WWW-Authenticate headers, or nil.
+func parseDigestChallenge(headers []string) *digestChallenge {
+	var best *digestChallenge
+	for _, header := range headers {
+		for _, challenge := range splitChallenges(header) {
+			scheme, params, _ := strings.Cut(challenge, " ")
+			if !strings.EqualFold(scheme, "Digest") {
+				continue
+			}
+			p := authParams(params)
+			ch := &digestChallenge{realm: p["realm"], nonce: p["nonce"], opaque: p["opaque"], algorithm: p["algorithm"]}
+			ch.stale = strings.EqualFold(p["stale"], "true")
+			if ch.nonce == "" || digestHash(ch.algorithm) == nil {
+				continue
+			}
+			if qop, ok := p["qop"]; ok {
+				for _, q := range strings.Split(qop, ",") {
+					if strings.TrimSpace(q) == "auth" {
+						ch.qop = "auth"
+					}
+				}
+				if ch.qop == "" {
+					// Only auth-int, protecting the body, is offered.
+					continue
+				}
+			}
+			if best == nil || digestStrength(ch.algorithm) > digestStrength(best.algorithm) {
+				best = ch
+			}
+		}
+	}
+	return best
+}
+
+// splitChallenges splits a WWW-Authenticate header into its challenges, each a scheme followed by its parameters.
+func splitChallenges(header string) []string {
+	var challenges []string
+	start := 0
+	quoted := false
+	for i := 0; i < len(header); i++ {
+		switch c := header[i]; {
+		case c == '"' && (i == 0 || header[i-1] != '\\'):
+			quoted = !quoted
+		case c == ',' && !quoted:
+			// A new challenge starts after the comma if its first token is not followed by "=".
+			rest := strings.TrimLeft(header[i+1:], " \t")
+			token := rest
+			if j := strings.IndexAny(rest, " \t=,"); j >= 0 {
+				token = rest[:j]
+			}
+			if token != "" && !strings.HasPrefix(strings.TrimLeft(rest[len(token):], " \t"), "=") {
+				challenges = append(challenges, strings.TrimSpace(header[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(challenges, strings.TrimSpace(header[start:]))
+}
+
+// authParams parses the comma-separated name=value parameters of an authentication header, unquoting their values.
+func authParams(s string) map[string]string {
+	params := map[string]string{}
+	for s != "" {
+		s = strings.TrimLeft(s, " \t,")
+		name, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		rest = strings.TrimLeft(rest, " \t")
+		var value strings.Builder
+		if strings.HasPrefix(rest, `"`) {
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				value.WriteByte(rest[i])
+			}
+			if i < len(rest) {
+				i++
+			}
+			s = rest[i:]
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end < 0 {
+				end = len(rest)
+			}
+			value.WriteString(strings.TrimSpace(rest[:end]))
+			s = rest[end:]
+		}
+		params[name] = value.String()
+	}
+	return params
+}
+
+// digestHash returns the hash of algorithm, or nil if it is not supported.
+func digestHash(algorithm string) func() hash.Hash {
+	switch digestBase(algorithm) {
+	case "", "MD5":
+		return md5.New
+	case "SHA-256":
+		return sha256.New
+	case "SHA-512-256":
+		return sha512.New512_256
+	}
+	return nil
+}
+
+func digestStrength(algorithm string) int {
+	switch digestBase(algorithm) {
+	case "SHA-512-256":
+		return 2
+	case "SHA-256":
+		return 1
+	}
+	return 0
+}
+
+// digestBase returns algorithm without its -sess suffix, upper-cased.
+func digestBase(algorithm string) string {
+	return strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS")
+}
+
+func digestHex(h func() hash.Hash, s string) string {
+	sum := h()
+	sum.Write([]byte(s))
+	return hex.EncodeToString(sum.Sum(nil))
+}
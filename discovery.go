@@ -0,0 +1,198 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// defaultDiscoveryRefresh is how often a ServiceResolver is asked for the backends when no interval is given.
+	defaultDiscoveryRefresh = 30 * time.Second
+	// discoveryTimeout bounds each resolution.
+	discoveryTimeout = 10 * time.Second
+)
+
+// ServiceResolver supplies the current base URLs of the API's backends, such as those registered in a service
+// discovery system, in order of preference. Implementations must be safe for concurrent use.
+type ServiceResolver interface {
+	Resolve(ctx context.Context) ([]string, error)
+}
+
+// WithServiceDiscovery configures the client to spread requests over the base URLs supplied by r, according to
+// strategy and policy as with WithLoadBalancing, and to ask r for them again every refresh (30 seconds if not
+// positive). The backends are resolved once when the client is created, which fails if r does; a later resolution
+// that fails or supplies no backend leaves the current ones in place. Backends that remain across a refresh keep
+// their health.
+func WithServiceDiscovery(r ServiceResolver, refresh time.Duration, strategy BalanceStrategy, policy FailoverPolicy) ClientOption {
+	return func(c *Client) error {
+		if r == nil {
+			return errors.New("apiclient: nil service resolver")
+		}
+		if refresh <= 0 {
+			refresh = defaultDiscoveryRefresh
+		}
+		// The backends are filled in by startDiscovery, once the options are applied.
+		c.endpoints = newEndpointSet(strategy, policy)
+		c.discovery = r
+		c.discoveryRefresh = refresh
+		return nil
+	}
+}
+
+// startDiscovery resolves the backends and schedules their refresh.
+func (c *Client) startDiscovery() error {
+	if err := c.discover(); err != nil {
+		return err
+	}
+	c.scheduler.every(c.discoveryRefresh, c.discoveryRefresh, func() {
+		if !atomic.CompareAndSwapInt32(&c.discovering, 0, 1) {
+			return
+		}
+		go func() {
+			defer atomic.StoreInt32(&c.discovering, 0)
+			if err := c.discover(); err != nil {
+				c.log("service discovery failed", map[string]interface{}{"error": err})
+			}
+		}()
+	})
+	return nil
+}
+
+// discover asks the resolver for the backends and makes them the client's endpoints.
+func (c *Client) discover() error {
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+	bases, err := c.discovery.Resolve(ctx)
+	if err != nil {
+		return fmt.Errorf("apiclient: service discovery: %w", err)
+	}
+	if len(bases) == 0 {
+		return errors.New("apiclient: service discovery: no backend")
+	}
+	c.endpoints.replace(bases)
+	return nil
+}
+
+// SRVResolver is a ServiceResolver looking backends up in DNS SRV records, such as _api._tcp.example.com.
+type SRVResolver struct {
+	// Service, Proto and Name are those of net.LookupSRV. With an empty Service and Proto, Name is looked up as is.
+	Service string
+	Proto   string
+	Name    string
+	// Scheme of the base URLs. Defaults to https.
+	Scheme string
+	// Resolver looks up the records. Defaults to net.DefaultResolver.
+	Resolver *net.Resolver
+}
+
+// Resolve returns the base URLs of the targets of the SRV records, in the order of their priority and weight.
+func (r SRVResolver) Resolve(ctx context.Context) ([]string, error) {
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	_, records, err := resolver.LookupSRV(ctx, r.Service, r.Proto, r.Name)
+	if err != nil {
+		return nil, err
+	}
+	bases := make([]string, 0, len(records))
+	for _, rec := range records {
+		bases = append(bases, baseURL(r.Scheme, strings.TrimSuffix(rec.Target, "."), int(rec.Port)))
+	}
+	return bases, nil
+}
+
+// ConsulResolver is a ServiceResolver asking the health API of a Consul agent for the instances of a service that
+// pass their health checks.
+type ConsulResolver struct {
+	// Address of the Consul agent. Defaults to http://127.0.0.1:8500.
+	Address string
+	Service string
+	// Tag, if set, selects the instances of the service with that tag.
+	Tag string
+	// Datacenter defaults to that of the agent.
+	Datacenter string
+	// Token is the ACL token of the requests, if Consul requires one.
+	Token string
+	// Scheme of the base URLs. Defaults to https.
+	Scheme string
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// consulServiceEntry is the part of an entry of Consul's /v1/health/service response that locates an instance.
+type consulServiceEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    int
+	}
+}
+
+// Resolve returns the base URLs of the healthy instances of the service.
+func (r ConsulResolver) Resolve(ctx context.Context) ([]string, error) {
+	address := r.Address
+	if address == "" {
+		address = "http://127.0.0.1:8500"
+	}
+	q := url.Values{"passing": {"true"}}
+	if r.Tag != "" {
+		q.Set("tag", r.Tag)
+	}
+	if r.Datacenter != "" {
+		q.Set("dc", r.Datacenter)
+	}
+	u := strings.TrimSuffix(address, "/") + "/v1/health/service/" + url.PathEscape(r.Service) + "?" + q.Encode()
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.Token != "" {
+		req.Header.Set("X-Consul-Token", r.Token)
+	}
+	client := r.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	var entries []consulServiceEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, err
+	}
+	bases := make([]string, 0, len(entries))
+	for _, e := range entries {
+		host := e.Service.Address
+		if host == "" {
+			host = e.Node.Address
+		}
+		bases = append(bases, baseURL(r.Scheme, host, e.Service.Port))
+	}
+	return bases, nil
+}
+
+// baseURL returns the base URL of host and port with scheme, https if empty.
+func baseURL(scheme, host string, port int) string {
+	if scheme == "" {
+		scheme = "https"
+	}
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port))
+}
@@ -0,0 +1,217 @@
+package apiclient
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// DNSServers returns a resolver querying the given DNS servers, given as "host" or "host:port", in turn instead of
+// those of the system.
+func DNSServers(servers ...string) *net.Resolver {
+	addrs := make([]string, len(servers))
+	for i, s := range servers {
+		if _, _, err := net.SplitHostPort(s); err != nil {
+			s = net.JoinHostPort(s, "53")
+		}
+		addrs[i] = s
+	}
+	var next uint32
+	var d net.Dialer
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			if len(addrs) == 0 {
+				return nil, errors.New("apiclient: no DNS servers")
+			}
+			return d.DialContext(ctx, network, addrs[int(atomic.AddUint32(&next, 1)-1)%len(addrs)])
+		},
+	}
+}
+
+// DNSOverHTTPS returns a resolver sending its queries to the DNS-over-HTTPS (RFC 8484) endpoint at url, such as
+// https://1.1.1.1/dns-query, over client (http.DefaultClient if nil). The endpoint's own host name, if it has one,
+// is resolved by the system.
+func DNSOverHTTPS(url string, client *http.Client) *net.Resolver {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return &dohConn{ctx: ctx, url: url, client: client}, nil
+		},
+	}
+}
+
+// WithDNSResolver configures the client to resolve the hosts it connects to with r, such as one returned by
+// DNSServers or DNSOverHTTPS. Like WithProxy, it applies to the *http.Transport of the HTTP client.
+func WithDNSResolver(r *net.Resolver) ClientOption {
+	return func(c *Client) error {
+		if r == nil {
+			return errors.New("apiclient: nil DNS resolver")
+		}
+		c.dnsResolver = r
+		return c.installDialer()
+	}
+}
+
+// WithDNSCache configures the client to cache the addresses of the hosts it connects to for ttl, sparing a lookup
+// per new connection. The resolver does not report record TTLs, so ttl bounds them instead: it should not exceed
+// the TTLs of the API's records. An entry is dropped early when none of its addresses can be connected to.
+func WithDNSCache(ttl time.Duration) ClientOption {
+	return func(c *Client) error {
+		if ttl <= 0 {
+			return fmt.Errorf("apiclient: invalid DNS cache TTL %v", ttl)
+		}
+		c.dnsCache = &dnsCache{ttl: ttl, entries: map[string]dnsEntry{}}
+		return c.installDialer()
+	}
+}
+
+// installDialer makes the base transport dial through the client's resolver and DNS cache.
+func (c *Client) installDialer() error {
+	t, err := c.baseTransport()
+	if err != nil {
+		return err
+	}
+	t.DialContext = c.dialContext
+	return nil
+}
+
+// dialContext dials addr, resolving its host with the client's resolver, through its DNS cache if any.
+func (c *Client) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Resolver: c.dnsResolver}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || c.dnsCache == nil || net.ParseIP(host) != nil {
+		return d.DialContext(ctx, network, addr)
+	}
+	ips, err := c.dnsCache.lookup(ctx, c.dnsResolver, host, c.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		var conn net.Conn
+		if conn, err = d.DialContext(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+			return conn, nil
+		}
+	}
+	c.dnsCache.forget(host)
+	return nil, err
+}
+
+type dnsCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+}
+
+type dnsEntry struct {
+	ips     []string
+	expires time.Time
+}
+
+// lookup returns the addresses of host, resolving it with r (the default resolver if nil) unless cached.
+func (dc *dnsCache) lookup(ctx context.Context, r *net.Resolver, host string, now time.Time) ([]string, error) {
+	dc.mu.Lock()
+	e, ok := dc.entries[host]
+	dc.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.ips, nil
+	}
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	ips, err := r.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	dc.mu.Lock()
+	dc.entries[host] = dnsEntry{ips: ips, expires: now.Add(dc.ttl)}
+	dc.mu.Unlock()
+	return ips, nil
+}
+
+func (dc *dnsCache) forget(host string) {
+	dc.mu.Lock()
+	delete(dc.entries, host)
+	dc.mu.Unlock()
+}
+
+// dohConn is the connection of a DNS-over-HTTPS resolver. It is not a net.PacketConn, so the resolver writes its
+// queries with the two-byte length prefix of DNS over TCP, and each one is posted to the endpoint as it is read.
+type dohConn struct {
+	ctx    context.Context
+	url    string
+	client *http.Client
+	out    bytes.Buffer
+	in     bytes.Buffer
+}
+
+func (dc *dohConn) Write(p []byte) (int, error) {
+	return dc.out.Write(p)
+}
+
+func (dc *dohConn) Read(p []byte) (int, error) {
+	if dc.in.Len() == 0 {
+		if err := dc.exchange(); err != nil {
+			return 0, err
+		}
+	}
+	return dc.in.Read(p)
+}
+
+// exchange posts the pending query and buffers the answer, length-prefixed.
+func (dc *dohConn) exchange() error {
+	data := dc.out.Bytes()
+	if len(data) < 2 {
+		return io.ErrUnexpectedEOF
+	}
+	n := int(data[0])<<8 | int(data[1])
+	if len(data) < 2+n {
+		return io.ErrUnexpectedEOF
+	}
+	query := data[2 : 2+n]
+	req, err := http.NewRequest("POST", dc.url, bytes.NewReader(query))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := dc.client.Do(req.WithContext(dc.ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	answer, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16-1))
+	if err != nil {
+		return err
+	}
+	dc.out.Next(2 + n)
+	dc.in.Write([]byte{byte(len(answer) >> 8), byte(len(answer))})
+	dc.in.Write(answer)
+	return nil
+}
+
+func (dc *dohConn) Close() error                     { return nil }
+func (dc *dohConn) LocalAddr() net.Addr              { return dohAddr(dc.url) }
+func (dc *dohConn) RemoteAddr() net.Addr             { return dohAddr(dc.url) }
+func (dc *dohConn) SetDeadline(time.Time) error      { return nil }
+func (dc *dohConn) SetReadDeadline(time.Time) error  { return nil }
+func (dc *dohConn) SetWriteDeadline(time.Time) error { return nil }
+
+type dohAddr string
+
+func (a dohAddr) Network() string { return "https" }
+func (a dohAddr) String() string  { return string(a) }
@@ -0,0 +1,97 @@
+package apiclient
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+
+	"golang.org/x/net/context"
+)
+
+// Progress reports how much of a download has been written.
+type Progress struct {
+	Bytes int64
+	// Total is the size announced by the server, or -1 if it is unknown.
+	Total int64
+}
+
+// Percent returns the share of the download written so far, from 0 to 100, or -1 if the total size is unknown.
+func (p Progress) Percent() float64 {
+	if p.Total < 0 {
+		return -1
+	}
+	if p.Total == 0 {
+		return 100
+	}
+	return float64(p.Bytes) * 100 / float64(p.Total)
+}
+
+// DownloadFile streams the response of apiReq to the file at path, calling progress (if not nil) after every write.
+// The data is written to a temporary file in the same directory, synced to disk and only then renamed to path, so path
+// never holds a partial download; on error the temporary file is removed. The file is created with the permissions
+// of os.Create. A non-2xx response fails with an *HTTPError.
+func (c *Client) DownloadFile(ctx context.Context, config *APIConfig, apiReq APIRequest, path string, progress func(Progress), opts ...RequestOption) error {
+	o := c.newRequestOptions(opts)
+	ctx, cancel := o.context(ctx, config)
+	defer cancel()
+	ctx = c.withRequestID(c.binaryBody(ctx))
+	resp, err := c.getStreamed(ctx, config, apiReq)
+	if err != nil {
+		return c.requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+	if o.meta != nil {
+		o.meta.fill(resp)
+	}
+	if resp.StatusCode/100 != 2 {
+		return c.requestError(ctx, &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status})
+	}
+
+	tmp, err := createPartial(path)
+	if err != nil {
+		return c.requestError(ctx, err)
+	}
+	defer os.Remove(tmp.Name())
+	var w io.Writer = tmp
+	if progress != nil {
+		w = &progressWriter{w: tmp, p: Progress{Total: resp.ContentLength}, report: progress}
+	}
+	_, err = copyBuffer(w, c.throttled(ctx, c.downloadThrottle, resp.Body))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	return c.requestError(ctx, err)
+}
+
+// createPartial creates the temporary file of a download to path, next to it. Unlike those of os.CreateTemp, which
+// are 0600, its permissions are those os.Create gives, 0666 less the umask, since it is renamed to path.
+func createPartial(path string) (*os.File, error) {
+	for i := 0; ; i++ {
+		f, err := os.OpenFile(fmt.Sprintf("%s.part%d", path, rand.Uint32()), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(err) && i < 100 {
+			continue
+		}
+		return f, err
+	}
+}
+
+// progressWriter reports the bytes written through it.
+type progressWriter struct {
+	w      io.Writer
+	p      Progress
+	report func(Progress)
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	n, err := pw.w.Write(b)
+	pw.p.Bytes += int64(n)
+	pw.report(pw.p)
+	return n, err
+}
@@ -0,0 +1,64 @@
+package apiclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestDownloadFileMode(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data"))
+	})
+	// The mode os.Create gives new files, 0666 less the umask.
+	ref, err := os.Create(filepath.Join(t.TempDir(), "ref"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref.Close()
+	info, err := os.Stat(ref.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := info.Mode().Perm()
+
+	tests := []struct {
+		name     string
+		existing bool
+	}{
+		{name: "new file", existing: false},
+		{name: "replaced file", existing: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "file")
+			if tt.existing {
+				if err := ioutil.WriteFile(path, []byte("old"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c := newTestClient(t)
+			if err := c.DownloadFile(context.Background(), &APIConfig{Host: srv.URL}, testParams{}, path, nil); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != want {
+				t.Errorf("mode = %v, want %v", info.Mode().Perm(), want)
+			}
+			if data, _ := ioutil.ReadFile(path); string(data) != "data" {
+				t.Errorf("file holds %q", data)
+			}
+			if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 {
+				t.Errorf("%d files left in the directory, want 1", len(entries))
+			}
+		})
+	}
+}
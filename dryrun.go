@@ -0,0 +1,57 @@
+package apiclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// DryRunHandler receives the requests of a client in dry-run mode, fully built and authenticated, and returns the
+// response to pretend they got, or nil for the default one. It may read the request body.
+type DryRunHandler func(req *http.Request) *http.Response
+
+// WithDryRun configures the client to hand every request to handler instead of sending it, to preview what
+// destructive operations would do or audit what would be sent. Without a handler, requests are reported to the log
+// hook as "dry run" events. Calls get the handler's response, or else a 200 with the JSON body {}. Responses are
+// never cached.
+func WithDryRun(handler DryRunHandler) ClientOption {
+	return func(c *Client) error {
+		c.dryRun = true
+		c.dryRunHandler = handler
+		return nil
+	}
+}
+
+// dryRunResponse returns the pretended response to req.
+func (c *Client) dryRunResponse(req *http.Request) *http.Response {
+	if req.Body == nil {
+		req.Body = http.NoBody
+	}
+	var resp *http.Response
+	if c.dryRunHandler != nil {
+		resp = c.dryRunHandler(req)
+	} else {
+		c.log("dry run", map[string]interface{}{"method": req.Method, "url": c.redactURL(req.Context(), req.URL.String())})
+	}
+	req.Body.Close()
+	if resp == nil {
+		resp = &http.Response{
+			Status:        "200 OK",
+			StatusCode:    http.StatusOK,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": {"application/json"}},
+			Body:          io.NopCloser(bytes.NewReader([]byte("{}"))),
+			ContentLength: 2,
+		}
+	}
+	if resp.Header == nil {
+		resp.Header = http.Header{}
+	}
+	if resp.Body == nil {
+		resp.Body = http.NoBody
+	}
+	resp.Request = req
+	return resp
+}
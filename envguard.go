@@ -0,0 +1,54 @@
+package apiclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// ErrEnvironmentMismatch is returned for write requests made with credentials that belong to another environment
+// than the one the client runs in.
+var ErrEnvironmentMismatch = errors.New("apiclient: credentials do not belong to this environment")
+
+// EnvironmentDetector names the environment ("production", "sandbox", ...) credentials belong to, for example from a
+// key prefix such as "sk_live_". It returns "" if it cannot tell.
+type EnvironmentDetector func(creds []Credential) string
+
+// WithEnvironmentGuard configures the client to refuse requests with unsafe methods (anything but GET, HEAD, OPTIONS
+// and TRACE) with ErrEnvironmentMismatch when detector attributes the request's credentials to an environment other
+// than expected, such as a production key used from staging or a test key used in production.
+func WithEnvironmentGuard(expected string, detector EnvironmentDetector) ClientOption {
+	return func(c *Client) error {
+		c.environmentGuard = &environmentGuard{expected: expected, detect: detector}
+		return nil
+	}
+}
+
+type environmentGuard struct {
+	expected string
+	detect   EnvironmentDetector
+}
+
+// checkEnvironment rejects a request with method if it is unsafe and its credentials belong to the wrong
+// environment.
+func (c *Client) checkEnvironment(ctx context.Context, method string) error {
+	g := c.environmentGuard
+	if g == nil || isSafeMethod(method) {
+		return nil
+	}
+	if env := g.detect(c.requestCredentials(ctx)); env != "" && env != g.expected {
+		return fmt.Errorf("%w: %s credentials in %s", ErrEnvironmentMismatch, env, g.expected)
+	}
+	return nil
+}
+
+// isSafeMethod reports whether method is read-only as defined by RFC 7231.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
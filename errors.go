@@ -0,0 +1,74 @@
+package apiclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// HTTPError reports a response whose status code the client treats as a failure.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPError) Error() string {
+	if e.Status != "" {
+		return fmt.Sprintf("apiclient: unexpected response status %s", e.Status)
+	}
+	return fmt.Sprintf("apiclient: unexpected response status %d", e.StatusCode)
+}
+
+// statusOf returns the status code of the HTTPError in err's chain, or 0 if there is none.
+func statusOf(err error) int {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound reports whether err is a 404 Not Found or 410 Gone response.
+func IsNotFound(err error) bool {
+	status := statusOf(err)
+	return status == http.StatusNotFound || status == http.StatusGone
+}
+
+// IsRateLimited reports whether err is a 429 Too Many Requests response.
+func IsRateLimited(err error) bool {
+	return statusOf(err) == http.StatusTooManyRequests
+}
+
+// IsUnauthorized reports whether err is a 401 Unauthorized or 403 Forbidden response, meaning the credentials are
+// missing, invalid or insufficient.
+func IsUnauthorized(err error) bool {
+	status := statusOf(err)
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
+
+// IsTimeout reports whether err is a call that ran out of time: its deadline passed, the network timed out, or the
+// server answered 408 Request Timeout or 504 Gateway Timeout.
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	status := statusOf(err)
+	return status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout
+}
+
+// IsTemporary reports whether err is a failure that may not recur when the call is retried later: a timeout, a rate
+// limit, a full bulkhead, load shedding, or a 5xx response other than 501 Not Implemented.
+func IsTemporary(err error) bool {
+	if IsTimeout(err) || IsRateLimited(err) || errors.Is(err, ErrBulkheadFull) || errors.Is(err, ErrOverloaded) {
+		return true
+	}
+	status := statusOf(err)
+	return status >= 500 && status != http.StatusNotImplemented
+}
@@ -0,0 +1,37 @@
+package apiclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestIsTemporary(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "deadline", err: context.DeadlineExceeded, want: true},
+		{name: "cancelled", err: context.Canceled, want: false},
+		{name: "rate limited", err: &HTTPError{StatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "unavailable", err: &HTTPError{StatusCode: http.StatusServiceUnavailable}, want: true},
+		{name: "not implemented", err: &HTTPError{StatusCode: http.StatusNotImplemented}, want: false},
+		{name: "not found", err: &HTTPError{StatusCode: http.StatusNotFound}, want: false},
+		{name: "bulkhead full", err: ErrBulkheadFull, want: true},
+		{name: "overloaded", err: ErrOverloaded, want: true},
+		{name: "wrapped overloaded", err: fmt.Errorf("call: %w", ErrOverloaded), want: true},
+		{name: "other", err: errors.New("boom"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTemporary(tt.err); got != tt.want {
+				t.Errorf("IsTemporary(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package apiclient
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// FailoverPolicy controls how a client configured WithBaseURLs moves between its endpoints.
+type FailoverPolicy struct {
+	// FailureThreshold is the number of consecutive failures after which an endpoint is considered unhealthy.
+	// Defaults to 1.
+	FailureThreshold int
+	// RecoveryInterval is how long an unhealthy endpoint is avoided before it is tried again. Defaults to 30 seconds.
+	RecoveryInterval time.Duration
+}
+
+// BalanceStrategy decides how requests are spread over the healthy endpoints of a client.
+type BalanceStrategy int
+
+const (
+	// PrimaryFirst sends every request to the first healthy endpoint, using the others only as fallbacks.
+	PrimaryFirst BalanceStrategy = iota
+	// RoundRobin rotates requests over the healthy endpoints.
+	RoundRobin
+	// LeastPending sends each request to the healthy endpoint with the fewest requests in flight.
+	LeastPending
+)
+
+// WithBaseURLs configures the client to send requests to baseURLs instead of each APIConfig's Host, preferring
+// them in order. A request that fails to connect or gets a 5xx response is retried against the next healthy
+// endpoint. Endpoints marked unhealthy by policy are tried again after its RecoveryInterval, so traffic returns to
+// the primary once it recovers.
+func WithBaseURLs(baseURLs []string, policy FailoverPolicy) ClientOption {
+	return WithLoadBalancing(baseURLs, PrimaryFirst, policy)
+}
+
+// WithLoadBalancing configures the client to spread requests over a set of equivalent baseURLs according to
+// strategy. Each endpoint has its own circuit: after policy's FailureThreshold consecutive failures it is taken out
+// of rotation for RecoveryInterval, then let back in and taken out again on its next failure until it succeeds.
+// Requests that fail on one endpoint are retried on the next, as with WithBaseURLs.
+func WithLoadBalancing(baseURLs []string, strategy BalanceStrategy, policy FailoverPolicy) ClientOption {
+	return func(c *Client) error {
+		if len(baseURLs) == 0 {
+			return errors.New("apiclient: at least one base URL is required")
+		}
+		c.endpoints = newEndpointSet(strategy, policy)
+		c.endpoints.replace(baseURLs)
+		return nil
+	}
+}
+
+func newEndpointSet(strategy BalanceStrategy, policy FailoverPolicy) *endpointSet {
+	if policy.FailureThreshold <= 0 {
+		policy.FailureThreshold = 1
+	}
+	if policy.RecoveryInterval <= 0 {
+		policy.RecoveryInterval = 30 * time.Second
+	}
+	return &endpointSet{policy: policy, strategy: strategy}
+}
+
+// endpointSet tracks the health of a client's base URLs.
+type endpointSet struct {
+	policy   FailoverPolicy
+	strategy BalanceStrategy
+
+	mu   sync.Mutex
+	list []*endpoint
+	next int
+}
+
+type endpoint struct {
+	base      string
+	failures  int
+	downUntil time.Time
+	pending   int
+}
+
+// snapshot returns the endpoints of the set.
+func (es *endpointSet) snapshot() []*endpoint {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	return append([]*endpoint(nil), es.list...)
+}
+
+// primary returns the first base URL of the set.
+func (es *endpointSet) primary() string {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	return es.list[0].base
+}
+
+// replace makes bases the endpoints of the set, in order. Endpoints already in the set keep their health.
+func (es *endpointSet) replace(bases []string) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	known := make(map[string]*endpoint, len(es.list))
+	for _, ep := range es.list {
+		known[ep.base] = ep
+	}
+	list := make([]*endpoint, 0, len(bases))
+	for _, base := range bases {
+		ep := known[base]
+		if ep == nil {
+			ep = &endpoint{base: base}
+		}
+		list = append(list, ep)
+	}
+	es.list = list
+}
+
+// candidates returns the endpoints to try in order: healthy ones first, ordered by the balancing strategy, then
+// those still considered down. prefer, if not nil, comes first among the healthy or the down ones.
+func (es *endpointSet) candidates(now time.Time, prefer *endpoint) []*endpoint {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	var up, down []*endpoint
+	for _, ep := range es.list {
+		if now.Before(ep.downUntil) {
+			down = append(down, ep)
+		} else {
+			up = append(up, ep)
+		}
+	}
+	switch es.strategy {
+	case RoundRobin:
+		if len(up) > 0 {
+			i := es.next % len(up)
+			es.next++
+			up = append(up[i:], up[:i]...)
+		}
+	case LeastPending:
+		sort.SliceStable(up, func(i, j int) bool { return up[i].pending < up[j].pending })
+	}
+	if prefer != nil {
+		first := func(eps []*endpoint) {
+			for i, ep := range eps {
+				if ep == prefer {
+					copy(eps[1:i+1], eps[:i])
+					eps[0] = ep
+				}
+			}
+		}
+		first(up)
+		first(down)
+	}
+	return append(up, down...)
+}
+
+// track adjusts the number of requests in flight to ep by delta.
+func (es *endpointSet) track(ep *endpoint, delta int) {
+	es.mu.Lock()
+	ep.pending += delta
+	es.mu.Unlock()
+}
+
+// report records the outcome of a request to ep.
+func (es *endpointSet) report(ep *endpoint, ok bool, now time.Time) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if ok {
+		ep.failures = 0
+		ep.downUntil = time.Time{}
+		return
+	}
+	ep.failures++
+	if ep.failures >= es.policy.FailureThreshold {
+		ep.downUntil = now.Add(es.policy.RecoveryInterval)
+	}
+}
+
+// do calls try with each candidate endpoint, prefer first if not nil, until one neither fails to connect nor answers
+// with a 5xx status. The last endpoint's outcome is returned if they all fail. Without failover only the best
+// candidate is tried. An error of the client itself, such as a full bulkhead, is returned right away, and not held
+// against the endpoint.
+func (es *endpointSet) do(ctx context.Context, clk Clock, prefer *endpoint, failover bool, try func(base string) (*http.Response, error)) (*http.Response, error) {
+	var resp *http.Response
+	var err error
+	candidates := es.candidates(clk.Now(), prefer)
+	if !failover {
+		candidates = candidates[:1]
+	}
+	for _, ep := range candidates {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		es.track(ep, 1)
+		resp, err = try(ep.base)
+		es.track(ep, -1)
+		if ctx.Err() != nil {
+			return resp, err
+		}
+		if err != nil && !isTransportError(err) {
+			// The client turned the request down itself, which says nothing of the endpoint's health.
+			return resp, err
+		}
+		ok := err == nil && resp.StatusCode < 500
+		es.report(ep, ok, clk.Now())
+		if ok {
+			return resp, nil
+		}
+	}
+	return resp, err
+}
+
+// isTransportError reports whether err is a failure to exchange a request with an endpoint, as opposed to an error of
+// the client turning the request down before it was sent.
+func isTransportError(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne)
+}
@@ -0,0 +1,141 @@
+package apiclient
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// rejection is a request the client turns down itself, before it reaches any endpoint.
+type rejection struct {
+	name    string
+	options []ClientOption
+	// prepare readies the client to reject the request, such as by filling its bulkhead.
+	prepare func(c *Client)
+	method  string
+	config  APIConfig
+	want    error
+}
+
+func rejections() []rejection {
+	noSubject := func(ctx context.Context) string { return "mallory" }
+	return []rejection{
+		{name: "read-only", options: []ClientOption{WithReadOnly()}, method: "POST", want: ErrReadOnly},
+		{name: "path", method: "GET", config: APIConfig{Path: "/items/{id}"}},
+		{
+			name:    "impersonation",
+			options: []ClientOption{WithImpersonation("X-On-Behalf-Of", noSubject, []string{"alice"})},
+			method:  "GET",
+			want:    ErrSubjectNotAllowed,
+		},
+		{
+			name:    "bulkhead",
+			options: []ClientOption{WithBulkhead(PathPrefix("/"), 1, 0)},
+			prepare: func(c *Client) { c.bulkheads[0].slots <- struct{}{} },
+			method:  "GET",
+			config:  APIConfig{Path: "/"},
+			want:    ErrBulkheadFull,
+		},
+		{
+			name:    "quota",
+			options: []ClientOption{WithQuota(Quota{Limit: 1, Window: QuotaDaily})},
+			prepare: func(c *Client) { c.takeQuota(context.Background()) },
+			method:  "GET",
+			want:    ErrQuotaExhausted,
+		},
+	}
+}
+
+// testRejections sends each rejected request to a client configured with endpoints, and checks that no endpoint was
+// tried or marked down.
+func testRejections(t *testing.T, endpoints func(bases []string) ClientOption) {
+	var hits int32
+	handler := func(w http.ResponseWriter, r *http.Request) { atomic.AddInt32(&hits, 1) }
+	bases := []string{newTestServer(t, handler).URL, newTestServer(t, handler).URL}
+	for _, tt := range rejections() {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&hits, 0)
+			c := newTestClient(t, append([]ClientOption{endpoints(bases)}, tt.options...)...)
+			if tt.prepare != nil {
+				tt.prepare(c)
+			}
+			for i := 0; i < 3; i++ {
+				resp, err := c.send(context.Background(), tt.method, &tt.config, testParams{}, nil, nil)
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("request not rejected")
+				}
+				if tt.want != nil && !errors.Is(err, tt.want) {
+					t.Fatalf("got %v, want %v", err, tt.want)
+				}
+			}
+			if n := atomic.LoadInt32(&hits); n != 0 {
+				t.Errorf("endpoints got %d requests, want none", n)
+			}
+			for _, ep := range c.endpoints.snapshot() {
+				if ep.failures != 0 || !ep.downUntil.IsZero() {
+					t.Errorf("endpoint %s has %d failures, down until %v", ep.base, ep.failures, ep.downUntil)
+				}
+			}
+		})
+	}
+}
+
+func TestFailoverIgnoresRejections(t *testing.T) {
+	testRejections(t, func(bases []string) ClientOption { return WithBaseURLs(bases, FailoverPolicy{}) })
+}
+
+func TestFailoverCountsTransportErrors(t *testing.T) {
+	down := newTestServer(t, func(http.ResponseWriter, *http.Request) {})
+	down.Close()
+	var hits int32
+	up := newTestServer(t, func(w http.ResponseWriter, r *http.Request) { atomic.AddInt32(&hits, 1) })
+	tests := []struct {
+		name         string
+		bases        []string
+		wantFailures []int
+	}{
+		{name: "connection refused", bases: []string{down.URL, up.URL}, wantFailures: []int{1, 0}},
+		{name: "healthy", bases: []string{up.URL, down.URL}, wantFailures: []int{0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&hits, 0)
+			c := newTestClient(t, WithBaseURLs(tt.bases, FailoverPolicy{}))
+			resp, err := c.send(context.Background(), "GET", &APIConfig{}, testParams{}, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if atomic.LoadInt32(&hits) != 1 {
+				t.Errorf("healthy endpoint got %d requests, want 1", hits)
+			}
+			for i, ep := range c.endpoints.snapshot() {
+				if ep.failures != tt.wantFailures[i] {
+					t.Errorf("endpoint %d has %d failures, want %d", i, ep.failures, tt.wantFailures[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadBalancingIgnoresRejections(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy BalanceStrategy
+	}{
+		{"primary-first", PrimaryFirst},
+		{"round-robin", RoundRobin},
+		{"least-pending", LeastPending},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRejections(t, func(bases []string) ClientOption {
+				return WithLoadBalancing(bases, tt.strategy, FailoverPolicy{})
+			})
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package apiclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// WithFallback configures the client to answer the GetJSON, GetXML and GetBinary calls of the registered endpoint
+// named endpoint with fallback when the API is unavailable, such as with a default or the last value known to be good,
+// in place of an error. The API is unavailable when a call fails after its retries and failovers with a network error,
+// a timeout, a 429 or 5xx response other than 501, a full bulkhead or load shedding; the error of a call whose
+// context ended, or of a call the API answered, is returned as is. The fallback's value is stored in the response of
+// the call, which must be a *T; T is BinaryResponse for GetBinary calls. Registering a fallback twice for an endpoint
+// is an error.
+func WithFallback[T any](endpoint string, fallback func(ctx context.Context, apiReq APIRequest) (T, error)) ClientOption {
+	return func(c *Client) error {
+		if fallback == nil {
+			return errors.New("apiclient: nil fallback")
+		}
+		if _, ok := c.fallbacks[endpoint]; ok {
+			return fmt.Errorf("apiclient: endpoint %q already has a fallback", endpoint)
+		}
+		if c.fallbacks == nil {
+			c.fallbacks = map[string]fallbackFunc{}
+		}
+		c.fallbacks[endpoint] = func(ctx context.Context, apiReq APIRequest, resp interface{}) error {
+			p, ok := resp.(*T)
+			if !ok {
+				return fmt.Errorf("apiclient: fallback of endpoint %q needs a %T response, not %T", endpoint, p, resp)
+			}
+			v, err := fallback(ctx, apiReq)
+			if err != nil {
+				return err
+			}
+			*p = v
+			return nil
+		}
+		return nil
+	}
+}
+
+// fallbackFunc stores the fallback value of a call in resp.
+type fallbackFunc func(ctx context.Context, apiReq APIRequest, resp interface{}) error
+
+// withFallback makes a call of endpoint e with call, answering it with the endpoint's fallback, if it has one, when
+// the API is unavailable.
+func (e *Endpoint) withFallback(ctx context.Context, apiReq APIRequest, resp interface{}, opts []RequestOption, call func(opts []RequestOption) error) error {
+	fallback := e.client.fallbacks[e.Name]
+	if fallback == nil {
+		return call(opts)
+	}
+	err := call(append(append([]RequestOption(nil), opts...), failOnOutage))
+	if err == nil || ctx.Err() != nil || !isOutage(err) {
+		return err
+	}
+	e.client.log("fallback used", map[string]interface{}{"endpoint": e.Name, "error": err.Error()})
+	return fallback(ctx, apiReq, resp)
+}
+
+// failOnOutage fails a call answered with a status meaning the API is unavailable with an *HTTPError, instead of
+// handing the response to the caller.
+func failOnOutage(o *requestOptions) {
+	o.failOnOutage = true
+}
+
+type failOnOutageKey struct{}
+
+// outageError returns the *HTTPError of resp if the call made with ctx asked to fail on outages and resp reports one.
+func outageError(ctx context.Context, resp *http.Response) error {
+	if fail, _ := ctx.Value(failOnOutageKey{}).(bool); !fail {
+		return nil
+	}
+	if err := (&HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}); IsTemporary(err) {
+		return err
+	}
+	return nil
+}
+
+// isOutage reports whether err means the API is unavailable.
+func isOutage(err error) bool {
+	var ne net.Error
+	return IsTemporary(err) || errors.As(err, &ne)
+}
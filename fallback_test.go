@@ -0,0 +1,200 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestFallbackAnswersEndpointCalls(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(e *Endpoint) (string, error)
+	}{
+		{
+			name: "GetJSON",
+			call: func(e *Endpoint) (string, error) {
+				var resp string
+				err := e.GetJSON(context.Background(), testParams{}, &resp)
+				return resp, err
+			},
+		},
+		{
+			name: "GetXML",
+			call: func(e *Endpoint) (string, error) {
+				var resp string
+				err := e.GetXML(context.Background(), testParams{}, &resp)
+				return resp, err
+			},
+		},
+		{
+			name: "GetBinary",
+			call: func(e *Endpoint) (string, error) {
+				resp, err := e.GetBinary(context.Background(), testParams{})
+				if err != nil {
+					return "", err
+				}
+				defer resp.Data.Close()
+				data, err := ioutil.ReadAll(resp.Data)
+				return string(data), err
+			},
+		},
+	}
+	statuses := []struct {
+		status   int
+		fallback bool
+	}{
+		{status: http.StatusServiceUnavailable, fallback: true},
+		{status: http.StatusTooManyRequests, fallback: true},
+		{status: http.StatusNotFound, fallback: false},
+	}
+	for _, tt := range tests {
+		for _, st := range statuses {
+			t.Run(tt.name+"/"+http.StatusText(st.status), func(t *testing.T) {
+				srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(st.status)
+					w.Write([]byte(`"from the API"`))
+				})
+				c := newTestClient(t,
+					WithFallback("string", func(ctx context.Context, apiReq APIRequest) (string, error) {
+						return "fallback", nil
+					}),
+					WithFallback("binary", func(ctx context.Context, apiReq APIRequest) (BinaryResponse, error) {
+						return BinaryResponse{Data: ioutil.NopCloser(strings.NewReader("fallback"))}, nil
+					}))
+				name := "string"
+				if tt.name == "GetBinary" {
+					name = "binary"
+				}
+				if err := c.RegisterEndpoint(name, APIConfig{Host: srv.URL}); err != nil {
+					t.Fatal(err)
+				}
+
+				got, err := tt.call(c.Endpoint(name))
+				if !st.fallback {
+					if got == "fallback" {
+						t.Error("fallback used for a response of the API")
+					}
+					return
+				}
+				if err != nil || got != "fallback" {
+					t.Errorf("got %q, %v, want the fallback", got, err)
+				}
+			})
+		}
+	}
+}
+
+func TestFailOnOutageAppliesToEveryCall(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`[]`))
+	})
+	config := &APIConfig{Host: srv.URL}
+	tests := []struct {
+		name string
+		call func(c *Client, opts ...RequestOption) error
+	}{
+		{
+			name: "GetJSON",
+			call: func(c *Client, opts ...RequestOption) error {
+				var resp interface{}
+				return c.GetJSON(context.Background(), config, testParams{}, &resp, opts...)
+			},
+		},
+		{
+			name: "GetXML",
+			call: func(c *Client, opts ...RequestOption) error {
+				var resp interface{}
+				return c.GetXML(context.Background(), config, testParams{}, &resp, opts...)
+			},
+		},
+		{
+			name: "GetBinary",
+			call: func(c *Client, opts ...RequestOption) error {
+				resp, err := c.GetBinary(context.Background(), config, testParams{}, opts...)
+				if err == nil {
+					resp.Data.Close()
+				}
+				return err
+			},
+		},
+		{
+			name: "GetCSV",
+			call: func(c *Client, opts ...RequestOption) error {
+				var resp []struct{}
+				return c.GetCSV(context.Background(), config, testParams{}, &resp, opts...)
+			},
+		},
+		{
+			name: "GetJSONStream",
+			call: func(c *Client, opts ...RequestOption) error {
+				handle := func(json.RawMessage) error { return nil }
+				return c.GetJSONStream(context.Background(), config, testParams{}, handle, opts...)
+			},
+		},
+		{
+			name: "DownloadFile",
+			call: func(c *Client, opts ...RequestOption) error {
+				path := filepath.Join(t.TempDir(), "file")
+				return c.DownloadFile(context.Background(), config, testParams{}, path, nil, opts...)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t)
+			err := tt.call(c, failOnOutage)
+			var herr *HTTPError
+			if !errors.As(err, &herr) || herr.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("got %v, want a 503 *HTTPError", err)
+			}
+		})
+	}
+}
+
+func TestFallbackKeepsCallerOptions(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	c := newTestClient(t, WithFallback("e", func(ctx context.Context, apiReq APIRequest) (string, error) {
+		return "fallback", nil
+	}))
+	e := &Endpoint{Name: "e", client: c, config: APIConfig{Host: srv.URL}}
+
+	marker := func(o *requestOptions) {}
+	tests := []struct {
+		name string
+		opts []RequestOption
+	}{
+		{name: "empty with spare capacity", opts: make([]RequestOption, 0, 2)},
+		{name: "one option with spare capacity", opts: append(make([]RequestOption, 0, 2), WithHeader("X", "1"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spare := tt.opts[:cap(tt.opts)]
+			for i := len(tt.opts); i < len(spare); i++ {
+				spare[i] = marker
+			}
+			var resp string
+			err := e.withFallback(context.Background(), testParams{}, &resp, tt.opts, func(opts []RequestOption) error {
+				return c.GetJSON(context.Background(), &e.config, testParams{}, &resp, opts...)
+			})
+			if err != nil || resp != "fallback" {
+				t.Fatalf("got %q, %v, want the fallback", resp, err)
+			}
+			for i := len(tt.opts); i < len(spare); i++ {
+				if reflect.ValueOf(spare[i]).Pointer() != reflect.ValueOf(marker).Pointer() {
+					t.Errorf("caller's option slice overwritten at %d", i)
+				}
+			}
+		})
+	}
+}
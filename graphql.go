@@ -0,0 +1,174 @@
+package apiclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// GraphQLError is an error reported in the errors of a GraphQL response.
+type GraphQLError struct {
+	Message   string            `json:"message"`
+	Locations []GraphQLLocation `json:"locations,omitempty"`
+	// Path locates the field that failed in the response, as field names and list indices.
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// GraphQLLocation is a position in a GraphQL query.
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (e *GraphQLError) Error() string {
+	if len(e.Path) == 0 {
+		return "apiclient: graphql: " + e.Message
+	}
+	path := make([]string, len(e.Path))
+	for i, p := range e.Path {
+		path[i] = fmt.Sprint(p)
+	}
+	return fmt.Sprintf("apiclient: graphql: %s (at %s)", e.Message, strings.Join(path, "."))
+}
+
+// Code returns the error code set by the server in the error's extensions, such as "UNAUTHENTICATED", or "".
+func (e *GraphQLError) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+// GraphQLErrors is the error returned for a GraphQL response reporting errors. errors.As finds each *GraphQLError
+// in it.
+type GraphQLErrors []*GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", e[0].Error(), len(e)-1)
+}
+
+// Unwrap returns the individual errors.
+func (e GraphQLErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// WithPersistedQuery makes a GraphQL call send the SHA-256 hash of its query instead of the query itself, following
+// the automatic persisted queries protocol. If the server does not know the hash yet, the call is sent again with
+// the query, which the server then stores under it.
+func WithPersistedQuery() RequestOption {
+	return func(o *requestOptions) {
+		o.persistedQuery = true
+	}
+}
+
+// graphQLRequest is the envelope of a GraphQL query sent over HTTP.
+type graphQLRequest struct {
+	Query      string                 `json:"query,omitempty"`
+	Variables  map[string]interface{} `json:"variables,omitempty"`
+	Extensions *graphQLExtensions     `json:"extensions,omitempty"`
+}
+
+type graphQLExtensions struct {
+	PersistedQuery persistedQuery `json:"persistedQuery"`
+}
+
+type persistedQuery struct {
+	Version    int    `json:"version"`
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+// graphQLResponse is the envelope of a GraphQL response.
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors GraphQLErrors   `json:"errors"`
+}
+
+// PostGraphQL POSTs query with variables to the GraphQL endpoint of config and decodes the data of the response into
+// out. If the response reports errors, they are returned as GraphQLErrors, after decoding any partial data.
+func (c *Client) PostGraphQL(ctx context.Context, config *APIConfig, query string, variables map[string]interface{}, out interface{}, opts ...RequestOption) error {
+	o := c.newRequestOptions(opts)
+	ctx, cancel := o.context(ctx, config)
+	defer cancel()
+	ctx = c.withRequestID(defaultAccept(ctx, "application/graphql-response+json, application/json;q=0.9"))
+	req := graphQLRequest{Query: query, Variables: variables}
+	if o.persistedQuery {
+		sum := sha256.Sum256([]byte(query))
+		req.Query = ""
+		req.Extensions = &graphQLExtensions{persistedQuery{Version: 1, SHA256Hash: hex.EncodeToString(sum[:])}}
+	}
+	resp, err := c.postGraphQL(ctx, config, &req, o)
+	if err == nil && req.Query == "" && resp.Errors.persistedQueryNotFound() {
+		req.Query = query
+		resp, err = c.postGraphQL(ctx, config, &req, o)
+	}
+	if err != nil {
+		return c.requestError(ctx, err)
+	}
+	if len(resp.Data) > 0 && string(resp.Data) != "null" {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			return c.requestError(ctx, err)
+		}
+	}
+	if len(resp.Errors) > 0 {
+		return c.requestError(ctx, resp.Errors)
+	}
+	return nil
+}
+
+// postGraphQL sends req and returns the response envelope.
+func (c *Client) postGraphQL(ctx context.Context, config *APIConfig, req *graphQLRequest, o *requestOptions) (*graphQLResponse, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpResp, err := c.send(ctx, "POST", config, queryParams(nil), nil, bytesBody("application/json", data))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	if o.meta != nil {
+		o.meta.fill(httpResp)
+	}
+	body, err := c.jsonBody(httpResp)
+	if err != nil {
+		return nil, err
+	}
+	data, err = io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	var resp graphQLResponse
+	if err := json.Unmarshal(data, &resp); err != nil || (resp.Data == nil && resp.Errors == nil) {
+		// GraphQL servers report errors in the body, whatever the status; a body without them is an HTTP failure.
+		if httpResp.StatusCode/100 != 2 {
+			return nil, &HTTPError{StatusCode: httpResp.StatusCode, Status: httpResp.Status}
+		}
+		if err == nil {
+			err = errors.New("apiclient: graphql: response has neither data nor errors")
+		}
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// persistedQueryNotFound reports whether the server asked for the query of a persisted query it does not know.
+func (e GraphQLErrors) persistedQueryNotFound() bool {
+	for _, err := range e {
+		if err.Code() == "PERSISTED_QUERY_NOT_FOUND" || err.Message == "PersistedQueryNotFound" {
+			return true
+		}
+	}
+	return false
+}
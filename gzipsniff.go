@@ -0,0 +1,29 @@
+package apiclient
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// gzipMagic are the leading bytes of every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// WithGzipSniffing configures the client to detect gzip-compressed JSON bodies sent without a Content-Encoding
+// header, and decompress them before decoding.
+func WithGzipSniffing() ClientOption {
+	return func(c *Client) error {
+		c.gzipSniffing = true
+		return nil
+	}
+}
+
+// sniffGzip returns body decompressed if it starts with the gzip magic bytes, and unchanged otherwise.
+func sniffGzip(body io.ReadCloser, pooled bool) (io.ReadCloser, error) {
+	br := bufio.NewReader(body)
+	head, _ := br.Peek(len(gzipMagic))
+	if !bytes.Equal(head, gzipMagic) {
+		return readCloser{br, body}, nil
+	}
+	return gzipBody(body, br, pooled)
+}
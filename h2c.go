@@ -0,0 +1,26 @@
+//go:build !apiclient_slim
+
+package apiclient
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/http2"
+)
+
+// newH2CTransport returns a transport speaking cleartext HTTP/2 to the hosts base would dial.
+func newH2CTransport(base *http.Transport) (http.RoundTripper, error) {
+	return &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			if dial := base.DialContext; dial != nil {
+				return dial(ctx, network, addr)
+			}
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}, nil
+}
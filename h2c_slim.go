@@ -0,0 +1,14 @@
+//go:build apiclient_slim
+
+package apiclient
+
+import (
+	"errors"
+	"net/http"
+)
+
+// newH2CTransport fails: binaries built with the apiclient_slim tag leave out the HTTP/2 implementation of
+// golang.org/x/net, which cleartext HTTP/2 needs.
+func newH2CTransport(base *http.Transport) (http.RoundTripper, error) {
+	return nil, errors.New("apiclient: HTTP/2 with prior knowledge is not available in apiclient_slim builds")
+}
@@ -0,0 +1,282 @@
+package apiclient
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/context"
+)
+
+// maxHARBody is how much of a request or response body a HAR entry keeps.
+const maxHARBody = 1 << 20
+
+// HARRecorder records the traffic of a client as an HTTP Archive (HAR 1.2), which browser devtools and most HTTP
+// debugging tools can open. Credentials, Authorization and cookie headers are redacted, and bodies are kept up to
+// 1MiB each. Entries accumulate for the lifetime of the recorder; see Reset.
+type HARRecorder struct {
+	mu      sync.Mutex
+	entries []*harEntry
+}
+
+// NewHARRecorder returns an empty HARRecorder.
+func NewHARRecorder() *HARRecorder {
+	return &HARRecorder{}
+}
+
+// WithHARCapture configures the client to record every request, its response and its timings into rec. An entry is
+// complete once the response body has been closed.
+func WithHARCapture(rec *HARRecorder) ClientOption {
+	return func(c *Client) error {
+		c.har = rec
+		return nil
+	}
+}
+
+// WriteTo writes the recorded entries to w as a HAR document.
+func (h *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	h.mu.Lock()
+	doc := harDocument{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "apiclient", Version: strings.TrimPrefix(userAgent, "ApiClientGo/")},
+		Entries: append([]*harEntry{}, h.entries...),
+	}}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	h.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// Save writes the recorded entries to the file at path as a HAR document.
+func (h *HARRecorder) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := h.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Reset discards the recorded entries.
+func (h *HARRecorder) Reset() {
+	h.mu.Lock()
+	h.entries = nil
+	h.mu.Unlock()
+}
+
+func (h *HARRecorder) add(e *harEntry) {
+	h.mu.Lock()
+	h.entries = append(h.entries, e)
+	h.mu.Unlock()
+}
+
+type harDocument struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string      `json:"version"`
+	Creator harCreator  `json:"creator"`
+	Entries []*harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	// Error is set for requests that got no response; HAR allows custom fields prefixed by an underscore.
+	Error string `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// harTimings are in milliseconds, -1 for phases that did not apply.
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// recordHAR arranges for the exchange of req to be added to the HAR recorder once its response body is closed.
+func (c *Client) recordHAR(ctx context.Context, req *http.Request, start time.Time, t Timings, resp *http.Response, err error) {
+	rawURL := c.redactURL(ctx, req.URL.String())
+	e := &harEntry{
+		StartedDateTime: start.Format(time.RFC3339Nano),
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         rawURL,
+			HTTPVersion: req.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(c.redactExport(ctx, req.Header)),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    req.ContentLength,
+		},
+		Response: harResponse{Cookies: []harNameValue{}, Headers: []harNameValue{}, HeadersSize: -1, BodySize: -1},
+	}
+	if u, err := url.Parse(rawURL); err == nil {
+		e.Request.QueryString = harHeaders(http.Header(u.Query()))
+	}
+	if body, ok := replayBody(req); ok && len(body) > 0 && len(body) <= maxHARBody && utf8.Valid(body) {
+		e.Request.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: string(body)}
+	}
+	if err != nil {
+		// Transport errors quote the URL, credentials included.
+		e.Error = strings.ReplaceAll(err.Error(), req.URL.String(), rawURL)
+		e.Time = milliseconds(time.Since(start))
+		e.Timings = harPhases(t, time.Since(start))
+		c.har.add(e)
+		return
+	}
+	e.Response.Status = resp.StatusCode
+	e.Response.StatusText = http.StatusText(resp.StatusCode)
+	if i := strings.IndexByte(resp.Status, ' '); i >= 0 {
+		e.Response.StatusText = resp.Status[i+1:]
+	}
+	e.Response.HTTPVersion = resp.Proto
+	e.Response.Headers = harHeaders(c.redactExport(ctx, resp.Header))
+	e.Response.RedirectURL = resp.Header.Get("Location")
+	e.Response.Content.MimeType = resp.Header.Get("Content-Type")
+	body := resp.Body
+	buf := &cappedBuffer{max: maxHARBody}
+	var size int64
+	var once sync.Once
+	resp.Body = readCloser{io.TeeReader(body, countingWriter{buf, &size}), closerFunc(func() error {
+		once.Do(func() {
+			elapsed := time.Since(start)
+			e.Time = milliseconds(elapsed)
+			e.Timings = harPhases(t, elapsed)
+			e.Response.BodySize = size
+			e.Response.Content.Size = size
+			if data := buf.Bytes(); utf8.Valid(data) {
+				e.Response.Content.Text = string(data)
+			} else {
+				e.Response.Content.Text = base64.StdEncoding.EncodeToString(data)
+				e.Response.Content.Encoding = "base64"
+			}
+			c.har.add(e)
+		})
+		return body.Close()
+	})}
+}
+
+// harHeaders returns header as HAR name/value pairs, sorted by name.
+func harHeaders(header http.Header) []harNameValue {
+	pairs := []harNameValue{}
+	for name, values := range header {
+		for _, v := range values {
+			pairs = append(pairs, harNameValue{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Name < pairs[j].Name })
+	return pairs
+}
+
+// harPhases splits the elapsed time of a request into HAR timings. Bodies are streamed, so sending is not timed
+// separately from waiting.
+func harPhases(t Timings, elapsed time.Duration) harTimings {
+	phases := harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1}
+	setup := time.Duration(0)
+	if !t.ConnReused {
+		if t.DNSLookup > 0 {
+			phases.DNS = milliseconds(t.DNSLookup)
+		}
+		if t.Connect > 0 {
+			// HAR counts the TLS handshake as part of connecting.
+			phases.Connect = milliseconds(t.Connect + t.TLSHandshake)
+		}
+		if t.TLSHandshake > 0 {
+			phases.SSL = milliseconds(t.TLSHandshake)
+		}
+		setup = t.DNSLookup + t.Connect + t.TLSHandshake
+	}
+	ttfb := t.TimeToFirstByte
+	if ttfb <= 0 || ttfb > elapsed {
+		ttfb = elapsed
+	}
+	if wait := ttfb - setup; wait > 0 {
+		phases.Wait = milliseconds(wait)
+	}
+	phases.Receive = milliseconds(elapsed - ttfb)
+	return phases
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// countingWriter passes writes through to w, adding their length to n.
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (cw countingWriter) Write(p []byte) (int, error) {
+	*cw.n += int64(len(p))
+	return cw.w.Write(p)
+}
@@ -0,0 +1,53 @@
+package apiclient
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// Head makes a HEAD request to the API endpoint and describes the response, whose status and headers the API would
+// have sent for a GET, such as the Content-Length and ETag of a large resource, without transferring the body.
+// Error statuses are reported in the StatusCode of the result rather than as an error, so checking whether a
+// resource exists needs no error handling beyond that of requests that got no response.
+func (c *Client) Head(ctx context.Context, config *APIConfig, apiReq APIRequest, opts ...RequestOption) (ResponseMeta, error) {
+	return c.bodiless(ctx, http.MethodHead, config, apiReq, opts)
+}
+
+// Options makes an OPTIONS request to the API endpoint to discover its capabilities, and returns the methods listed
+// by the Allow header of the response along with a description of the response, as for Head.
+func (c *Client) Options(ctx context.Context, config *APIConfig, apiReq APIRequest, opts ...RequestOption) ([]string, ResponseMeta, error) {
+	meta, err := c.bodiless(ctx, http.MethodOptions, config, apiReq, opts)
+	if err != nil {
+		return nil, meta, err
+	}
+	var methods []string
+	for _, v := range meta.Header.Values("Allow") {
+		for _, m := range strings.Split(v, ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				methods = append(methods, strings.ToUpper(m))
+			}
+		}
+	}
+	return methods, meta, nil
+}
+
+// bodiless makes a request with method whose response body, if any, is discarded, and returns the description of the
+// response.
+func (c *Client) bodiless(ctx context.Context, method string, config *APIConfig, apiReq APIRequest, opts []RequestOption) (ResponseMeta, error) {
+	o := c.newRequestOptions(opts)
+	if o.meta == nil {
+		o.meta = &ResponseMeta{}
+	}
+	ctx, cancel := o.context(ctx, config)
+	defer cancel()
+	ctx = c.withRequestID(ctx)
+	httpResp, err := c.send(ctx, method, config, apiReq, nil, nil)
+	if err != nil {
+		return ResponseMeta{}, c.requestError(ctx, err)
+	}
+	httpResp.Body.Close()
+	o.meta.fill(httpResp)
+	return *o.meta, nil
+}
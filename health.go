@@ -0,0 +1,145 @@
+package apiclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// healthProbeTimeout bounds a health probe whose HealthProbe sets no Timeout.
+const healthProbeTimeout = 5 * time.Second
+
+// HealthProbe is the lightweight request, such as a ping or status endpoint, HealthCheck sends to check the health of
+// the upstream.
+type HealthProbe struct {
+	Config  *APIConfig
+	Request APIRequest
+	// Method defaults to GET. HEAD spares transferring a body, if the endpoint supports it.
+	Method string
+	// ExpectedStatus is the status of a healthy response. Zero accepts any 2xx status.
+	ExpectedStatus int
+	// Timeout bounds each probe. Defaults to 5 seconds.
+	Timeout time.Duration
+	// Interval, if set, makes the client probe the upstream in the background that often, see Health.
+	Interval time.Duration
+}
+
+// WithHealthProbe configures the probe sent by HealthCheck. With several base URLs or regions, each of them is
+// probed, and the outcomes count towards their health like those of other requests: a failed probe can take a base
+// URL out of rotation, and a successful one brings it back before its RecoveryInterval has elapsed.
+func WithHealthProbe(probe HealthProbe) ClientOption {
+	return func(c *Client) error {
+		if probe.Config == nil || probe.Request == nil {
+			return errors.New("apiclient: health probe needs a config and a request")
+		}
+		if probe.Method == "" {
+			probe.Method = http.MethodGet
+		}
+		if probe.Timeout <= 0 {
+			probe.Timeout = healthProbeTimeout
+		}
+		c.healthProbe = &probe
+		return nil
+	}
+}
+
+// HealthStatus is the outcome of probing one base URL of the upstream.
+type HealthStatus struct {
+	Base    string
+	Healthy bool
+	// StatusCode is that of the response, zero if the probe got none.
+	StatusCode int
+	Latency    time.Duration
+	Checked    time.Time
+	// Err is the error the probe failed with, if it failed.
+	Err error
+}
+
+// HealthCheck probes the upstream with the configured HealthProbe. The error is nil if at least one base URL is
+// healthy; the outcome for each of them is then available from Health.
+func (c *Client) HealthCheck(ctx context.Context) error {
+	p := c.healthProbe
+	if p == nil {
+		return errors.New("apiclient: no health probe configured")
+	}
+	ctx = c.defaultsContext(ctx)
+	var statuses []HealthStatus
+	var healthy bool
+	es := c.endpoints
+	if c.regions != nil {
+		es = c.regions.set
+	}
+	if es != nil {
+		for _, ep := range es.snapshot() {
+			status := c.probeHealth(ctx, p, ep.base)
+			es.report(ep, status.Healthy, c.clock.Now())
+			statuses = append(statuses, status)
+			healthy = healthy || status.Healthy
+		}
+	} else {
+		status := c.probeHealth(ctx, p, c.host(ctx, p.Config))
+		statuses = append(statuses, status)
+		healthy = status.Healthy
+	}
+	c.healthMu.Lock()
+	c.health = statuses
+	c.healthMu.Unlock()
+	if !healthy {
+		return fmt.Errorf("apiclient: upstream unhealthy: %w", statuses[0].Err)
+	}
+	return nil
+}
+
+// Health returns the outcome of the last HealthCheck for each base URL, or nil if the upstream was never probed.
+func (c *Client) Health() []HealthStatus {
+	c.healthMu.Lock()
+	defer c.healthMu.Unlock()
+	return append([]HealthStatus(nil), c.health...)
+}
+
+// probeHealth sends p to base.
+func (c *Client) probeHealth(ctx context.Context, p *HealthProbe, base string) HealthStatus {
+	ctx, cancel := context.WithTimeout(c.withRequestID(ctx), p.Timeout)
+	defer cancel()
+	status := HealthStatus{Base: base, Checked: c.clock.Now()}
+	start := time.Now()
+	resp, err := c.sendDirect(ctx, base, p.Method, p.Config, p.Request, nil, nil)
+	status.Latency = time.Since(start)
+	if err == nil {
+		resp.Body.Close()
+		status.StatusCode = resp.StatusCode
+		if !p.healthy(resp.StatusCode) {
+			err = &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+		}
+	}
+	status.Healthy, status.Err = err == nil, err
+	return status
+}
+
+// healthy reports whether a response with statusCode is that of a healthy upstream.
+func (p *HealthProbe) healthy(statusCode int) bool {
+	if p.ExpectedStatus != 0 {
+		return statusCode == p.ExpectedStatus
+	}
+	return statusCode/100 == 2
+}
+
+// startHealthProber schedules the background health checks. A check still running when the next one is due delays
+// it to the following interval.
+func (c *Client) startHealthProber() {
+	c.scheduler.every(c.healthProbe.Interval, c.healthProbe.Interval, func() {
+		if !atomic.CompareAndSwapInt32(&c.healthProbing, 0, 1) {
+			return
+		}
+		go func() {
+			defer atomic.StoreInt32(&c.healthProbing, 0)
+			if err := c.HealthCheck(context.Background()); err != nil {
+				c.log("upstream unhealthy", map[string]interface{}{"error": err})
+			}
+		}()
+	})
+}
@@ -0,0 +1,60 @@
+package apiclient
+
+import (
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// DefaultIdempotencyKeyHeader is the header WithIdempotencyKeys uses when none is given.
+const DefaultIdempotencyKeyHeader = "Idempotency-Key"
+
+// WithIdempotencyKeys configures the client to send a random key in header (Idempotency-Key if empty) with every
+// POST, PUT, PATCH and DELETE request, so a provider such as Stripe performs a write only once however often it is
+// retried or failed over: the key stays the same for all the attempts of a call. Calls may choose their own key
+// with WithIdempotencyKey, or set the header themselves.
+func WithIdempotencyKeys(header string) ClientOption {
+	return func(c *Client) error {
+		if header == "" {
+			header = DefaultIdempotencyKeyHeader
+		}
+		c.idempotencyHeader = header
+		return nil
+	}
+}
+
+// WithIdempotencyKey sends key as the idempotency key of a call, for instance one derived from the operation so that
+// it is also recognized when the call is repeated by another process. It is sent, in the header configured with
+// WithIdempotencyKeys or else in Idempotency-Key, even if keys are not generated automatically.
+func WithIdempotencyKey(key string) RequestOption {
+	return func(o *requestOptions) {
+		o.idempotencyKey = key
+	}
+}
+
+type idempotencyKeyKey struct{}
+
+// withIdempotencyKey returns header with the idempotency key of a call with method, if it needs one.
+func (c *Client) withIdempotencyKey(ctx context.Context, method string, header http.Header) http.Header {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "TRACE":
+		return header
+	}
+	name := c.idempotencyHeader
+	if name == "" {
+		name = DefaultIdempotencyKeyHeader
+	}
+	key, _ := ctx.Value(idempotencyKeyKey{}).(string)
+	if key == "" {
+		if c.idempotencyHeader == "" || header.Get(name) != "" || headerFromContext(ctx).Get(name) != "" {
+			return header
+		}
+		key = newUUID()
+	}
+	h := header.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	h.Set(name, key)
+	return h
+}
@@ -0,0 +1,46 @@
+package apiclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+// ErrSubjectNotAllowed is returned for requests on behalf of a subject that is not allowed to be impersonated.
+var ErrSubjectNotAllowed = errors.New("apiclient: impersonation of subject not allowed")
+
+// WithImpersonation configures the client to send the subject a request is made on behalf of in header (such as
+// X-On-Behalf-Of or Sudo, depending on the provider). The subject is returned by subjectFromContext for the request
+// context; requests with an empty subject are sent without the header. Only the subjects in allowed may be
+// impersonated, any other subject fails the request with ErrSubjectNotAllowed.
+func WithImpersonation(header string, subjectFromContext func(context.Context) string, allowed []string) ClientOption {
+	return func(c *Client) error {
+		subjects := make(map[string]bool, len(allowed))
+		for _, s := range allowed {
+			subjects[s] = true
+		}
+		c.impersonation = &impersonation{header: header, subject: subjectFromContext, allowed: subjects}
+		return nil
+	}
+}
+
+type impersonation struct {
+	header  string
+	subject func(context.Context) string
+	allowed map[string]bool
+}
+
+// apply sets the impersonation header for ctx on header.
+func (im *impersonation) apply(ctx context.Context, header http.Header) error {
+	subject := im.subject(ctx)
+	if subject == "" {
+		return nil
+	}
+	if !im.allowed[subject] {
+		return fmt.Errorf("%w: %q", ErrSubjectNotAllowed, subject)
+	}
+	header.Set(im.header, subject)
+	return nil
+}
@@ -0,0 +1,130 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// GetJSONArrayStream fetches a JSON response holding an array and passes its elements to handle one at a time as
+// they are decoded, so that arrays of hundreds of thousands of records are consumed without holding them all in
+// memory. The array is the whole response, or the one found by following the object keys of WithArrayPath. It stops
+// at the first error returned by handle. Like GetJSONStream, the response is never cached or shared between callers.
+func (c *Client) GetJSONArrayStream(ctx context.Context, config *APIConfig, apiReq APIRequest, handle func(json.RawMessage) error, opts ...RequestOption) error {
+	o := c.newRequestOptions(opts)
+	ctx, cancel := o.context(ctx, config)
+	defer cancel()
+	ctx = c.withRequestID(defaultAccept(ctx, "application/json"))
+	httpResp, err := c.send(ctx, "GET", config, apiReq, nil, nil)
+	if err != nil {
+		return c.requestError(ctx, err)
+	}
+	defer httpResp.Body.Close()
+	if o.meta != nil {
+		o.meta.fill(httpResp)
+	}
+
+	body, err := c.jsonBody(httpResp)
+	if err != nil {
+		return c.requestError(ctx, err)
+	}
+	dec := json.NewDecoder(body)
+	if err := seekArray(dec, o.arrayPath); err != nil {
+		if httpResp.StatusCode/100 != 2 {
+			err = &HTTPError{StatusCode: httpResp.StatusCode, Status: httpResp.Status}
+		}
+		return c.requestError(ctx, err)
+	}
+	for dec.More() {
+		var elem json.RawMessage
+		if err := dec.Decode(&elem); err != nil {
+			return c.requestError(ctx, err)
+		}
+		if err := handle(elem); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return c.requestError(ctx, err)
+	}
+	return nil
+}
+
+// WithArrayPath makes GetJSONArrayStream stream the array found under keys, one per level of nested objects, such
+// as "data", "items" for {"data": {"items": [...]}}.
+func WithArrayPath(keys ...string) RequestOption {
+	return func(o *requestOptions) {
+		o.arrayPath = keys
+	}
+}
+
+// seekArray advances dec past the opening bracket of the array under path.
+func seekArray(dec *json.Decoder, path []string) error {
+	for depth, key := range path {
+		if err := expectDelim(dec, '{', path[:depth]); err != nil {
+			return err
+		}
+		for {
+			if !dec.More() {
+				return fmt.Errorf("apiclient: no key %q in JSON response", strings.Join(path[:depth+1], "."))
+			}
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if tok == key {
+				break
+			}
+			if err := skipValue(dec); err != nil {
+				return err
+			}
+		}
+	}
+	return expectDelim(dec, '[', path)
+}
+
+// expectDelim reads the next token of dec, which must open the value at path with delim.
+func expectDelim(dec *json.Decoder, delim json.Delim, path []string) error {
+	tok, err := dec.Token()
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		kind := "an array"
+		if delim == '{' {
+			kind = "an object"
+		}
+		at := "JSON response"
+		if len(path) > 0 {
+			at = fmt.Sprintf("%q in JSON response", strings.Join(path, "."))
+		}
+		return fmt.Errorf("apiclient: %s is not %s", at, kind)
+	}
+	return nil
+}
+
+// skipValue reads past the next value of dec without decoding it.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"golang.org/x/net/context"
+)
+
+// RPCError is the error object of a failed JSON-RPC 2.0 call.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// The error codes defined by the JSON-RPC 2.0 specification.
+const (
+	RPCParseError     = -32700
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	RPCInternalError  = -32603
+)
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("apiclient: json-rpc error %d: %s", e.Code, e.Message)
+}
+
+// RPCClient calls the methods of a JSON-RPC 2.0 endpoint, see Client.JSONRPC.
+type RPCClient struct {
+	client *Client
+	config APIConfig
+	lastID int64
+}
+
+// JSONRPC returns a client for the JSON-RPC 2.0 endpoint of config. Calls are POSTed to it, numbered with IDs
+// unique to the returned RPCClient.
+func (c *Client) JSONRPC(config APIConfig) *RPCClient {
+	return &RPCClient{client: c, config: config}
+}
+
+// RPCCall is one call of a batch, see RPCClient.Batch.
+type RPCCall struct {
+	Method string
+	Params interface{}
+	// Result receives the result of the call, unless it is nil.
+	Result interface{}
+	// Err is set by Batch if the call failed, to an *RPCError if the server reported its failure.
+	Err error
+}
+
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+	ID      *int64      `json:"id,omitempty"`
+}
+
+type rpcResponse struct {
+	ID     *int64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// Call calls method with params, an array or object, and decodes its result into result. A failure reported by the
+// server is returned as an *RPCError.
+func (r *RPCClient) Call(ctx context.Context, method string, params interface{}, result interface{}, opts ...RequestOption) error {
+	call := &RPCCall{Method: method, Params: params, Result: result}
+	if err := r.Batch(ctx, []*RPCCall{call}, opts...); err != nil {
+		return err
+	}
+	return call.Err
+}
+
+// Notify sends a notification of method with params, a call the server does not answer.
+func (r *RPCClient) Notify(ctx context.Context, method string, params interface{}, opts ...RequestOption) error {
+	c := r.client
+	o := c.newRequestOptions(opts)
+	ctx, cancel := o.context(ctx, &r.config)
+	defer cancel()
+	ctx = c.withRequestID(ctx)
+	_, err := r.post(ctx, rpcRequest{JSONRPC: "2.0", Method: method, Params: params}, o)
+	return c.requestError(ctx, err)
+}
+
+// Batch sends calls in one request, setting the Err of those that fail. The returned error is that of the request
+// as a whole. A single call is sent on its own rather than as a batch of one.
+func (r *RPCClient) Batch(ctx context.Context, calls []*RPCCall, opts ...RequestOption) error {
+	if len(calls) == 0 {
+		return nil
+	}
+	c := r.client
+	o := c.newRequestOptions(opts)
+	ctx, cancel := o.context(ctx, &r.config)
+	defer cancel()
+	ctx = c.withRequestID(ctx)
+	reqs := make([]rpcRequest, len(calls))
+	pending := make(map[int64]*RPCCall, len(calls))
+	for i, call := range calls {
+		id := atomic.AddInt64(&r.lastID, 1)
+		reqs[i] = rpcRequest{JSONRPC: "2.0", Method: call.Method, Params: call.Params, ID: &id}
+		pending[id] = call
+	}
+	var body interface{} = reqs
+	if len(reqs) == 1 {
+		body = reqs[0]
+	}
+	data, err := r.post(ctx, body, o)
+	if err != nil {
+		return c.requestError(ctx, err)
+	}
+	var resps []rpcResponse
+	if err := json.Unmarshal(data, &resps); err != nil {
+		// A single call, or an invalid batch, is answered with a single response.
+		var resp rpcResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return c.requestError(ctx, err)
+		}
+		if resp.ID == nil && resp.Error != nil {
+			return c.requestError(ctx, resp.Error)
+		}
+		resps = []rpcResponse{resp}
+	}
+	for _, resp := range resps {
+		if resp.ID == nil {
+			continue
+		}
+		call := pending[*resp.ID]
+		if call == nil {
+			continue
+		}
+		delete(pending, *resp.ID)
+		switch {
+		case resp.Error != nil:
+			call.Err = resp.Error
+		case call.Result != nil:
+			call.Err = json.Unmarshal(resp.Result, call.Result)
+		}
+	}
+	for _, call := range pending {
+		call.Err = errMissingRPCResponse
+	}
+	return nil
+}
+
+// errMissingRPCResponse is set on the calls of a batch that the server's response does not answer.
+var errMissingRPCResponse = errors.New("apiclient: json-rpc response missing")
+
+// post POSTs body to the endpoint and returns the response body.
+func (r *RPCClient) post(ctx context.Context, body interface{}, o *requestOptions) ([]byte, error) {
+	c := r.client
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	httpResp, err := c.send(ctx, "POST", &r.config, queryParams(nil), nil, bytesBody("application/json", data))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	if o.meta != nil {
+		o.meta.fill(httpResp)
+	}
+	jsonBody, err := c.jsonBody(httpResp)
+	if err != nil {
+		return nil, err
+	}
+	data, err = io.ReadAll(jsonBody)
+	if err != nil {
+		return nil, err
+	}
+	// Some servers answer failed calls with an error status, but still with a JSON-RPC response.
+	if httpResp.StatusCode/100 != 2 && !json.Valid(data) {
+		return nil, &HTTPError{StatusCode: httpResp.StatusCode, Status: httpResp.Status}
+	}
+	return data, nil
+}
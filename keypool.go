@@ -0,0 +1,147 @@
+package apiclient
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// KeyStrategy decides which key of a pool set with WithAPIKeys a request is sent with.
+type KeyStrategy int
+
+const (
+	// RoundRobinKeys uses the keys in turn.
+	RoundRobinKeys KeyStrategy = iota
+	// QuotaAwareKeys uses the key with the most quota left, as reported by the API in the X-RateLimit-Remaining or
+	// RateLimit-Remaining header of its last response. Keys that have not been used yet go first.
+	QuotaAwareKeys
+)
+
+const (
+	// rateLimitedKeyBench is how long a key answered 429 Too Many Requests without a Retry-After is benched.
+	rateLimitedKeyBench = time.Minute
+	// rejectedKeyBench is how long a key answered 401 Unauthorized is benched.
+	rejectedKeyBench = 10 * time.Minute
+)
+
+// WithAPIKeys configures the client to spread its requests over a pool of keys, such as API keys granted separate
+// quotas, according to strategy. A key the API answers 429 Too Many Requests is benched for as long as its
+// Retry-After header asks, or a minute, and one answered 401 Unauthorized for ten minutes; benched keys are only used
+// when every key is benched, the one reinstated soonest first. The keys are redacted like other credentials.
+func WithAPIKeys(keys []Credential, strategy KeyStrategy) ClientOption {
+	return func(c *Client) error {
+		if len(keys) == 0 {
+			return errors.New("apiclient: empty API key pool")
+		}
+		pool := &keyPool{strategy: strategy}
+		for _, key := range keys {
+			pool.keys = append(pool.keys, &pooledKey{cred: key, remaining: -1})
+		}
+		c.apiKeys = pool
+		return nil
+	}
+}
+
+type keyPool struct {
+	strategy KeyStrategy
+	mu       sync.Mutex
+	keys     []*pooledKey
+	next     int
+}
+
+type pooledKey struct {
+	cred         Credential
+	benchedUntil time.Time
+	// remaining is the quota left reported for the key, -1 while unknown.
+	remaining int
+	uses      int
+}
+
+// credentials returns the keys of the pool.
+func (p *keyPool) credentials() []Credential {
+	if p == nil {
+		return nil
+	}
+	creds := make([]Credential, len(p.keys))
+	for i, k := range p.keys {
+		creds[i] = k.cred
+	}
+	return creds
+}
+
+// pick returns the key to send the next request with.
+func (p *keyPool) pick(now time.Time) *pooledKey {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var best *pooledKey
+	for i := range p.keys {
+		k := p.keys[(p.next+i)%len(p.keys)]
+		if now.Before(k.benchedUntil) {
+			continue
+		}
+		if p.strategy == RoundRobinKeys {
+			best = k
+			break
+		}
+		if best == nil || k.quotaLeft() > best.quotaLeft() || k.quotaLeft() == best.quotaLeft() && k.uses < best.uses {
+			best = k
+		}
+	}
+	if best == nil {
+		for _, k := range p.keys {
+			if best == nil || k.benchedUntil.Before(best.benchedUntil) {
+				best = k
+			}
+		}
+	}
+	for i, k := range p.keys {
+		if k == best {
+			p.next = i + 1
+		}
+	}
+	best.uses++
+	return best
+}
+
+func (k *pooledKey) quotaLeft() int {
+	if k.remaining < 0 {
+		return math.MaxInt32
+	}
+	return k.remaining
+}
+
+// observe updates k from the response to a request sent with it, and reports whether k was benched.
+func (p *keyPool) observe(k *pooledKey, resp *http.Response, retryAfter, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, h := range []string{"X-RateLimit-Remaining", "RateLimit-Remaining"} {
+		if n, err := strconv.Atoi(resp.Header.Get(h)); err == nil {
+			k.remaining = n
+			break
+		}
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		k.benchedUntil = retryAfter
+	case http.StatusUnauthorized:
+		k.benchedUntil = now.Add(rejectedKeyBench)
+	default:
+		return false
+	}
+	return true
+}
+
+// observeKey updates the key of the pool a request was sent with from its response.
+func (c *Client) observeKey(k *pooledKey, resp *http.Response) {
+	now := c.clock.Now()
+	until := now.Add(rateLimitedKeyBench)
+	if d := c.retryAfter(resp.Header); d >= 0 {
+		until = now.Add(d)
+	}
+	if c.apiKeys.observe(k, resp, until, now) {
+		c.log("api key benched", map[string]interface{}{"key": fingerprint(k.cred.Value), "status": resp.StatusCode, "until": until})
+	}
+}
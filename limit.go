@@ -0,0 +1,64 @@
+package apiclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ResponseTooLargeError reports a response body longer than the limit set with WithMaxResponseBytes.
+type ResponseTooLargeError struct {
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("apiclient: response body exceeds %d bytes", e.Limit)
+}
+
+// WithMaxResponseBytes configures the client to read at most n bytes of any response body, after decompression, so a
+// misbehaving upstream cannot stream an unbounded payload into a decoder. A response declaring a longer
+// Content-Length fails right away; a longer body fails with a *ResponseTooLargeError once the limit is read past.
+func WithMaxResponseBytes(n int64) ClientOption {
+	return func(c *Client) error {
+		if n <= 0 {
+			return fmt.Errorf("apiclient: invalid response size limit %d", n)
+		}
+		c.maxResponseBytes = n
+		return nil
+	}
+}
+
+// limitBody bounds the body of resp to n bytes.
+func limitBody(resp *http.Response, n int64) error {
+	if resp.ContentLength > n {
+		resp.Body.Close()
+		return &ResponseTooLargeError{Limit: n}
+	}
+	resp.Body = readCloser{&limitedReader{r: resp.Body, n: n, limit: n}, resp.Body}
+	return nil
+}
+
+// limitedReader reads from r until limit bytes have been read, failing if r has more.
+type limitedReader struct {
+	r     io.Reader
+	n     int64
+	limit int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		// Read one byte past the limit to tell a body of exactly limit bytes from a longer one.
+		var b [1]byte
+		n, err := l.r.Read(b[:])
+		if n > 0 {
+			return 0, &ResponseTooLargeError{Limit: l.limit}
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
@@ -0,0 +1,439 @@
+package apiclient
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// maxRequestsPerSecond is the highest rate limit, refilling one token every nanosecond.
+const maxRequestsPerSecond = int(time.Second)
+
+// checkRate returns an error if requestsPerSecond is not a valid rate limit.
+func checkRate(requestsPerSecond int) error {
+	if requestsPerSecond < 1 || requestsPerSecond > maxRequestsPerSecond {
+		return fmt.Errorf("apiclient: invalid rate limit %d", requestsPerSecond)
+	}
+	return nil
+}
+
+// startRateLimiter fills the rate limiter and schedules its refill.
+func (c *Client) startRateLimiter() error {
+	interval := time.Second / time.Duration(c.requestsPerSecond)
+
+	// Implement a bursty rate limiter.
+	// Allow up to 1 second worth of requests to be made at once, unless configured otherwise.
+	burst := c.rateLimitBurst
+	if burst == 0 {
+		burst = c.requestsPerSecond
+	}
+	c.rateLimiter = newLimiter(burst, c.trafficShares)
+	c.rateLimiter.interval = interval
+	c.rateLimiter.maxQueue, c.rateLimiter.maxWait = c.shedQueue, c.shedWait
+	// Prefill rateLimiter with a burst of requests, and wait for it to drain at the configured rate before refilling.
+	// If limiter state was persisted, resume from it instead.
+	tokens, first := burst, time.Duration(burst)*interval
+	if c.limiterStore != nil {
+		state, ok, err := c.limiterStore.Load()
+		if err != nil {
+			return err
+		}
+		if ok {
+			tokens, first = state.Tokens+int(c.clock.Now().Sub(state.Time)/interval), interval
+			if tokens > burst {
+				tokens = burst
+			}
+			c.restoreQuotas(state.Quotas)
+		}
+	}
+	c.rateLimiter.add(tokens)
+
+	// Then, refill rateLimiter continuously.
+	c.scheduleRefill(first, interval)
+
+	if c.limiterStore != nil {
+		c.scheduler.every(c.limiterSaveInterval, c.limiterSaveInterval, c.saveLimiterState)
+	}
+	return nil
+}
+
+// scheduleRefill refills the rate limiter every interval, starting after first. The wheel may fire less often than
+// the refill interval, so every token that came due since the last run is topped up.
+func (c *Client) scheduleRefill(first, interval time.Duration) {
+	next := c.clock.Now().Add(first)
+	c.refill = c.scheduler.every(first, interval, func() {
+		now := c.clock.Now()
+		if next.After(now) {
+			return
+		}
+		n := now.Sub(next)/interval + 1
+		next = next.Add(n * interval)
+		c.rateLimiter.add(int(n))
+	})
+}
+
+// SetRateLimit changes the rate limit of a live client to requestsPerSecond, for instance while the API announces a
+// temporary quota reduction. Unless a burst was set with WithRateLimitBurst, the burst size follows the new rate.
+// Requests already waiting for the limiter are served at the new rate. The rate must be between 1 and one per
+// nanosecond.
+func (c *Client) SetRateLimit(requestsPerSecond int) error {
+	if err := checkRate(requestsPerSecond); err != nil {
+		return err
+	}
+	c.reconfigure(func() {
+		c.requestsPerSecond = requestsPerSecond
+		c.resizeRateLimiter()
+	})
+	return nil
+}
+
+// resizeRateLimiter applies the client's rate limit and burst to its live rate limiter.
+func (c *Client) resizeRateLimiter() {
+	burst := c.rateLimitBurst
+	if burst == 0 {
+		burst = c.requestsPerSecond
+	}
+	interval := time.Second / time.Duration(c.requestsPerSecond)
+	c.rateLimiter.resize(burst, interval)
+	c.scheduler.cancel(c.refill)
+	c.scheduleRefill(interval, interval)
+}
+
+// Priority orders requests waiting for the rate limiter: when tokens are scarce, waiting requests of a higher
+// priority are let through first, and requests of the same priority in the order they arrived.
+type Priority int
+
+const (
+	// PriorityLow is for background and batch work.
+	PriorityLow Priority = -1
+	// PriorityNormal is the default.
+	PriorityNormal Priority = 0
+	// PriorityHigh is for interactive calls.
+	PriorityHigh Priority = 1
+)
+
+type priorityKey struct{}
+
+// ContextWithPriority returns a copy of ctx whose requests wait for the rate limiter with priority p.
+func ContextWithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// priorityFromContext returns the priority of requests made with ctx.
+func priorityFromContext(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityKey{}).(Priority)
+	return p
+}
+
+// trafficClass separates reads from writes for WithTrafficShares.
+type trafficClass int
+
+const (
+	readTraffic trafficClass = iota
+	writeTraffic
+)
+
+// classOf returns the traffic class of requests with method.
+func classOf(method string) trafficClass {
+	if isSafeMethod(method) {
+		return readTraffic
+	}
+	return writeTraffic
+}
+
+// WithTrafficShares partitions the rate limit between reads (GET, HEAD, OPTIONS, TRACE) and writes (every other
+// method) in the ratio readShare:writeShare, e.g. 70 and 30. The shares only apply while both classes are waiting
+// for the limiter; a class with nothing waiting leaves its share to the other.
+func WithTrafficShares(readShare, writeShare float64) ClientOption {
+	return func(c *Client) error {
+		if readShare <= 0 || writeShare <= 0 {
+			return errors.New("apiclient: traffic shares must be positive")
+		}
+		c.trafficShares = &[2]float64{readShare, writeShare}
+		return nil
+	}
+}
+
+// limiter is a token bucket whose waiters are served by priority, and optionally shared between traffic classes
+// by weight.
+type limiter struct {
+	// waits counts the takers that had to wait, for waited nanoseconds in total. The counters come first to be
+	// 64-bit aligned for atomic access.
+	waits  int64
+	waited int64
+	// shed counts the takers turned away by load shedding.
+	shed   int64
+	mu     sync.Mutex
+	tokens int
+	burst  int
+	// interval is the time between refills, one token each.
+	interval time.Duration
+	// maxQueue and maxWait, if set, bound the backlog of waiters, see WithLoadShedding.
+	maxQueue int
+	maxWait  time.Duration
+	// waiting holds, per traffic class, the queues of waiters for PriorityHigh, PriorityNormal and PriorityLow.
+	// Without shares, every waiter is queued as a read.
+	waiting [2][3]list.List
+	// shares weighs the traffic classes, and served counts the tokens each was handed while both were waiting.
+	shares *[2]float64
+	served [2]float64
+	// closed is closed when the limiter is torn down, failing every waiter.
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+type limiterWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+func newLimiter(burst int, shares *[2]float64) *limiter {
+	return &limiter{burst: burst, shares: shares, closed: make(chan struct{})}
+}
+
+// queue returns the waiting queue for class and p.
+func (l *limiter) queue(class trafficClass, p Priority) *list.List {
+	if l.shares == nil {
+		class = readTraffic
+	}
+	switch {
+	case p > PriorityNormal:
+		return &l.waiting[class][0]
+	case p < PriorityNormal:
+		return &l.waiting[class][2]
+	}
+	return &l.waiting[class][1]
+}
+
+// wait takes a token, blocking until one is handed out or ctx is done.
+func (l *limiter) wait(ctx context.Context, class trafficClass, p Priority) error {
+	l.mu.Lock()
+	if l.tokens > 0 {
+		l.tokens--
+		l.mu.Unlock()
+		return nil
+	}
+	if l.overloaded(p) {
+		l.mu.Unlock()
+		atomic.AddInt64(&l.shed, 1)
+		return ErrOverloaded
+	}
+	w := &limiterWaiter{ready: make(chan struct{})}
+	q := l.queue(class, p)
+	e := q.PushBack(w)
+	l.mu.Unlock()
+
+	start := time.Now()
+	defer func() {
+		atomic.AddInt64(&l.waits, 1)
+		atomic.AddInt64(&l.waited, int64(time.Since(start)))
+	}()
+	var err error
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-l.closed:
+		err = ErrShutdown
+	}
+	l.mu.Lock()
+	granted := w.granted
+	if !granted {
+		q.Remove(e)
+	}
+	l.mu.Unlock()
+	if granted {
+		// The token arrived as the wait was abandoned, pass it on.
+		l.add(1)
+	}
+	return err
+}
+
+// close fails the current and future waiters with ErrShutdown.
+func (l *limiter) close() {
+	l.closeOnce.Do(func() { close(l.closed) })
+}
+
+// add hands n tokens to the waiters, and keeps the remainder up to the burst size. Within a traffic class waiters
+// are served highest priority first.
+func (l *limiter) add(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for ; n > 0; n-- {
+		class, ok := l.nextClass()
+		if !ok {
+			break
+		}
+		for i := range l.waiting[class] {
+			if q := &l.waiting[class][i]; q.Len() > 0 {
+				w := q.Remove(q.Front()).(*limiterWaiter)
+				w.granted = true
+				close(w.ready)
+				break
+			}
+		}
+		l.served[class]++
+	}
+	l.tokens += n
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+// nextClass picks the traffic class to hand the next token to: the one furthest below its share if both are
+// waiting, otherwise whichever is. l.mu must be held.
+func (l *limiter) nextClass() (trafficClass, bool) {
+	reads, writes := l.backlogged(readTraffic), l.backlogged(writeTraffic)
+	if reads && writes {
+		if l.served[readTraffic]/l.shares[readTraffic] <= l.served[writeTraffic]/l.shares[writeTraffic] {
+			return readTraffic, true
+		}
+		return writeTraffic, true
+	}
+	// Without contention there's nothing to be fair about, start counting afresh.
+	l.served = [2]float64{}
+	if writes {
+		return writeTraffic, true
+	}
+	return readTraffic, reads
+}
+
+// backlogged reports whether any waiter of class is queued. l.mu must be held.
+func (l *limiter) backlogged(class trafficClass) bool {
+	for i := range l.waiting[class] {
+		if l.waiting[class][i].Len() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// resize changes the burst size to burst, dropping the tokens beyond it, and the refill interval to interval.
+func (l *limiter) resize(burst int, interval time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.burst, l.interval = burst, interval
+	if l.tokens > burst {
+		l.tokens = burst
+	}
+}
+
+// available returns the number of tokens that can be taken without waiting.
+func (l *limiter) available() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.tokens
+}
+
+// LimiterState is a snapshot of the rate limiter and quotas, persisted so that restarts don't reset quota
+// accounting.
+type LimiterState struct {
+	// Tokens is the number of requests that could be made immediately.
+	Tokens int
+	// Time is when the snapshot was taken.
+	Time time.Time
+	// Quotas holds the usage of the client's quotas, in the order they were added.
+	Quotas []QuotaState `json:",omitempty"`
+}
+
+// QuotaState is the usage of a Quota in the window ending at Reset.
+type QuotaState struct {
+	Used  int
+	Reset time.Time
+}
+
+// LimiterStore persists LimiterState between runs of a process. Implementations must be safe for concurrent use.
+type LimiterStore interface {
+	// Load returns the last saved state, or false if there is none.
+	Load() (LimiterState, bool, error)
+	Save(LimiterState) error
+}
+
+// WithLimiterStore configures the client to save its rate limiter state to store every interval, and to resume from
+// the saved state when created, so a crash loop or rolling deploy doesn't hand out a fresh burst of requests, or a
+// fresh quota, on every start. The usage of a quota is restored if its window has not ended since it was saved; the
+// requests made since the last save are not counted, so interval should be short for quotas that matter. interval
+// defaults to one second. Errors saving the state are ignored; the next save tries again.
+func WithLimiterStore(store LimiterStore, interval time.Duration) ClientOption {
+	return func(c *Client) error {
+		if interval <= 0 {
+			interval = time.Second
+		}
+		c.limiterStore = store
+		c.limiterSaveInterval = interval
+		return nil
+	}
+}
+
+// saveLimiterState saves a snapshot of the rate limiter. It runs on the scheduler, so the save itself is done in the
+// background, skipping a save while the previous one is still running.
+func (c *Client) saveLimiterState() {
+	if !atomic.CompareAndSwapInt32(&c.limiterSaving, 0, 1) {
+		return
+	}
+	state := c.limiterState()
+	go func() {
+		defer atomic.StoreInt32(&c.limiterSaving, 0)
+		c.limiterStore.Save(state)
+	}()
+}
+
+// limiterState returns a snapshot of the rate limiter and quotas.
+func (c *Client) limiterState() LimiterState {
+	state := LimiterState{Tokens: c.rateLimiter.available(), Time: c.clock.Now()}
+	for _, u := range c.QuotaUsage() {
+		state.Quotas = append(state.Quotas, QuotaState{Used: u.Used, Reset: u.Reset})
+	}
+	return state
+}
+
+// FileLimiterStore is a LimiterStore keeping the state in a JSON file.
+type FileLimiterStore struct {
+	Path string
+}
+
+// Load reads the state from the file. A missing file means there is no saved state.
+func (f FileLimiterStore) Load() (LimiterState, bool, error) {
+	var state LimiterState
+	data, err := os.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return state, false, nil
+	}
+	if err != nil {
+		return state, false, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, false, err
+	}
+	return state, true, nil
+}
+
+// Save atomically replaces the file with state.
+func (f FileLimiterStore) Save(state LimiterState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), filepath.Base(f.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
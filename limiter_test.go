@@ -0,0 +1,87 @@
+package apiclient
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestRateLimitBounds(t *testing.T) {
+	tests := []struct {
+		name         string
+		rps          int
+		wantErr      bool
+		wantInterval time.Duration
+	}{
+		{name: "zero", rps: 0, wantErr: true},
+		{name: "negative", rps: -1, wantErr: true},
+		{name: "one", rps: 1, wantInterval: time.Second},
+		{name: "default", rps: defaultRequestsPerSecond, wantInterval: time.Second / time.Duration(defaultRequestsPerSecond)},
+		{name: "one per nanosecond", rps: maxRequestsPerSecond, wantInterval: time.Nanosecond},
+		{name: "above one per nanosecond", rps: maxRequestsPerSecond + 1, wantErr: true},
+		{name: "far above", rps: 1 << 40, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Run("WithRateLimit", func(t *testing.T) {
+				c, err := NewClient(WithRateLimit(tt.rps))
+				if tt.wantErr {
+					if err == nil {
+						t.Fatal("NewClient succeeded")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if c.rateLimiter.interval != tt.wantInterval {
+					t.Errorf("interval = %v, want %v", c.rateLimiter.interval, tt.wantInterval)
+				}
+			})
+			t.Run("SetRateLimit", func(t *testing.T) {
+				c := newTestClient(t)
+				err := c.SetRateLimit(tt.rps)
+				if tt.wantErr {
+					if err == nil {
+						t.Fatal("SetRateLimit succeeded")
+					}
+					if c.requestsPerSecond != 1000 {
+						t.Errorf("rate changed to %d", c.requestsPerSecond)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if c.rateLimiter.interval != tt.wantInterval {
+					t.Errorf("interval = %v, want %v", c.rateLimiter.interval, tt.wantInterval)
+				}
+			})
+		})
+	}
+}
+
+func TestRefillCatchesUp(t *testing.T) {
+	tests := []struct {
+		name string
+		rps  int
+	}{
+		{name: "slow", rps: 10},
+		{name: "one per nanosecond", rps: maxRequestsPerSecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewClient(WithRateLimit(tt.rps), WithRateLimitBurst(2))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 2; i++ {
+				if err := c.rateLimiter.wait(context.Background(), readTraffic, PriorityNormal); err != nil {
+					t.Fatal(err)
+				}
+			}
+			eventually(t, func() bool { return c.rateLimiter.available() == 2 })
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package apiclient
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrOverloaded is returned for requests turned away by load shedding, see WithLoadShedding.
+var ErrOverloaded = errors.New("apiclient: overloaded, rate limiter backlog too long")
+
+// WithLoadShedding configures the client to fail requests fast with ErrOverloaded, instead of queueing them for the
+// rate limiter, once maxQueue requests are already waiting for it or their wait would exceed maxWait, so that a slow
+// upstream makes for quick errors rather than a pile-up. The wait is estimated from the requests of the same or a
+// higher priority queued ahead and the rate limit. Zero leaves either bound out.
+func WithLoadShedding(maxQueue int, maxWait time.Duration) ClientOption {
+	return func(c *Client) error {
+		if maxQueue < 0 || maxWait < 0 {
+			return errors.New("apiclient: negative load shedding bound")
+		}
+		c.shedQueue, c.shedWait = maxQueue, maxWait
+		return nil
+	}
+}
+
+// overloaded reports whether a taker of priority p must be turned away rather than queued. l.mu must be held.
+func (l *limiter) overloaded(p Priority) bool {
+	if l.maxQueue == 0 && l.maxWait == 0 {
+		return false
+	}
+	// The queues of a class go from PriorityHigh to PriorityLow; those up to the taker's are ahead of it.
+	last := 1
+	switch {
+	case p > PriorityNormal:
+		last = 0
+	case p < PriorityNormal:
+		last = 2
+	}
+	var queued, ahead int
+	for class := range l.waiting {
+		for i := range l.waiting[class] {
+			n := l.waiting[class][i].Len()
+			queued += n
+			if i <= last {
+				ahead += n
+			}
+		}
+	}
+	if l.maxQueue > 0 && queued >= l.maxQueue {
+		return true
+	}
+	return l.maxWait > 0 && time.Duration(ahead+1)*l.interval > l.maxWait
+}
@@ -0,0 +1,71 @@
+package apiclient
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// ResponseMeta describes the response to a call. Pass one to WithResponseMeta to have it filled in.
+type ResponseMeta struct {
+	StatusCode int
+	// Header holds the response headers, such as pagination links, rate-limit counters and the provider's request ID.
+	Header http.Header
+	// Latency is the time from the start of the call until the response headers were received, including rate
+	// limiting, retries and failover.
+	Latency time.Duration
+	// Attempts is the number of requests sent for the call: more than one after failing over between base URLs, and
+	// none if the response came from the cache.
+	Attempts int
+	// Replayed is true if the provider reported that it answered with the stored result of an earlier request with
+	// the same idempotency key, instead of performing the request again.
+	Replayed bool
+	// Proto is the protocol the response came over, such as "HTTP/1.1", "HTTP/2.0" or "HTTP/3.0".
+	Proto string
+
+	start time.Time
+}
+
+// ReplayHeaders are the response headers whose value "true" marks a replayed idempotent request.
+var ReplayHeaders = []string{"Idempotent-Replayed", "X-Idempotent-Replayed", "Idempotency-Replayed"}
+
+// WithResponseMeta makes a call fill in meta from the response it gets.
+func WithResponseMeta(meta *ResponseMeta) RequestOption {
+	return func(o *requestOptions) {
+		o.meta = meta
+	}
+}
+
+type metaKey struct{}
+
+// withMeta returns a copy of ctx that counts the attempts of its call into meta, starting the call's latency.
+func withMeta(ctx context.Context, meta *ResponseMeta) context.Context {
+	*meta = ResponseMeta{}
+	meta.start = time.Now()
+	return context.WithValue(ctx, metaKey{}, meta)
+}
+
+// countAttempt records that a request is sent for the call of ctx.
+func countAttempt(ctx context.Context) {
+	if meta, _ := ctx.Value(metaKey{}).(*ResponseMeta); meta != nil {
+		meta.Attempts++
+	}
+}
+
+// fill sets the fields of meta that come from resp.
+func (meta *ResponseMeta) fill(resp *http.Response) {
+	meta.StatusCode = resp.StatusCode
+	meta.Header = resp.Header
+	meta.Proto = resp.Proto
+	if !meta.start.IsZero() {
+		meta.Latency = time.Since(meta.start)
+	}
+	meta.Replayed = false
+	for _, h := range ReplayHeaders {
+		if strings.EqualFold(resp.Header.Get(h), "true") {
+			meta.Replayed = true
+		}
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !apiclient_slim
+
+package apiclient
+
+import (
+	"github.com/MaTriXy/api-client/codec/msgpack"
+)
+
+// MessagePackCodec is the Codec of application/msgpack. It honors json struct tags, so the same values can be
+// decoded from JSON and MessagePack. It is a msgpack.Codec, which binaries built with the apiclient_slim tag register
+// themselves.
+var MessagePackCodec Codec = msgpack.Codec{}
+
+// WithMessagePack configures GetJSON to ask for MessagePack instead of JSON, to save bandwidth with servers that
+// support it. Responses sent as application/msgpack (or application/x-msgpack) are decoded with MessagePackCodec into
+// the same values as JSON would be; any other response is decoded as usual. Key normalization does not apply to
+// MessagePack responses.
+func WithMessagePack() ClientOption {
+	return func(c *Client) error {
+		c.registerCodec(msgpack.MediaType, MessagePackCodec)
+		c.registerCodec(msgpack.LegacyMediaType, MessagePackCodec)
+		return nil
+	}
+}
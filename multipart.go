@@ -0,0 +1,108 @@
+package apiclient
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// MultipartFile is a file part of a multipart/form-data body.
+type MultipartFile struct {
+	// Field is the name of the form field.
+	Field string
+	// Name is the file name sent to the server.
+	Name string
+	// ContentType defaults to application/octet-stream.
+	ContentType string
+	Data        io.Reader
+}
+
+// errBodyConsumed is returned when a streamed body would have to be sent a second time.
+var errBodyConsumed = errors.New("apiclient: request body already sent")
+
+// PostMultipart POSTs a multipart/form-data body made of fields and files to the API endpoint, and decodes the JSON
+// response into resp like GetJSON does. The files are streamed from their readers as the request is sent, never
+// buffered whole, so each can be read only once: such requests do not fail over between base URLs.
+func (c *Client) PostMultipart(ctx context.Context, config *APIConfig, apiReq APIRequest, fields url.Values, files []MultipartFile, resp interface{}, opts ...RequestOption) error {
+	o := c.newRequestOptions(opts)
+	ctx, cancel := o.context(ctx, config)
+	defer cancel()
+	ctx = c.withRequestID(defaultAccept(ctx, c.codecAccept))
+
+	body := multipartBody(fields, files)
+	httpResp, err := c.send(ctx, "POST", config, apiReq, nil, body)
+	if err != nil {
+		return c.requestError(ctx, err)
+	}
+	defer httpResp.Body.Close()
+	if o.meta != nil {
+		o.meta.fill(httpResp)
+	}
+	return c.requestError(ctx, c.decodeJSON(httpResp, resp, o))
+}
+
+// multipartBody returns a body that writes fields, in key order, and files into a pipe as it is read.
+func multipartBody(fields url.Values, files []MultipartFile) *requestBody {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	opened := false
+	return &requestBody{
+		contentType: mw.FormDataContentType(),
+		open: func() (io.ReadCloser, error) {
+			if opened {
+				return nil, errBodyConsumed
+			}
+			opened = true
+			go func() {
+				pw.CloseWithError(writeMultipart(mw, fields, files))
+			}()
+			return pr, nil
+		},
+	}
+}
+
+// writeMultipart writes the parts of a form to mw and closes it.
+func writeMultipart(mw *multipart.Writer, fields url.Values, files []MultipartFile) error {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range fields[k] {
+			if err := mw.WriteField(k, v); err != nil {
+				return err
+			}
+		}
+	}
+	for _, f := range files {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", `form-data; name="`+escapeQuotes(f.Field)+`"; filename="`+escapeQuotes(f.Name)+`"`)
+		ct := f.ContentType
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+		h.Set("Content-Type", ct)
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, f.Data); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// escapeQuotes escapes a form field or file name for a Content-Disposition header, like mime/multipart does.
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
@@ -0,0 +1,43 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"io"
+
+	"golang.org/x/net/context"
+)
+
+// GetJSONStream fetches a newline-delimited JSON (NDJSON / JSON Lines) response and passes its records to handle
+// one at a time as they arrive, so arbitrarily large exports are consumed in constant memory. It stops at the
+// first error returned by handle. The response is never cached or shared between callers.
+func (c *Client) GetJSONStream(ctx context.Context, config *APIConfig, apiReq APIRequest, handle func(json.RawMessage) error, opts ...RequestOption) error {
+	o := c.newRequestOptions(opts)
+	ctx, cancel := o.context(ctx, config)
+	defer cancel()
+	ctx = c.withRequestID(ctx)
+	httpResp, err := c.send(ctx, "GET", config, apiReq, nil, nil)
+	if err != nil {
+		return c.requestError(ctx, err)
+	}
+	defer httpResp.Body.Close()
+	if o.meta != nil {
+		o.meta.fill(httpResp)
+	}
+
+	body, err := c.jsonBody(httpResp)
+	if err != nil {
+		return c.requestError(ctx, err)
+	}
+	dec := json.NewDecoder(body)
+	for {
+		var record json.RawMessage
+		if err := dec.Decode(&record); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return c.requestError(ctx, err)
+		}
+		if err := handle(record); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package apiclient
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// WithNegativeCache configures the client to remember 404 Not Found and 410 Gone responses for ttl, answering
+// repeated GETs for the same URL from memory. Both fresh and cached negative results are returned as *HTTPError.
+// Use InvalidateNegativeCache once the resource has been created.
+func WithNegativeCache(ttl time.Duration) ClientOption {
+	return func(c *Client) error {
+		c.negativeCache = &negativeCache{ttl: ttl, entries: make(map[string]negativeEntry)}
+		return nil
+	}
+}
+
+// InvalidateNegativeCache forgets a cached negative result for the given request made with ctx.
+func (c *Client) InvalidateNegativeCache(ctx context.Context, config *APIConfig, apiReq APIRequest) {
+	if c.negativeCache != nil {
+		c.negativeCache.delete(c.cacheKey(c.defaultsContext(ctx), "GET", config, apiReq))
+	}
+}
+
+type negativeCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]negativeEntry
+}
+
+type negativeEntry struct {
+	err     HTTPError
+	expires time.Time
+}
+
+// isNegative reports whether status is cacheable as a negative result.
+func isNegative(status int) bool {
+	return status == http.StatusNotFound || status == http.StatusGone
+}
+
+// get returns the cached negative result for key, or nil.
+func (nc *negativeCache) get(key string, now time.Time) *HTTPError {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	e, ok := nc.entries[key]
+	if !ok || !now.Before(e.expires) {
+		return nil
+	}
+	err := e.err
+	return &err
+}
+
+// put records resp as the negative result for key, evicting it through s once it expires.
+func (nc *negativeCache) put(s *scheduler, key string, resp *http.Response, now time.Time) {
+	expires := now.Add(nc.ttl)
+	nc.mu.Lock()
+	nc.entries[key] = negativeEntry{err: HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}, expires: expires}
+	nc.mu.Unlock()
+	s.after(nc.ttl, func() {
+		nc.mu.Lock()
+		if e, ok := nc.entries[key]; ok && e.expires.Equal(expires) {
+			delete(nc.entries, key)
+		}
+		nc.mu.Unlock()
+	})
+}
+
+func (nc *negativeCache) delete(key string) {
+	nc.mu.Lock()
+	delete(nc.entries, key)
+	nc.mu.Unlock()
+}
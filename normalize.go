@@ -0,0 +1,85 @@
+package apiclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// WithKeyNormalizer configures GetJSON to rewrite every object key of a response with normalize before decoding it,
+// for providers that are inconsistent about key casing across endpoints or versions. Note that encoding/json
+// already matches keys to struct fields case-insensitively; normalizing with SnakeToCamel additionally lets
+// "user_id" populate a field tagged "userId".
+func WithKeyNormalizer(normalize func(key string) string) ClientOption {
+	return func(c *Client) error {
+		c.keyNormalizer = normalize
+		return nil
+	}
+}
+
+// SnakeToCamel converts a snake_case or kebab-case key to camelCase.
+func SnakeToCamel(key string) string {
+	var b strings.Builder
+	upper := false
+	for i, r := range key {
+		switch {
+		case r == '_' || r == '-':
+			upper = i > 0
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// CamelToSnake converts a camelCase or PascalCase key to snake_case.
+func CamelToSnake(key string) string {
+	var b strings.Builder
+	runes := []rune(key)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// decodeNormalized decodes the JSON document in r into v, after rewriting its object keys with normalize.
+func decodeNormalized(r io.Reader, v interface{}, normalize func(string) string) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(normalizeKeys(doc, normalize)); err != nil {
+		return err
+	}
+	return json.NewDecoder(&buf).Decode(v)
+}
+
+func normalizeKeys(doc interface{}, normalize func(string) string) interface{} {
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(d))
+		for k, v := range d {
+			m[normalize(k)] = normalizeKeys(v, normalize)
+		}
+		return m
+	case []interface{}:
+		for i, v := range d {
+			d[i] = normalizeKeys(v, normalize)
+		}
+	}
+	return doc
+}
@@ -0,0 +1,95 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/context"
+)
+
+// PagedRequest is implemented by requests for a paginated collection.
+type PagedRequest interface {
+	Params() url.Values
+	// ParsePage extracts the items of one page from its response, and returns the request for the following page,
+	// or nil if this was the last one.
+	ParsePage(header http.Header, body []byte) (items []json.RawMessage, next PagedRequest, err error)
+}
+
+// Pager walks a paginated collection one page at a time.
+type Pager struct {
+	client *Client
+	config *APIConfig
+	next   PagedRequest
+	items  []json.RawMessage
+	err    error
+}
+
+// NewPager returns a Pager starting at the page requested by apiReq.
+func (c *Client) NewPager(config *APIConfig, apiReq PagedRequest) *Pager {
+	return &Pager{client: c, config: config, next: apiReq}
+}
+
+// Next fetches the next page. It returns false once the collection is exhausted or a request failed, see Err.
+func (p *Pager) Next(ctx context.Context) bool {
+	if p.next == nil || p.err != nil {
+		return false
+	}
+	ctx = p.client.withRequestID(p.client.defaultsContext(ctx))
+	httpResp, body, err := p.client.getBody(ctx, p.config, p.next)
+	if err != nil {
+		p.err = p.client.requestError(ctx, err)
+		return false
+	}
+	p.items, p.next, err = p.next.ParsePage(httpResp.Header, body)
+	if err != nil {
+		p.err = p.client.requestError(ctx, err)
+		return false
+	}
+	return true
+}
+
+// Items returns the items of the current page.
+func (p *Pager) Items() []json.RawMessage {
+	return p.items
+}
+
+// Err returns the error that stopped the Pager, if any.
+func (p *Pager) Err() error {
+	return p.err
+}
+
+// GetAllJSON walks every page of the collection starting at apiReq and passes each item to perItem. It stops at the
+// first error returned by perItem, or when ctx is done.
+func (c *Client) GetAllJSON(ctx context.Context, config *APIConfig, apiReq PagedRequest, perItem func(json.RawMessage) error) error {
+	p := c.NewPager(config, apiReq)
+	for p.Next(ctx) {
+		for _, item := range p.Items() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := perItem(item); err != nil {
+				return err
+			}
+		}
+	}
+	return p.Err()
+}
+
+// getBody performs a GET and reads its whole JSON body. The returned response's body is already closed.
+func (c *Client) getBody(ctx context.Context, config *APIConfig, apiReq APIRequest) (*http.Response, []byte, error) {
+	httpResp, err := c.get(ctx, config, apiReq)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer httpResp.Body.Close()
+	r, err := c.jsonBody(httpResp)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := readAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return httpResp, body, nil
+}
@@ -0,0 +1,65 @@
+package apiclient
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// PathRequest is implemented by requests that supply the values of the placeholders in their APIConfig's Path, such
+// as "/v1/users/{id}/orders/{orderID}".
+type PathRequest interface {
+	PathParams() map[string]string
+}
+
+// WithPathParam supplies the value of the {name} placeholder in the APIConfig's Path for a single call, overriding
+// any value given by the request's PathParams.
+func WithPathParam(name, value string) RequestOption {
+	return func(o *requestOptions) {
+		if o.pathParams == nil {
+			o.pathParams = map[string]string{}
+		}
+		o.pathParams[name] = value
+	}
+}
+
+type pathParamsKey struct{}
+
+// expandPath returns the path of config with its placeholders replaced by the path-escaped values supplied by the call
+// options carried by ctx, or else by apiReq. A placeholder without a value is an error.
+func expandPath(ctx context.Context, config *APIConfig, apiReq APIRequest) (string, error) {
+	path := config.Path
+	if !strings.Contains(path, "{") {
+		return path, nil
+	}
+	fromCall, _ := ctx.Value(pathParamsKey{}).(map[string]string)
+	var fromRequest map[string]string
+	if pr, ok := apiReq.(PathRequest); ok {
+		fromRequest = pr.PathParams()
+	}
+	var b strings.Builder
+	for {
+		open := strings.IndexByte(path, '{')
+		if open < 0 {
+			b.WriteString(path)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(path[open:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("apiclient: unterminated placeholder in path %q", config.Path)
+		}
+		name := path[open+1 : open+end]
+		value, ok := fromCall[name]
+		if !ok {
+			value, ok = fromRequest[name]
+		}
+		if !ok {
+			return "", fmt.Errorf("apiclient: no value for path parameter {%s} of %q", name, config.Path)
+		}
+		b.WriteString(path[:open])
+		b.WriteString(url.PathEscape(value))
+		path = path[open+end+1:]
+	}
+}
@@ -0,0 +1,123 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// minPollBackoff is the first delay after a failed poll when no interval was given.
+	minPollBackoff = time.Second
+	// maxPollBackoff caps the delay between failed polls.
+	maxPollBackoff = 5 * time.Minute
+)
+
+// PollResult is an item received by Poll, or an error that interrupted polling for a while.
+type PollResult struct {
+	Item json.RawMessage
+	Err  error
+}
+
+// Poll long-polls the endpoint of apiReq until ctx is done, sending every item received, and every error met, on the
+// returned channel. The channel is closed once ctx is done.
+//
+// apiReq carries the wait and cursor parameters of the endpoint; ParsePage returns the request for the following
+// poll, typically with an advanced cursor, or nil to repeat the same request. Polls are issued interval apart, or
+// after the delay given by the server in a Retry-After header. After a failed poll, or a 4xx/5xx response, the delay
+// doubles, starting at interval or one second, up to five minutes, and is reset by the next successful poll.
+func (c *Client) Poll(ctx context.Context, config *APIConfig, apiReq PagedRequest, interval time.Duration) <-chan PollResult {
+	ctx = c.defaultsContext(ctx)
+	results := make(chan PollResult)
+	go func() {
+		defer close(results)
+		backoff := time.Duration(0)
+		for {
+			items, next, wait, err := c.poll(ctx, config, apiReq)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				switch {
+				case backoff == 0 && interval > 0:
+					backoff = interval
+				case backoff == 0:
+					backoff = minPollBackoff
+				default:
+					backoff *= 2
+				}
+				if backoff > maxPollBackoff {
+					backoff = maxPollBackoff
+				}
+				select {
+				case results <- PollResult{Err: err}:
+				case <-ctx.Done():
+					return
+				}
+				if wait < backoff {
+					wait = backoff
+				}
+			} else {
+				backoff = 0
+				for _, item := range items {
+					select {
+					case results <- PollResult{Item: item}:
+					case <-ctx.Done():
+						return
+					}
+				}
+				if next != nil {
+					apiReq = next
+				}
+				if wait < 0 {
+					wait = interval
+				}
+			}
+			if err := c.scheduler.sleep(ctx, wait); err != nil {
+				return
+			}
+		}
+	}()
+	return results
+}
+
+// poll issues a single poll. wait is the delay the server asked for before the next one, or -1 if it gave none.
+func (c *Client) poll(ctx context.Context, config *APIConfig, apiReq PagedRequest) (items []json.RawMessage, next PagedRequest, wait time.Duration, err error) {
+	ctx = c.withRequestID(ctx)
+	httpResp, body, err := c.getBody(ctx, config, apiReq)
+	if err != nil {
+		return nil, nil, -1, c.requestError(ctx, err)
+	}
+	wait = c.retryAfter(httpResp.Header)
+	switch {
+	case httpResp.StatusCode >= 400:
+		return nil, nil, wait, c.requestError(ctx, &HTTPError{StatusCode: httpResp.StatusCode, Status: httpResp.Status})
+	case httpResp.StatusCode == http.StatusNoContent || httpResp.StatusCode == http.StatusNotModified:
+		// The wait elapsed with nothing new.
+		return nil, nil, wait, nil
+	}
+	items, next, err = apiReq.ParsePage(httpResp.Header, body)
+	return items, next, wait, c.requestError(ctx, err)
+}
+
+// retryAfter returns the delay requested by a Retry-After header, in seconds or as an HTTP date, or -1 if there is
+// none.
+func (c *Client) retryAfter(header http.Header) time.Duration {
+	v := header.Get("Retry-After")
+	if v == "" {
+		return -1
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(c.clock.Now()); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return -1
+}
@@ -0,0 +1,120 @@
+package apiclient
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+
+	"golang.org/x/net/context"
+)
+
+// Pool spreads requests over several Clients built from the same options, each with its own rate limiter and
+// internal state, to avoid contention on a single Client at very high request rates. The configured rate limit is
+// divided among the shards, as is the burst size, so the pool as a whole keeps to them.
+type Pool struct {
+	shards []*Client
+	next   uint32
+}
+
+// NewPool returns a Pool of n Clients configured with options, or one per CPU if n is not positive. State passed in
+// through options, such as a Cache, is shared by every shard. Every shard needs at least one request per second
+// and, if a burst is set, a burst of one, so n may not exceed either; one per CPU is reduced to fit them.
+func NewPool(n int, options ...ClientOption) (*Pool, error) {
+	perCPU := n <= 0
+	if perCPU {
+		n = runtime.GOMAXPROCS(0)
+	}
+	p := &Pool{}
+	for i := 0; i < n; i++ {
+		shard := i
+		opts := append(options[:len(options):len(options)], func(c *Client) error {
+			if shard == 0 && perCPU {
+				// The first shard settles how many there are, once the options set the rate limit.
+				n = shardsFor(n, c.requestsPerSecond, c.rateLimitBurst)
+			}
+			if err := checkShares(n, c.requestsPerSecond, c.rateLimitBurst); err != nil {
+				return err
+			}
+			c.requestsPerSecond = shareOf(c.requestsPerSecond, n, shard)
+			if c.rateLimitBurst > 0 {
+				c.rateLimitBurst = shareOf(c.rateLimitBurst, n, shard)
+			}
+			return nil
+		})
+		c, err := NewClient(opts...)
+		if err != nil {
+			return nil, err
+		}
+		p.shards = append(p.shards, c)
+	}
+	return p, nil
+}
+
+// shardsFor returns n, reduced so that each shard gets a part of requestsPerSecond and of burst, if set.
+func shardsFor(n, requestsPerSecond, burst int) int {
+	if requestsPerSecond > 0 && n > requestsPerSecond {
+		n = requestsPerSecond
+	}
+	if burst > 0 && n > burst {
+		n = burst
+	}
+	return n
+}
+
+// checkShares returns an error if requestsPerSecond or burst, if set, cannot be divided among n shards.
+func checkShares(n, requestsPerSecond, burst int) error {
+	if requestsPerSecond < n {
+		return fmt.Errorf("apiclient: rate limit %d cannot be shared by %d shards", requestsPerSecond, n)
+	}
+	if burst > 0 && burst < n {
+		return fmt.Errorf("apiclient: rate limit burst %d cannot be shared by %d shards", burst, n)
+	}
+	return nil
+}
+
+// shareOf returns the part of total given to shard out of n, which total must be at least. The remainder of the
+// division is handed out to the first shards, so no capacity is lost.
+func shareOf(total, n, shard int) int {
+	share := total / n
+	if shard < total%n {
+		share++
+	}
+	return share
+}
+
+// SetRateLimit changes the rate limit of the pool as a whole, dividing it among the shards like NewPool, so it may
+// not be lower than the number of shards. See Client.SetRateLimit.
+func (p *Pool) SetRateLimit(requestsPerSecond int) error {
+	if err := checkRate(requestsPerSecond); err != nil {
+		return err
+	}
+	if err := checkShares(len(p.shards), requestsPerSecond, 0); err != nil {
+		return err
+	}
+	for i, c := range p.shards {
+		if err := c.SetRateLimit(shareOf(requestsPerSecond, len(p.shards), i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Client returns the shard to use for the next request, rotating through them.
+func (p *Pool) Client() *Client {
+	return p.shards[int(atomic.AddUint32(&p.next, 1)-1)%len(p.shards)]
+}
+
+// Shards returns all Clients of the pool.
+func (p *Pool) Shards() []*Client {
+	return p.shards
+}
+
+// GetJSON calls GetJSON on the next shard.
+func (p *Pool) GetJSON(ctx context.Context, config *APIConfig, apiReq APIRequest, resp interface{}, opts ...RequestOption) error {
+	return p.Client().GetJSON(ctx, config, apiReq, resp, opts...)
+}
+
+// GetBinary calls GetBinary on the next shard.
+func (p *Pool) GetBinary(ctx context.Context, config *APIConfig, apiReq APIRequest, opts ...RequestOption) (BinaryResponse, error) {
+	return p.Client().GetBinary(ctx, config, apiReq, opts...)
+}
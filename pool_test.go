@@ -0,0 +1,89 @@
+package apiclient
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestNewPoolSharesRateLimit(t *testing.T) {
+	cpus := runtime.GOMAXPROCS(0)
+	tests := []struct {
+		name       string
+		n          int
+		rps, burst int
+		wantShards int
+		wantErr    bool
+	}{
+		{name: "even", n: 4, rps: 100, wantShards: 4},
+		{name: "remainder", n: 3, rps: 10, burst: 5, wantShards: 3},
+		{name: "one per second each", n: 8, rps: 8, wantShards: 8},
+		{name: "rate below shards", n: 8, rps: 4, wantErr: true},
+		{name: "burst below shards", n: 4, rps: 100, burst: 2, wantErr: true},
+		{name: "per CPU", rps: 1000, wantShards: cpus},
+		{name: "per CPU above rate", rps: 1, wantShards: 1},
+		{name: "per CPU above burst", rps: 1000, burst: 1, wantShards: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := []ClientOption{WithRateLimit(tt.rps)}
+			if tt.burst > 0 {
+				options = append(options, WithRateLimitBurst(tt.burst))
+			}
+			p, err := NewPool(tt.n, options...)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("NewPool succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(p.Shards()) != tt.wantShards {
+				t.Fatalf("%d shards, want %d", len(p.Shards()), tt.wantShards)
+			}
+			rps, burst := 0, 0
+			for _, c := range p.Shards() {
+				rps += c.requestsPerSecond
+				burst += c.rateLimitBurst
+			}
+			if rps != tt.rps || burst != tt.burst {
+				t.Errorf("shards total %d rps and a burst of %d, want %d and %d", rps, burst, tt.rps, tt.burst)
+			}
+		})
+	}
+}
+
+func TestPoolSetRateLimit(t *testing.T) {
+	tests := []struct {
+		rps     int
+		wantErr bool
+	}{
+		{rps: 40},
+		{rps: 5},
+		{rps: 4},
+		{rps: 3, wantErr: true},
+		{rps: 0, wantErr: true},
+	}
+	p, err := NewPool(4, WithRateLimit(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		err := p.SetRateLimit(tt.rps)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SetRateLimit(%d) = %v, want error %v", tt.rps, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		total := 0
+		for _, c := range p.Shards() {
+			total += c.requestsPerSecond
+		}
+		if total != tt.rps {
+			t.Errorf("SetRateLimit(%d): shards total %d rps", tt.rps, total)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+//go:build !apiclient_slim
+
+package apiclient
+
+import (
+	"net/http"
+
+	"golang.org/x/net/context"
+	"google.golang.org/protobuf/proto"
+)
+
+// protoContentType is the media type of Protocol Buffers messages sent over HTTP.
+const protoContentType = "application/x-protobuf"
+
+// GetProto makes a request to the API endpoint, asking for a Protocol Buffers response, and unmarshals it into resp.
+// Binaries built with the apiclient_slim tag leave GetProto and PostProto out, and do not link Protocol Buffers.
+func (c *Client) GetProto(ctx context.Context, config *APIConfig, apiReq APIRequest, resp proto.Message, opts ...RequestOption) error {
+	o := c.newRequestOptions(opts)
+	ctx, cancel := o.context(ctx, config)
+	defer cancel()
+	ctx = c.withRequestID(defaultAccept(ctx, protoContentType))
+	httpResp, err := c.get(ctx, config, apiReq)
+	if err != nil {
+		return c.requestError(ctx, err)
+	}
+	defer httpResp.Body.Close()
+	if o.meta != nil {
+		o.meta.fill(httpResp)
+	}
+	return c.requestError(ctx, c.decodeProto(httpResp, resp, o))
+}
+
+// PostProto POSTs the Protocol Buffers message req to the API endpoint and unmarshals the response into resp.
+func (c *Client) PostProto(ctx context.Context, config *APIConfig, apiReq APIRequest, req, resp proto.Message, opts ...RequestOption) error {
+	o := c.newRequestOptions(opts)
+	ctx, cancel := o.context(ctx, config)
+	defer cancel()
+	ctx = c.withRequestID(defaultAccept(ctx, protoContentType))
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return c.requestError(ctx, err)
+	}
+	httpResp, err := c.send(ctx, "POST", config, apiReq, nil, bytesBody(protoContentType, data))
+	if err != nil {
+		return c.requestError(ctx, err)
+	}
+	defer httpResp.Body.Close()
+	if o.meta != nil {
+		o.meta.fill(httpResp)
+	}
+	return c.requestError(ctx, c.decodeProto(httpResp, resp, o))
+}
+
+// decodeProto unmarshals the body of httpResp into resp, with the call's decoder if it has one.
+func (c *Client) decodeProto(httpResp *http.Response, resp proto.Message, o *requestOptions) error {
+	if o.decoder != nil {
+		return o.decoder(httpResp, resp)
+	}
+	data, err := readAll(httpResp.Body)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(data, resp)
+}
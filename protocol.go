@@ -0,0 +1,119 @@
+package apiclient
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// protocolRetry is how long a host that failed to speak a protocol is sent HTTP/1.1 before the protocol is tried
+// again.
+const protocolRetry = 5 * time.Minute
+
+// WithHTTP2 configures the client to use HTTP/2. Over TLS, it is negotiated with each host, which falls back to
+// HTTP/1.1 if it does not support it; this is what the default transport does already, but not once its dialer or
+// TLS settings are changed. With priorKnowledge, cleartext http:// hosts are also spoken HTTP/2 directly (h2c),
+// without a proxy; a host that turns out not to understand it is sent HTTP/1.1 for five minutes instead. Binaries
+// built with the apiclient_slim tag do not support priorKnowledge.
+func WithHTTP2(priorKnowledge bool) ClientOption {
+	return func(c *Client) error {
+		base, err := c.baseTransport()
+		if err != nil {
+			return err
+		}
+		base.ForceAttemptHTTP2 = true
+		if !priorKnowledge {
+			return nil
+		}
+		h2c, err := newH2CTransport(base)
+		if err != nil {
+			return err
+		}
+		c.protocols().h2c = h2c
+		return nil
+	}
+}
+
+// WithHTTP3 configures the client to send its https:// requests over HTTP/3 through rt, such as the round tripper
+// of a QUIC implementation, which the client does not bundle. Support is experimental: a request that rt fails to
+// send is sent again over TCP if its body allows, and its host is sent HTTP/1.1 or HTTP/2 for five minutes. The
+// protocol a response came over is reported by ResponseMeta.Proto.
+func WithHTTP3(rt http.RoundTripper) ClientOption {
+	return func(c *Client) error {
+		if rt == nil {
+			return errors.New("apiclient: nil HTTP/3 round tripper")
+		}
+		c.protocols().h3 = rt
+		return nil
+	}
+}
+
+// protocols returns the protocol selection of the client's transport, adding it if needed.
+func (c *Client) protocols() *protocols {
+	t := c.httpClient.Transport.(*transport)
+	if t.protocols == nil {
+		t.protocols = &protocols{fallback: map[string]time.Time{}}
+	}
+	return t.protocols
+}
+
+// protocols sends requests over HTTP/3 or cleartext HTTP/2 when enabled, falling back to the base transport.
+type protocols struct {
+	h2c http.RoundTripper
+	h3  http.RoundTripper
+	mu  sync.Mutex
+	// fallback holds the hosts that failed to speak the protocol, until when they are sent requests over base.
+	fallback map[string]time.Time
+}
+
+func (p *protocols) roundTrip(req *http.Request, base http.RoundTripper) (*http.Response, error) {
+	var alt http.RoundTripper
+	switch {
+	case req.URL.Scheme == "https" && p.h3 != nil:
+		alt = p.h3
+	case req.URL.Scheme == "http" && p.h2c != nil:
+		alt = p.h2c
+	}
+	if alt == nil || p.fallingBack(req.URL.Host) {
+		return base.RoundTrip(req)
+	}
+	resp, err := alt.RoundTrip(req)
+	if err == nil || req.Context().Err() != nil || isDialError(err) {
+		return resp, err
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, err
+		}
+		body, bodyErr := req.GetBody()
+		if bodyErr != nil {
+			return nil, err
+		}
+		req = cloneRequest(req)
+		req.Body = body
+	}
+	p.mu.Lock()
+	p.fallback[req.URL.Host] = time.Now().Add(protocolRetry)
+	p.mu.Unlock()
+	return base.RoundTrip(req)
+}
+
+func (p *protocols) fallingBack(host string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	until, ok := p.fallback[host]
+	if ok && time.Now().After(until) {
+		delete(p.fallback, host)
+		return false
+	}
+	return ok
+}
+
+// isDialError reports whether err means the host could not be reached at all, so another protocol would fail too.
+// HTTP/3 runs over UDP, so only failures to reach a TCP host qualify.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial" && opErr.Net != "udp"
+}
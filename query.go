@@ -0,0 +1,208 @@
+package apiclient
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryMarshaler is implemented by types that encode themselves as the values of a query parameter.
+type QueryMarshaler interface {
+	MarshalQuery() ([]string, error)
+}
+
+var (
+	queryMarshalerType = reflect.TypeOf((*QueryMarshaler)(nil)).Elem()
+	textMarshalerType  = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	timeType           = reflect.TypeOf(time.Time{})
+)
+
+// EncodeQuery builds query parameters from the exported fields of the struct v points to, or is, following their url
+// tags:
+//
+//	Radius   int       `url:"radius,omitempty"`
+//	Types    []string  `url:"type"`           // one type=... parameter per element
+//	Fields   []string  `url:"fields,comma"`   // fields=a,b,c
+//	Since    time.Time `url:"since,unix"`     // seconds since the epoch; unixmilli for milliseconds
+//	Day      time.Time `url:"day" layout:"2006-01-02"`
+//	Internal string    `url:"-"`
+//
+// Fields without a tag name are named after the field; embedded structs are flattened. Times default to RFC 3339.
+// Values implementing QueryMarshaler, or else encoding.TextMarshaler, encode themselves. With omitempty, zero values
+// and empty slices are left out; nil pointers always are.
+func EncodeQuery(v interface{}) (url.Values, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return url.Values{}, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("apiclient: cannot encode %s as query parameters", rv.Type())
+	}
+	values := url.Values{}
+	if err := encodeStruct(values, rv); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// StructRequest is a request whose parameters are encoded from the struct V with EncodeQuery, so a tagged struct can
+// be used as a request without implementing Params. Params panics if V cannot be encoded, as that is a programming
+// error in the struct's definition.
+type StructRequest struct {
+	V interface{}
+}
+
+// Params returns the parameters encoded from V.
+func (r StructRequest) Params() url.Values {
+	values, err := EncodeQuery(r.V)
+	if err != nil {
+		panic(err)
+	}
+	return values
+}
+
+func encodeStruct(values url.Values, rv reflect.Value) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("url")
+		if tag == "-" {
+			continue
+		}
+		fv := rv.Field(i)
+		if f.Anonymous && tag == "" {
+			for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+				if err := encodeStruct(values, fv); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		field := queryField{name: name, layout: f.Tag.Get("layout")}
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "omitempty":
+				field.omitEmpty = true
+			case "comma":
+				field.comma = true
+			case "unix":
+				field.unix = time.Second
+			case "unixmilli":
+				field.unix = time.Millisecond
+			}
+		}
+		strs, err := field.encode(fv)
+		if err != nil {
+			return fmt.Errorf("apiclient: query parameter %s: %w", name, err)
+		}
+		if strs == nil {
+			continue
+		}
+		if field.comma {
+			strs = []string{strings.Join(strs, ",")}
+		}
+		values[name] = append(values[name], strs...)
+	}
+	return nil
+}
+
+// queryField holds the encoding options of a struct field.
+type queryField struct {
+	name      string
+	omitEmpty bool
+	comma     bool
+	unix      time.Duration
+	layout    string
+}
+
+// encode returns the values of fv, or nil if it is left out.
+func (qf queryField) encode(fv reflect.Value) ([]string, error) {
+	for fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface {
+		if fv.IsNil() {
+			return nil, nil
+		}
+		if fv.Type().Implements(queryMarshalerType) || fv.Type().Implements(textMarshalerType) {
+			break
+		}
+		fv = fv.Elem()
+	}
+	if qf.omitEmpty && fv.IsZero() {
+		return nil, nil
+	}
+	if fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array {
+		if !fv.Type().Implements(queryMarshalerType) && !fv.Type().Implements(textMarshalerType) {
+			if qf.omitEmpty && fv.Len() == 0 {
+				return nil, nil
+			}
+			strs := make([]string, 0, fv.Len())
+			for i := 0; i < fv.Len(); i++ {
+				s, err := qf.scalar(fv.Index(i))
+				if err != nil {
+					return nil, err
+				}
+				strs = append(strs, s...)
+			}
+			return strs, nil
+		}
+	}
+	return qf.scalar(fv)
+}
+
+// scalar encodes a single value.
+func (qf queryField) scalar(v reflect.Value) ([]string, error) {
+	if v.Type().Implements(queryMarshalerType) {
+		return v.Interface().(QueryMarshaler).MarshalQuery()
+	}
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		switch {
+		case qf.unix != 0:
+			return []string{strconv.FormatInt(t.UnixNano()/int64(qf.unix), 10)}, nil
+		case qf.layout != "":
+			return []string{t.Format(qf.layout)}, nil
+		}
+		return []string{t.Format(time.RFC3339)}, nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return []string{string(text)}, err
+	}
+	if v.CanAddr() && reflect.PtrTo(v.Type()).Implements(queryMarshalerType) {
+		return v.Addr().Interface().(QueryMarshaler).MarshalQuery()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return []string{v.String()}, nil
+	case reflect.Bool:
+		return []string{strconv.FormatBool(v.Bool())}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return []string{strconv.FormatInt(v.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return []string{strconv.FormatUint(v.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return []string{strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())}, nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return qf.scalar(v.Elem())
+	}
+	return nil, fmt.Errorf("unsupported type %s", v.Type())
+}
@@ -0,0 +1,161 @@
+package apiclient
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// ErrQuotaExhausted is returned, wrapped, for requests beyond a Quota that does not wait.
+var ErrQuotaExhausted = errors.New("apiclient: quota exhausted")
+
+// QuotaWindow is the calendar period a Quota is granted for.
+type QuotaWindow int
+
+const (
+	QuotaHourly QuotaWindow = iota
+	QuotaDaily
+	QuotaMonthly
+)
+
+// Quota is a budget of requests per calendar window, such as 25,000 a day, on top of the per-second rate limit.
+// Every request sent counts against it, retries included.
+type Quota struct {
+	// Limit is the number of requests allowed per window.
+	Limit  int
+	Window QuotaWindow
+	// Location is the time zone windows are aligned in, UTC if nil.
+	Location *time.Location
+	// ResetOffset shifts the start of windows, e.g. 8*time.Hour for a daily quota that resets at 08:00, or
+	// 24*time.Hour for a monthly quota that resets on the 2nd.
+	ResetOffset time.Duration
+	// Wait makes requests beyond the quota wait for the next window, or until their context is done, instead of
+	// failing with ErrQuotaExhausted.
+	Wait bool
+}
+
+// QuotaUsage is the consumption of a Quota in its current window.
+type QuotaUsage struct {
+	Quota Quota
+	Used  int
+	// Reset is when the current window ends.
+	Reset time.Time
+}
+
+// WithQuota adds q to the quotas of the client. A request is sent only if every quota has room for it.
+func WithQuota(q Quota) ClientOption {
+	return func(c *Client) error {
+		if q.Limit <= 0 {
+			return fmt.Errorf("apiclient: invalid quota limit %d", q.Limit)
+		}
+		if q.Window < QuotaHourly || q.Window > QuotaMonthly {
+			return fmt.Errorf("apiclient: invalid quota window %d", q.Window)
+		}
+		if q.Location == nil {
+			q.Location = time.UTC
+		}
+		c.quotas = append(c.quotas, &quota{Quota: q})
+		return nil
+	}
+}
+
+// QuotaUsage returns the consumption of the client's quotas, in the order they were added.
+func (c *Client) QuotaUsage() []QuotaUsage {
+	now := c.clock.Now()
+	usage := make([]QuotaUsage, len(c.quotas))
+	c.quotaMu.Lock()
+	defer c.quotaMu.Unlock()
+	for i, q := range c.quotas {
+		q.roll(now)
+		usage[i] = QuotaUsage{Quota: q.Quota, Used: q.used, Reset: q.reset}
+	}
+	return usage
+}
+
+type quota struct {
+	Quota
+	used  int
+	reset time.Time
+}
+
+// roll starts a new window if the current one ended by now.
+func (q *quota) roll(now time.Time) {
+	if now.Before(q.reset) {
+		return
+	}
+	q.used = 0
+	start := q.windowStart(now)
+	switch local := start.In(q.Location).Add(-q.ResetOffset); q.Window {
+	case QuotaHourly:
+		q.reset = start.Add(time.Hour)
+	case QuotaDaily:
+		q.reset = local.AddDate(0, 0, 1).Add(q.ResetOffset)
+	default:
+		q.reset = local.AddDate(0, 1, 0).Add(q.ResetOffset)
+	}
+}
+
+// windowStart returns the start of the window containing t.
+func (q *quota) windowStart(t time.Time) time.Time {
+	local := t.In(q.Location).Add(-q.ResetOffset)
+	y, m, d := local.Date()
+	var start time.Time
+	switch q.Window {
+	case QuotaHourly:
+		start = local.Truncate(time.Hour)
+	case QuotaDaily:
+		start = time.Date(y, m, d, 0, 0, 0, 0, q.Location)
+	default:
+		start = time.Date(y, m, 1, 0, 0, 0, 0, q.Location)
+	}
+	return start.Add(q.ResetOffset)
+}
+
+// restoreQuotas resumes the usage of the client's quotas from states saved by a LimiterStore, for those whose window
+// is still the current one.
+func (c *Client) restoreQuotas(states []QuotaState) {
+	now := c.clock.Now()
+	c.quotaMu.Lock()
+	defer c.quotaMu.Unlock()
+	for i, state := range states {
+		if i == len(c.quotas) {
+			break
+		}
+		q := c.quotas[i]
+		if q.roll(now); q.reset.Equal(state.Reset) {
+			q.used = state.Used
+		}
+	}
+}
+
+// takeQuota counts a request against every quota, waiting for or failing on those that are exhausted.
+func (c *Client) takeQuota(ctx context.Context) error {
+	for {
+		now := c.clock.Now()
+		c.quotaMu.Lock()
+		var full *quota
+		for _, q := range c.quotas {
+			if q.roll(now); q.used >= q.Limit {
+				full = q
+				break
+			}
+		}
+		if full == nil {
+			for _, q := range c.quotas {
+				q.used++
+			}
+			c.quotaMu.Unlock()
+			return nil
+		}
+		reset := full.reset
+		c.quotaMu.Unlock()
+		if !full.Wait {
+			return fmt.Errorf("%w: %d requests per window, resets at %v", ErrQuotaExhausted, full.Limit, reset)
+		}
+		if err := c.scheduler.sleep(ctx, reset.Sub(now)); err != nil {
+			return err
+		}
+	}
+}
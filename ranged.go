@@ -0,0 +1,174 @@
+package apiclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// DefaultChunkSize is the size of the ranges GetBinaryRanges requests when none is given.
+const DefaultChunkSize = 8 << 20
+
+// ErrResourceChanged is returned by GetBinaryRanges when the resource changed between two chunks, so the data
+// downloaded so far cannot be completed.
+var ErrResourceChanged = errors.New("apiclient: resource changed during download")
+
+// ErrRangeMismatch is returned by GetBinaryRanges when the server answers with a range other than the one requested,
+// or ignores ranges while resuming.
+var ErrRangeMismatch = errors.New("apiclient: response does not match requested range")
+
+// DownloadState is the progress of a ranged download. It can be saved, and passed to GetBinaryRanges again to resume
+// an interrupted download.
+type DownloadState struct {
+	// Offset is the number of bytes downloaded so far.
+	Offset int64
+	// Size is the total size of the resource, known once the first chunk has been received.
+	Size int64
+	// ETag is the entity tag of the resource as of the first chunk, if the server sent one.
+	ETag string
+}
+
+// GetBinaryRanges downloads the resource of apiReq to w in ranges of chunkSize bytes (DefaultChunkSize if 0),
+// starting at state.Offset and updating state after every chunk. w must be positioned at state.Offset. Each chunk
+// is checked against the previous ones: a different ETag or total size fails the download with ErrResourceChanged,
+// and a range or Content-Length other than requested with ErrRangeMismatch. Call options such as WithTimeout apply
+// to every chunk. Responses are never served from or stored in the cache.
+func (c *Client) GetBinaryRanges(ctx context.Context, config *APIConfig, apiReq APIRequest, w io.Writer, state *DownloadState, chunkSize int64, opts ...RequestOption) error {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	for state.Size == 0 || state.Offset < state.Size {
+		done, err := c.getRange(ctx, config, apiReq, w, state, chunkSize, opts)
+		if err != nil || done {
+			return err
+		}
+	}
+	return nil
+}
+
+// getRange downloads the chunk starting at state.Offset and reports whether the download is complete.
+func (c *Client) getRange(ctx context.Context, config *APIConfig, apiReq APIRequest, w io.Writer, state *DownloadState, chunkSize int64, opts []RequestOption) (bool, error) {
+	o := c.newRequestOptions(opts)
+	ctx, cancel := o.context(ctx, config)
+	defer cancel()
+	ctx = c.withRequestID(withRawBody(ctx))
+
+	header := http.Header{}
+	header.Set("Range", fmt.Sprintf("bytes=%d-%d", state.Offset, state.Offset+chunkSize-1))
+	if state.ETag != "" && !strings.HasPrefix(state.ETag, "W/") {
+		// Weak validators are not allowed in If-Range; those are compared below instead.
+		header.Set("If-Range", state.ETag)
+	}
+	resp, err := c.send(ctx, "GET", config, apiReq, header, nil)
+	if err != nil {
+		return false, c.requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+	if o.meta != nil {
+		o.meta.fill(resp)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, end, total, err := parseContentRange(resp.Header.Get("Content-Range"))
+		switch {
+		case err != nil:
+			return false, c.requestError(ctx, err)
+		case start != state.Offset || end < start:
+			return false, c.requestError(ctx, fmt.Errorf("%w: got bytes %d-%d, want from %d", ErrRangeMismatch, start, end, state.Offset))
+		case resp.ContentLength >= 0 && resp.ContentLength != end-start+1:
+			return false, c.requestError(ctx, fmt.Errorf("%w: Content-Length %d for %d bytes", ErrRangeMismatch, resp.ContentLength, end-start+1))
+		}
+		if err := state.check(resp.Header.Get("ETag"), total); err != nil {
+			return false, c.requestError(ctx, err)
+		}
+		n, err := copyBuffer(w, c.throttled(ctx, c.downloadThrottle, resp.Body))
+		state.Offset += n
+		if err == nil && n != end-start+1 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return false, c.requestError(ctx, err)
+		}
+		if total < 0 && n < chunkSize {
+			// Without a known size, a short chunk is the last one.
+			state.Size = state.Offset
+			return true, nil
+		}
+		return total >= 0 && state.Offset >= total, nil
+
+	case http.StatusOK:
+		// The server ignored the range, or the resource changed and If-Range asked for all of it.
+		if state.Offset > 0 {
+			if state.ETag != "" {
+				return false, c.requestError(ctx, ErrResourceChanged)
+			}
+			return false, c.requestError(ctx, fmt.Errorf("%w: server does not support ranges", ErrRangeMismatch))
+		}
+		state.ETag = resp.Header.Get("ETag")
+		n, err := copyBuffer(w, c.throttled(ctx, c.downloadThrottle, resp.Body))
+		state.Offset += n
+		if err == nil && resp.ContentLength >= 0 && n != resp.ContentLength {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return false, c.requestError(ctx, err)
+		}
+		state.Size = n
+		return true, nil
+
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Either everything has been downloaded already, or the resource shrank.
+		if _, _, total, err := parseContentRange(resp.Header.Get("Content-Range")); err == nil && total == state.Offset {
+			state.Size = total
+			return true, nil
+		}
+		return false, c.requestError(ctx, ErrResourceChanged)
+	}
+	return false, c.requestError(ctx, &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status})
+}
+
+// check records the validators of a chunk, failing if they differ from those of previous chunks.
+func (s *DownloadState) check(etag string, total int64) error {
+	if (s.ETag != "" && etag != s.ETag) || (s.Size > 0 && total >= 0 && total != s.Size) {
+		return ErrResourceChanged
+	}
+	if s.ETag == "" && s.Offset == 0 {
+		s.ETag = etag
+	}
+	if total >= 0 {
+		s.Size = total
+	}
+	return nil
+}
+
+// parseContentRange parses a Content-Range header of the form "bytes first-last/total" or "bytes */total". Unknown
+// parts are returned as -1.
+func parseContentRange(v string) (first, last, total int64, err error) {
+	first, last, total = -1, -1, -1
+	spec, ok := strings.CutPrefix(v, "bytes ")
+	rng, size, ok2 := strings.Cut(spec, "/")
+	if !ok || !ok2 {
+		return first, last, total, fmt.Errorf("%w: malformed Content-Range %q", ErrRangeMismatch, v)
+	}
+	if size != "*" {
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return -1, -1, -1, fmt.Errorf("%w: malformed Content-Range %q", ErrRangeMismatch, v)
+		}
+	}
+	if rng != "*" {
+		a, b, ok := strings.Cut(rng, "-")
+		var err1, err2 error
+		first, err1 = strconv.ParseInt(a, 10, 64)
+		last, err2 = strconv.ParseInt(b, 10, 64)
+		if !ok || err1 != nil || err2 != nil {
+			return -1, -1, -1, fmt.Errorf("%w: malformed Content-Range %q", ErrRangeMismatch, v)
+		}
+	}
+	return first, last, total, nil
+}
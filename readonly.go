@@ -0,0 +1,42 @@
+package apiclient
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrReadOnly is returned for requests other than GET and HEAD while the client is in read-only mode.
+var ErrReadOnly = errors.New("apiclient: client is in read-only mode")
+
+// WithReadOnly starts the client in read-only mode, see SetReadOnly.
+func WithReadOnly() ClientOption {
+	return func(c *Client) error {
+		c.readOnly = 1
+		return nil
+	}
+}
+
+// SetReadOnly switches read-only mode on or off. While it is on, every request with a method other than GET or HEAD
+// fails with ErrReadOnly without being sent, e.g. during incident response or when auditing an integration.
+func (c *Client) SetReadOnly(readOnly bool) {
+	var v int32
+	if readOnly {
+		v = 1
+	}
+	c.reconfigure(func() {
+		atomic.StoreInt32(&c.readOnly, v)
+	})
+}
+
+func (c *Client) isReadOnly() bool {
+	return atomic.LoadInt32(&c.readOnly) == 1
+}
+
+// checkReadOnly rejects requests with method while the client is read-only.
+func (c *Client) checkReadOnly(method string) error {
+	if c.isReadOnly() && method != http.MethodGet && method != http.MethodHead {
+		return ErrReadOnly
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package apiclient
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// WithRegions configures the client to send requests to the base URL of a region, such as https://api.eu.example.com
+// for "eu", in place of each APIConfig's Host and the client's base URLs. Calls go to the home region unless they
+// select another with WithRegion or ContextWithRegion. When the selected region is down, as told by policy like the
+// endpoints of WithBaseURLs, requests fail over to the other regions in the order of their names; WithPinnedRegion
+// selects a region that must not be failed over from, for data that has to stay in it.
+func WithRegions(regions map[string]string, home string, policy FailoverPolicy) ClientOption {
+	return func(c *Client) error {
+		if _, ok := regions[home]; !ok {
+			return fmt.Errorf("apiclient: home region %q has no base URL", home)
+		}
+		names := make([]string, 0, len(regions))
+		for name := range regions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		bases := make([]string, len(names))
+		for i, name := range names {
+			bases[i] = strings.TrimSuffix(regions[name], "/")
+		}
+		rs := &regionSet{set: newEndpointSet(PrimaryFirst, policy), home: home, byName: map[string]*endpoint{}}
+		rs.set.replace(bases)
+		for i, ep := range rs.set.snapshot() {
+			rs.byName[names[i]] = ep
+		}
+		c.regions = rs
+		return nil
+	}
+}
+
+// regionKey carries the region selected for requests made with a context.
+type regionKey struct{}
+
+type regionSelection struct {
+	name   string
+	pinned bool
+}
+
+// ContextWithRegion returns a copy of ctx whose requests go to region, failing over to others when it is down.
+func ContextWithRegion(ctx context.Context, region string) context.Context {
+	return context.WithValue(ctx, regionKey{}, regionSelection{name: region})
+}
+
+// WithRegion sends a call to region, failing over to others when it is down, see WithRegions.
+func WithRegion(region string) RequestOption {
+	return func(o *requestOptions) {
+		o.region = &regionSelection{name: region}
+	}
+}
+
+// WithPinnedRegion sends a call to region only, never failing over to another.
+func WithPinnedRegion(region string) RequestOption {
+	return func(o *requestOptions) {
+		o.region = &regionSelection{name: region, pinned: true}
+	}
+}
+
+// regionFromContext returns the region selected for requests made with ctx, with an empty name for the home region.
+func regionFromContext(ctx context.Context) regionSelection {
+	sel, _ := ctx.Value(regionKey{}).(regionSelection)
+	return sel
+}
+
+// regionSet tracks the health of a client's regions.
+type regionSet struct {
+	set    *endpointSet
+	home   string
+	byName map[string]*endpoint
+}
+
+// lookup returns the endpoint of the region of sel.
+func (rs *regionSet) lookup(sel regionSelection) (*endpoint, error) {
+	name := sel.name
+	if name == "" {
+		name = rs.home
+	}
+	ep, ok := rs.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("apiclient: unknown region %q", name)
+	}
+	return ep, nil
+}
+
+// do calls try with the base URL of the region selected by ctx, and with those of the other regions if it fails and
+// failover is allowed.
+func (rs *regionSet) do(ctx context.Context, clk Clock, failover bool, try func(base string) (*http.Response, error)) (*http.Response, error) {
+	sel := regionFromContext(ctx)
+	ep, err := rs.lookup(sel)
+	if err != nil {
+		return nil, err
+	}
+	if sel.pinned {
+		resp, err := try(ep.base)
+		if ctx.Err() == nil && (err == nil || isTransportError(err)) {
+			rs.set.report(ep, err == nil && resp.StatusCode < 500, clk.Now())
+		}
+		return resp, err
+	}
+	return rs.set.do(ctx, clk, ep, failover, try)
+}
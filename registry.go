@@ -0,0 +1,116 @@
+package apiclient
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/net/context"
+)
+
+// Endpoint is an API endpoint registered on a client under a name, see RegisterEndpoint.
+type Endpoint struct {
+	Name     string
+	client   *Client
+	config   APIConfig
+	defaults []RequestOption
+}
+
+// RegisterEndpoint describes an endpoint of the API once, so it can be called by name from anywhere the client is
+// shared instead of repeating its APIConfig:
+//
+//	c.RegisterEndpoint("geocode", APIConfig{Path: "/maps/api/geocode/json"}, WithPriority(PriorityHigh))
+//	err := c.Endpoint("geocode").GetJSON(ctx, req, &resp)
+//
+// defaults are applied to every call of the endpoint, before the options of the call itself. Registering a name
+// twice is an error.
+func (c *Client) RegisterEndpoint(name string, config APIConfig, defaults ...RequestOption) error {
+	c.registryMu.Lock()
+	defer c.registryMu.Unlock()
+	if _, ok := c.registry[name]; ok {
+		return fmt.Errorf("apiclient: endpoint %q already registered", name)
+	}
+	if c.registry == nil {
+		c.registry = map[string]*Endpoint{}
+	}
+	c.registry[name] = &Endpoint{Name: name, client: c, config: config, defaults: defaults}
+	return nil
+}
+
+// Endpoint returns the endpoint registered under name. Calls of an endpoint that was never registered fail with an
+// error naming it.
+func (c *Client) Endpoint(name string) *Endpoint {
+	c.registryMu.Lock()
+	defer c.registryMu.Unlock()
+	if e, ok := c.registry[name]; ok {
+		if e.client != c {
+			// Registered on a client c shares its state with: call it with c's defaults.
+			derived := *e
+			derived.client = c
+			return &derived
+		}
+		return e
+	}
+	return &Endpoint{Name: name}
+}
+
+// Endpoints returns the names of the registered endpoints.
+func (c *Client) Endpoints() []string {
+	c.registryMu.Lock()
+	defer c.registryMu.Unlock()
+	names := make([]string, 0, len(c.registry))
+	for name := range c.registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// options returns the endpoint's default options followed by opts.
+func (e *Endpoint) options(opts []RequestOption) []RequestOption {
+	return append(append([]RequestOption(nil), e.defaults...), opts...)
+}
+
+// check returns an error if the endpoint was never registered.
+func (e *Endpoint) check() error {
+	if e.client == nil {
+		return fmt.Errorf("apiclient: endpoint %q not registered", e.Name)
+	}
+	return nil
+}
+
+// GetJSON requests the endpoint with apiReq and decodes its JSON response into resp, see Client.GetJSON.
+func (e *Endpoint) GetJSON(ctx context.Context, apiReq APIRequest, resp interface{}, opts ...RequestOption) error {
+	if err := e.check(); err != nil {
+		return err
+	}
+	config := e.config
+	return e.withFallback(ctx, apiReq, resp, e.options(opts), func(opts []RequestOption) error {
+		return e.client.GetJSON(ctx, &config, apiReq, resp, opts...)
+	})
+}
+
+// GetXML requests the endpoint with apiReq and decodes its XML response into resp, see Client.GetXML.
+func (e *Endpoint) GetXML(ctx context.Context, apiReq APIRequest, resp interface{}, opts ...RequestOption) error {
+	if err := e.check(); err != nil {
+		return err
+	}
+	config := e.config
+	return e.withFallback(ctx, apiReq, resp, e.options(opts), func(opts []RequestOption) error {
+		return e.client.GetXML(ctx, &config, apiReq, resp, opts...)
+	})
+}
+
+// GetBinary requests the endpoint with apiReq and returns its response data, see Client.GetBinary.
+func (e *Endpoint) GetBinary(ctx context.Context, apiReq APIRequest, opts ...RequestOption) (BinaryResponse, error) {
+	if err := e.check(); err != nil {
+		return BinaryResponse{}, err
+	}
+	config := e.config
+	var resp BinaryResponse
+	err := e.withFallback(ctx, apiReq, &resp, e.options(opts), func(opts []RequestOption) error {
+		var err error
+		resp, err = e.client.GetBinary(ctx, &config, apiReq, opts...)
+		return err
+	})
+	return resp, err
+}
@@ -0,0 +1,198 @@
+package apiclient
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// RequestOption is the type of per-call options accepted by GetJSON and GetBinary.
+type RequestOption func(*requestOptions)
+
+// requestOptions holds the settings of a single call.
+type requestOptions struct {
+	decoder func(*http.Response, interface{}) error
+	timeout time.Duration
+	meta    *ResponseMeta
+	// priority is set if the call overrides the priority of its context.
+	priority   *Priority
+	accept     string
+	header     http.Header
+	pathParams map[string]string
+	// idempotencyKey is the key chosen by the call, see WithIdempotencyKey.
+	idempotencyKey string
+	persistedQuery bool
+	schema         *Schema
+	// retryPolicy replaces the client's retry policy if retrySet, see WithRetry.
+	retryPolicy RetryPolicy
+	retrySet    bool
+	cacheBypass bool
+	baseURL     string
+	region      *regionSelection
+	bulkhead    string
+	arrayPath   []string
+	// csvDelimiter separates the fields of CSV responses, if not a comma.
+	csvDelimiter rune
+	// failOnOutage is set for calls of endpoints with a fallback, see WithFallback.
+	failOnOutage bool
+	credentials  []Credential
+	// defaultTimeout is the client's timeout, see SetTimeout.
+	defaultTimeout time.Duration
+}
+
+func (c *Client) newRequestOptions(opts []RequestOption) *requestOptions {
+	c.callMu.RLock()
+	o := &requestOptions{defaultTimeout: c.defaultTimeout}
+	c.callMu.RUnlock()
+	for _, opt := range c.defaults {
+		opt(o)
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithDecoder replaces the JSON decoding of a single call with decode, for one-off endpoints with unusual formats
+// (JSONP wrappers, JSON embedded in strings, prefixed payloads). decode must not close the response body.
+func WithDecoder(decode func(resp *http.Response, v interface{}) error) RequestOption {
+	return func(o *requestOptions) {
+		o.decoder = decode
+	}
+}
+
+// WithTimeout bounds a single call to d, overriding the APIConfig's Timeout. Unlike http.Client's Timeout it applies
+// only to this call; for GetBinary it also covers reading the returned data.
+func WithTimeout(d time.Duration) RequestOption {
+	return func(o *requestOptions) {
+		o.timeout = d
+	}
+}
+
+// SetTimeout bounds every call of a live client to d, from then on, unless its APIConfig or WithTimeout sets a
+// timeout of its own; 0 removes the bound. Unlike the Timeout of the HTTP client, it can be changed while requests
+// are made.
+func (c *Client) SetTimeout(d time.Duration) {
+	c.reconfigure(func() {
+		c.callMu.Lock()
+		c.defaultTimeout = d
+		c.callMu.Unlock()
+	})
+}
+
+// WithPriority makes a call wait for the rate limiter with priority p, see ContextWithPriority.
+func WithPriority(p Priority) RequestOption {
+	return func(o *requestOptions) {
+		o.priority = &p
+	}
+}
+
+type acceptKey struct{}
+
+// withAccept returns a copy of ctx whose requests ask for mediaType in their Accept header.
+func withAccept(ctx context.Context, mediaType string) context.Context {
+	return context.WithValue(ctx, acceptKey{}, mediaType)
+}
+
+// acceptFromContext returns the media type requests made with ctx ask for, or "" to leave it to the server.
+func acceptFromContext(ctx context.Context) string {
+	accept, _ := ctx.Value(acceptKey{}).(string)
+	return accept
+}
+
+// defaultAccept returns a copy of ctx asking for mediaType, unless the call overrides it with WithAccept.
+func defaultAccept(ctx context.Context, mediaType string) context.Context {
+	if acceptFromContext(ctx) != "" {
+		return ctx
+	}
+	return withAccept(ctx, mediaType)
+}
+
+// WithAccept overrides the Accept header of a single call, which otherwise lists the formats the call can decode.
+// The response is still decoded according to its Content-Type.
+func WithAccept(mediaTypes ...string) RequestOption {
+	return func(o *requestOptions) {
+		o.accept = strings.Join(mediaTypes, ", ")
+	}
+}
+
+// WithHeader adds a header to the request of a single call. Headers set by the client itself, such as credentials,
+// take precedence.
+func WithHeader(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		if o.header == nil {
+			o.header = http.Header{}
+		}
+		o.header.Add(key, value)
+	}
+}
+
+type headerKey struct{}
+
+// headerFromContext returns the headers added to requests made with ctx.
+func headerFromContext(ctx context.Context) http.Header {
+	h, _ := ctx.Value(headerKey{}).(http.Header)
+	return h
+}
+
+// context returns ctx carrying the call's settings, bounded by its timeout if it has one.
+func (o *requestOptions) context(ctx context.Context, config *APIConfig) (context.Context, context.CancelFunc) {
+	ctx = o.values(ctx)
+	d := o.defaultTimeout
+	if config.Timeout > 0 {
+		d = config.Timeout
+	}
+	if o.timeout > 0 {
+		d = o.timeout
+	}
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// values returns ctx carrying the call's settings other than its timeout.
+func (o *requestOptions) values(ctx context.Context) context.Context {
+	if o.priority != nil {
+		ctx = ContextWithPriority(ctx, *o.priority)
+	}
+	if o.accept != "" {
+		ctx = withAccept(ctx, o.accept)
+	}
+	if o.header != nil {
+		ctx = context.WithValue(ctx, headerKey{}, o.header)
+	}
+	if o.pathParams != nil {
+		ctx = context.WithValue(ctx, pathParamsKey{}, o.pathParams)
+	}
+	if o.meta != nil {
+		ctx = withMeta(ctx, o.meta)
+	}
+	if o.idempotencyKey != "" {
+		ctx = context.WithValue(ctx, idempotencyKeyKey{}, o.idempotencyKey)
+	}
+	if o.retrySet {
+		ctx = context.WithValue(ctx, retryPolicyKey{}, retryOverride{o.retryPolicy})
+	}
+	if o.baseURL != "" {
+		ctx = context.WithValue(ctx, baseURLKey{}, o.baseURL)
+	}
+	if o.bulkhead != "" {
+		ctx = ContextWithBulkhead(ctx, o.bulkhead)
+	}
+	if o.region != nil {
+		ctx = context.WithValue(ctx, regionKey{}, *o.region)
+	}
+	if len(o.credentials) > 0 {
+		ctx = ContextWithCredentials(ctx, o.credentials...)
+	}
+	if o.cacheBypass {
+		ctx = context.WithValue(ctx, cacheBypassKey{}, true)
+	}
+	if o.failOnOutage {
+		ctx = context.WithValue(ctx, failOnOutageKey{}, true)
+	}
+	return ctx
+}
@@ -0,0 +1,86 @@
+package apiclient
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"golang.org/x/net/context"
+)
+
+// DefaultRequestIDHeader is the header WithRequestID uses when none is given.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// WithRequestID configures the client to tag every request with a unique ID, sent in header (X-Request-ID if
+// empty). The ID is attached to errors as a *RequestError and can be read by hooks with RequestIDFromContext.
+// An ID already present on the context, see ContextWithRequestID, is propagated instead of generating a new one.
+func WithRequestID(header string) ClientOption {
+	return func(c *Client) error {
+		if header == "" {
+			header = DefaultRequestIDHeader
+		}
+		c.requestIDHeader = header
+		return nil
+	}
+}
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying the request ID id.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, or "" if there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestError is returned for a failed request when request IDs are enabled.
+type RequestError struct {
+	RequestID string
+	Err       error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("request %s: %v", e.RequestID, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// withRequestID makes sure ctx carries a request ID when request IDs are enabled.
+func (c *Client) withRequestID(ctx context.Context) context.Context {
+	ctx = c.withCurlCapture(ctx)
+	if c.requestIDHeader == "" || RequestIDFromContext(ctx) != "" {
+		return ctx
+	}
+	return ContextWithRequestID(ctx, newUUID())
+}
+
+// requestError attaches the request ID carried by ctx to err.
+func (c *Client) requestError(ctx context.Context, err error) error {
+	if err != nil && c.curlOnError {
+		err = curlError(ctx, err)
+	}
+	if err == nil || c.requestIDHeader == "" {
+		return err
+	}
+	if id := RequestIDFromContext(ctx); id != "" {
+		return &RequestError{RequestID: id, Err: err}
+	}
+	return err
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,160 @@
+package apiclient
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// retryBaseDelay is the delay before the first retry of a call, doubled for every further one.
+	retryBaseDelay = 250 * time.Millisecond
+	// retryMaxDelay bounds the delay between retries.
+	retryMaxDelay = 30 * time.Second
+)
+
+// RetryPolicy decides which failed requests are retried. ShouldRetry is given the outcome of attempt (1 for the
+// first request of a call): a response, or the error of a request that got none. A policy that needs to look at the
+// body of resp, for APIs that report transient failures with a 200 and an error code, can do so with PeekBody.
+type RetryPolicy interface {
+	ShouldRetry(resp *http.Response, err error, attempt int) bool
+}
+
+// RetryDelayer is implemented by retry policies that choose how long to wait before retrying attempt. Without it,
+// the client waits as long as a Retry-After header asks, or else for an exponentially growing, jittered delay.
+type RetryDelayer interface {
+	RetryDelay(resp *http.Response, attempt int) time.Duration
+}
+
+// RetryPolicyFunc adapts a function to RetryPolicy.
+type RetryPolicyFunc func(resp *http.Response, err error, attempt int) bool
+
+// ShouldRetry calls f.
+func (f RetryPolicyFunc) ShouldRetry(resp *http.Response, err error, attempt int) bool {
+	return f(resp, err, attempt)
+}
+
+// RetryTransient returns a policy retrying network failures and temporary error responses (408, 429 and 5xx other
+// than 501) until maxAttempts requests have been sent.
+func RetryTransient(maxAttempts int) RetryPolicy {
+	return RetryPolicyFunc(func(resp *http.Response, err error, attempt int) bool {
+		if attempt >= maxAttempts {
+			return false
+		}
+		if err != nil {
+			var ne net.Error
+			return errors.As(err, &ne)
+		}
+		return IsTemporary(&HTTPError{StatusCode: resp.StatusCode})
+	})
+}
+
+// WithRetryPolicy configures the client to retry the requests that policy selects. Requests whose body cannot be
+// replayed, such as streamed multipart uploads, are never retried, nor are those whose retry could not be sent before
+// the call's deadline. See WithRetryBudget to bound the retries, and WithRetry to override the policy for a single
+// call.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *Client) error {
+		c.retryPolicy = policy
+		return nil
+	}
+}
+
+// SetRetryPolicy replaces the retry policy of a live client with policy; nil stops retrying. Calls already being
+// retried keep the policy they started with.
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.reconfigure(func() {
+		c.callMu.Lock()
+		c.retryPolicy = policy
+		c.callMu.Unlock()
+	})
+}
+
+// WithRetry retries a single call as policy asks, in place of the client's retry policy; nil disables retries for
+// the call.
+func WithRetry(policy RetryPolicy) RequestOption {
+	return func(o *requestOptions) {
+		o.retryPolicy, o.retrySet = policy, true
+	}
+}
+
+type retryPolicyKey struct{}
+
+// retryOverride carries the retry policy of a call in its context, even a nil one.
+type retryOverride struct {
+	policy RetryPolicy
+}
+
+// currentRetryPolicy returns the retry policy of requests made with ctx: that of the call if it set one, otherwise
+// the client's.
+func (c *Client) currentRetryPolicy(ctx context.Context) RetryPolicy {
+	if o, ok := ctx.Value(retryPolicyKey{}).(retryOverride); ok {
+		return o.policy
+	}
+	c.callMu.RLock()
+	defer c.callMu.RUnlock()
+	return c.retryPolicy
+}
+
+// PeekBody returns up to n bytes from the start of the body of resp, leaving the whole body to be read again.
+func PeekBody(resp *http.Response, n int64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(resp.Body, n))
+	resp.Body = readCloser{io.MultiReader(bytes.NewReader(b), resp.Body), resp.Body}
+	return b, err
+}
+
+// retry sends a request with send, again for as long as policy asks.
+func (c *Client) retry(ctx context.Context, policy RetryPolicy, send func() (*http.Response, error)) (*http.Response, error) {
+	if c.retryBudget != nil {
+		c.retryBudget.call(c.clock.Now())
+	}
+	for attempt := 1; ; attempt++ {
+		resp, err := send()
+		if ctx.Err() != nil || !policy.ShouldRetry(resp, err, attempt) {
+			return resp, err
+		}
+		delay := c.retryDelay(policy, resp, attempt)
+		if !c.retryFits(ctx, resp, delay) {
+			c.log("retry past deadline", map[string]interface{}{"attempt": attempt, "delay": delay})
+			return resp, err
+		}
+		if c.retryBudget != nil && !c.retryBudget.withdraw(c.clock.Now()) {
+			c.log("retry shed", map[string]interface{}{"attempt": attempt})
+			return resp, err
+		}
+		atomic.AddInt64(&c.stats.retries, 1)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if err := c.scheduler.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryDelay returns how long to wait before retrying attempt under policy.
+func (c *Client) retryDelay(policy RetryPolicy, resp *http.Response, attempt int) time.Duration {
+	if d, ok := policy.(RetryDelayer); ok {
+		return d.RetryDelay(resp, attempt)
+	}
+	if resp != nil {
+		if d := c.retryAfter(resp.Header); d >= 0 {
+			return d
+		}
+	}
+	d := retryMaxDelay
+	if attempt < 16 {
+		if d = retryBaseDelay << (attempt - 1); d > retryMaxDelay {
+			d = retryMaxDelay
+		}
+	}
+	// Wait between half and all of d, so that clients failing together do not retry together.
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
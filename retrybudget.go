@@ -0,0 +1,91 @@
+package apiclient
+
+import (
+	"sync"
+	"time"
+)
+
+// budgetBuckets is the number of slices the window of a retry budget is divided into.
+const budgetBuckets = 10
+
+// RetryBudget bounds the retries of a client configured WithRetryPolicy, so that when the upstream fails broadly
+// retries are shed instead of multiplying its load.
+type RetryBudget struct {
+	// Ratio is the share of the calls made within Window that may be retried. Defaults to 0.2.
+	Ratio float64
+	// MinRetries is the number of retries allowed within Window regardless of Ratio, so that a client making few
+	// calls can still retry some. Defaults to 10.
+	MinRetries int
+	// Window is how far back calls and retries are counted. Defaults to 10 seconds.
+	Window time.Duration
+}
+
+// WithRetryBudget configures the client to retry failed calls only while its retries within budget's window stay
+// below MinRetries plus Ratio times its calls. The last failure of a call whose retry is shed is returned as is, and
+// a "retry shed" event is logged.
+func WithRetryBudget(budget RetryBudget) ClientOption {
+	return func(c *Client) error {
+		if budget.Ratio <= 0 {
+			budget.Ratio = 0.2
+		}
+		if budget.MinRetries <= 0 {
+			budget.MinRetries = 10
+		}
+		if budget.Window <= 0 {
+			budget.Window = 10 * time.Second
+		}
+		c.retryBudget = &retryBudget{budget: budget, slot: budget.Window / budgetBuckets}
+		return nil
+	}
+}
+
+// retryBudget counts a client's recent calls and retries in the slices of a sliding window.
+type retryBudget struct {
+	budget RetryBudget
+	slot   time.Duration
+
+	mu      sync.Mutex
+	buckets [budgetBuckets]budgetBucket
+}
+
+type budgetBucket struct {
+	slot    int64
+	calls   int
+	retries int
+}
+
+// bucket returns the bucket counting the current slice of the window, emptied if it last counted an earlier one.
+func (rb *retryBudget) bucket(now time.Time) *budgetBucket {
+	slot := now.UnixNano() / int64(rb.slot)
+	b := &rb.buckets[slot%budgetBuckets]
+	if b.slot != slot {
+		*b = budgetBucket{slot: slot}
+	}
+	return b
+}
+
+// call records a call.
+func (rb *retryBudget) call(now time.Time) {
+	rb.mu.Lock()
+	rb.bucket(now).calls++
+	rb.mu.Unlock()
+}
+
+// withdraw records a retry and reports true if the budget allows it.
+func (rb *retryBudget) withdraw(now time.Time) bool {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	current := rb.bucket(now)
+	var calls, retries int
+	for i := range rb.buckets {
+		if b := &rb.buckets[i]; b.slot > current.slot-budgetBuckets {
+			calls += b.calls
+			retries += b.retries
+		}
+	}
+	if float64(retries) >= float64(rb.budget.MinRetries)+rb.budget.Ratio*float64(calls) {
+		return false
+	}
+	current.retries++
+	return true
+}
@@ -0,0 +1,195 @@
+package apiclient
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Clock is the time source behind the scheduler. Swapping it out gives a single point of control over every
+// piece of delayed work the client performs: rate limiter refills, retry and poll delays, and every other sleep are
+// run off its ticks, so a fake clock that is advanced by hand makes them deterministic.
+type Clock interface {
+	Now() time.Time
+	// Tick returns a channel delivering ticks every d, and a func that stops it. Like a time.Ticker, it may drop
+	// ticks for a slow receiver.
+	Tick(d time.Duration) (<-chan time.Time, func())
+}
+
+// WithClock configures the client to take the time from clock instead of package time, for tests. See
+// apiclienttest.ManualClock.
+func WithClock(clock Clock) ClientOption {
+	return func(c *Client) error {
+		c.clock = clock
+		return nil
+	}
+}
+
+// systemClock is the clock backed by package time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) Tick(d time.Duration) (<-chan time.Time, func()) {
+	t := time.NewTicker(d)
+	return t.C, t.Stop
+}
+
+const (
+	// schedulerResolution is the granularity of the timer wheel; delays are rounded up to a multiple of it.
+	schedulerResolution = 10 * time.Millisecond
+	// schedulerSlots is the number of buckets in the timer wheel.
+	schedulerSlots = 256
+)
+
+// scheduler is a hashed timer wheel that runs all of a client's delayed work (limiter refills, retry sleeps etc.)
+// from a single goroutine, instead of spawning a timer per task. Tasks run on the wheel goroutine and must not block.
+type scheduler struct {
+	clock Clock
+	tick  time.Duration
+
+	mu    sync.Mutex
+	slots [schedulerSlots][]*task
+	pos   int
+	last  time.Time
+
+	stopTicker func()
+	done       chan struct{}
+	stopOnce   sync.Once
+}
+
+// task is a unit of work scheduled on the wheel.
+type task struct {
+	fn      func()
+	period  time.Duration
+	rounds  int
+	stopped bool
+}
+
+func newScheduler(c Clock, tick time.Duration) *scheduler {
+	ticks, stop := c.Tick(tick)
+	s := &scheduler{
+		clock:      c,
+		tick:       tick,
+		last:       c.Now(),
+		stopTicker: stop,
+		done:       make(chan struct{}),
+	}
+	go s.run(ticks)
+	return s
+}
+
+func (s *scheduler) run(ticks <-chan time.Time) {
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticks:
+			s.advance(s.clock.Now())
+		}
+	}
+}
+
+// advance moves the wheel forward to now, running every task that came due.
+func (s *scheduler) advance(now time.Time) {
+	var due []*task
+	s.mu.Lock()
+	for !s.last.Add(s.tick).After(now) {
+		s.last = s.last.Add(s.tick)
+		s.pos = (s.pos + 1) % schedulerSlots
+		slot := s.slots[s.pos]
+		kept := slot[:0]
+		for _, t := range slot {
+			switch {
+			case t.stopped:
+			case t.rounds > 0:
+				t.rounds--
+				kept = append(kept, t)
+			default:
+				due = append(due, t)
+			}
+		}
+		for i := len(kept); i < len(slot); i++ {
+			slot[i] = nil
+		}
+		s.slots[s.pos] = kept
+	}
+	s.mu.Unlock()
+
+	for _, t := range due {
+		t.fn()
+		if t.period > 0 {
+			s.mu.Lock()
+			if !t.stopped {
+				s.insert(t, s.last, t.period)
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// insert places t on the wheel to fire after d has elapsed since now. s.mu must be held.
+func (s *scheduler) insert(t *task, now time.Time, d time.Duration) {
+	// Count from the current wheel position, which may be part way through a tick, so tasks never fire early.
+	d += now.Sub(s.last)
+	ticks := int((d + s.tick - 1) / s.tick)
+	if ticks < 1 {
+		ticks = 1
+	}
+	t.rounds = (ticks - 1) / schedulerSlots
+	slot := (s.pos + ticks) % schedulerSlots
+	s.slots[slot] = append(s.slots[slot], t)
+}
+
+// after runs fn once, after d has elapsed.
+func (s *scheduler) after(d time.Duration, fn func()) *task {
+	return s.schedule(d, 0, fn)
+}
+
+// every runs fn after first has elapsed, then repeatedly every period.
+func (s *scheduler) every(first, period time.Duration, fn func()) *task {
+	return s.schedule(first, period, fn)
+}
+
+func (s *scheduler) schedule(d, period time.Duration, fn func()) *task {
+	t := &task{fn: fn, period: period}
+	s.mu.Lock()
+	s.insert(t, s.clock.Now(), d)
+	s.mu.Unlock()
+	return t
+}
+
+// cancel prevents t from running again.
+func (s *scheduler) cancel(t *task) {
+	s.mu.Lock()
+	t.stopped = true
+	s.mu.Unlock()
+}
+
+// sleep blocks for d, or until ctx is done in which case ctx.Err() is returned, or the scheduler is stopped in which
+// case ErrShutdown is.
+func (s *scheduler) sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	wake := make(chan struct{})
+	t := s.after(d, func() { close(wake) })
+	select {
+	case <-wake:
+		return nil
+	case <-ctx.Done():
+		s.cancel(t)
+		return ctx.Err()
+	case <-s.done:
+		return ErrShutdown
+	}
+}
+
+// stop shuts down the wheel goroutine. Pending tasks never run.
+func (s *scheduler) stop() {
+	s.stopOnce.Do(func() {
+		s.stopTicker()
+		close(s.done)
+	})
+}
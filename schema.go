@@ -0,0 +1,459 @@
+package apiclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Schema is a JSON Schema that responses can be validated against, see WithSchema. It supports the keywords that
+// describe the shape of data: type, properties, required, additionalProperties, items, enum, const, the numeric,
+// length and size bounds, pattern, allOf, anyOf, oneOf, not, $ref within the document, and OpenAPI's nullable.
+type Schema struct {
+	root interface{}
+	node interface{}
+
+	mu       sync.Mutex
+	patterns map[string]*regexp.Regexp
+}
+
+// CompileSchema parses the JSON Schema document data.
+func CompileSchema(data []byte) (*Schema, error) {
+	var root interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("apiclient: invalid schema: %w", err)
+	}
+	return &Schema{root: root, node: root}, nil
+}
+
+// OpenAPISchema returns the schema of the component named component in the OpenAPI (or Swagger 2) document doc. The
+// references in it are resolved against doc.
+func OpenAPISchema(doc []byte, component string) (*Schema, error) {
+	s, err := CompileSchema(doc)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range []string{"#/components/schemas/", "#/definitions/"} {
+		if node, ok := s.resolve(ref + escapePointer(component)); ok {
+			s.node = node
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("apiclient: schema %q not found in OpenAPI document", component)
+}
+
+// SchemaViolation is a way in which a value does not match a schema.
+type SchemaViolation struct {
+	// Path is the JSON Pointer to the offending value, such as "/results/0/lat", or "" for the value itself.
+	Path    string
+	Message string
+}
+
+// ValidationError reports a value that does not match its schema.
+type ValidationError struct {
+	Violations []SchemaViolation
+}
+
+func (e *ValidationError) Error() string {
+	v := e.Violations[0]
+	msg := fmt.Sprintf("apiclient: response does not match schema: %s", v.Message)
+	if v.Path != "" {
+		msg = fmt.Sprintf("apiclient: response does not match schema at %s: %s", v.Path, v.Message)
+	}
+	if len(e.Violations) > 1 {
+		msg += fmt.Sprintf(" (and %d more)", len(e.Violations)-1)
+	}
+	return msg
+}
+
+// WithSchema makes GetJSON check a JSON response against s before decoding it, failing with a *ValidationError
+// that lists every violation, so that drift in the upstream's contract is caught where the response enters. Responses
+// in the formats of other codecs are not validated.
+func WithSchema(s *Schema) RequestOption {
+	return func(o *requestOptions) {
+		o.schema = s
+	}
+}
+
+// ValidateJSON checks the JSON document data against s, returning a *ValidationError if it does not match.
+func (s *Schema) ValidateJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	var violations []SchemaViolation
+	s.validate(s.node, v, "", &violations)
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+// validate appends the ways in which v does not match node to violations.
+func (s *Schema) validate(node, v interface{}, path string, violations *[]SchemaViolation) {
+	fail := func(format string, args ...interface{}) {
+		*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	switch node := node.(type) {
+	case bool:
+		if !node {
+			fail("no value is allowed")
+		}
+		return
+	case map[string]interface{}:
+		if ref, ok := node["$ref"].(string); ok {
+			target, ok := s.resolve(ref)
+			if !ok {
+				fail("unresolvable reference %q", ref)
+				return
+			}
+			s.validate(target, v, path, violations)
+			return
+		}
+		if v == nil && node["nullable"] == true {
+			return
+		}
+		if t, ok := node["type"]; ok && !matchesType(t, v) {
+			fail("expected %s, got %s", typeNames(t), jsonType(v))
+			return
+		}
+		if enum, ok := node["enum"].([]interface{}); ok && !containsValue(enum, v) {
+			fail("value is not one of the allowed values")
+		}
+		if c, ok := node["const"]; ok && !equalJSON(c, v) {
+			fail("value is not the required constant")
+		}
+		s.validateCombinators(node, v, path, violations)
+		switch v := v.(type) {
+		case map[string]interface{}:
+			s.validateObject(node, v, path, violations)
+		case []interface{}:
+			s.validateArray(node, v, path, violations)
+		case string:
+			s.validateString(node, v, fail)
+		case json.Number:
+			validateNumber(node, v, fail)
+		}
+	}
+}
+
+func (s *Schema) validateCombinators(node map[string]interface{}, v interface{}, path string, violations *[]SchemaViolation) {
+	if all, ok := node["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			s.validate(sub, v, path, violations)
+		}
+	}
+	matches := func(sub interface{}) bool {
+		var vs []SchemaViolation
+		s.validate(sub, v, path, &vs)
+		return len(vs) == 0
+	}
+	fail := func(message string) {
+		*violations = append(*violations, SchemaViolation{Path: path, Message: message})
+	}
+	if anyOf, ok := node["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if matches(sub) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("value matches none of anyOf")
+		}
+	}
+	if one, ok := node["oneOf"].([]interface{}); ok {
+		n := 0
+		for _, sub := range one {
+			if matches(sub) {
+				n++
+			}
+		}
+		if n != 1 {
+			fail(fmt.Sprintf("value matches %d of oneOf instead of exactly one", n))
+		}
+	}
+	if not, ok := node["not"]; ok && matches(not) {
+		fail("value matches not")
+	}
+}
+
+func (s *Schema) validateObject(node, obj map[string]interface{}, path string, violations *[]SchemaViolation) {
+	if required, ok := node["required"].([]interface{}); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, present := obj[name]; !present {
+					*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf("missing required property %q", name)})
+				}
+			}
+		}
+	}
+	props, _ := node["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		sub := path + "/" + escapePointer(k)
+		if prop, ok := props[k]; ok {
+			s.validate(prop, obj[k], sub, violations)
+			continue
+		}
+		switch additional := node["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf("unexpected property %q", k)})
+			}
+		case map[string]interface{}:
+			s.validate(additional, obj[k], sub, violations)
+		}
+	}
+	if n, ok := bound(node, "minProperties"); ok && float64(len(obj)) < n {
+		*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf("has %d properties, fewer than %v", len(obj), n)})
+	}
+	if n, ok := bound(node, "maxProperties"); ok && float64(len(obj)) > n {
+		*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf("has %d properties, more than %v", len(obj), n)})
+	}
+}
+
+func (s *Schema) validateArray(node map[string]interface{}, arr []interface{}, path string, violations *[]SchemaViolation) {
+	if items, ok := node["items"]; ok {
+		for i, item := range arr {
+			s.validate(items, item, path+"/"+strconv.Itoa(i), violations)
+		}
+	}
+	if n, ok := bound(node, "minItems"); ok && float64(len(arr)) < n {
+		*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf("has %d items, fewer than %v", len(arr), n)})
+	}
+	if n, ok := bound(node, "maxItems"); ok && float64(len(arr)) > n {
+		*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf("has %d items, more than %v", len(arr), n)})
+	}
+	if node["uniqueItems"] == true {
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if equalJSON(arr[i], arr[j]) {
+					*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf("items %d and %d are equal", i, j)})
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *Schema) validateString(node map[string]interface{}, str string, fail func(string, ...interface{})) {
+	length := float64(utf8.RuneCountInString(str))
+	if n, ok := bound(node, "minLength"); ok && length < n {
+		fail("is %v characters long, shorter than %v", length, n)
+	}
+	if n, ok := bound(node, "maxLength"); ok && length > n {
+		fail("is %v characters long, longer than %v", length, n)
+	}
+	if pattern, ok := node["pattern"].(string); ok {
+		re, err := s.pattern(pattern)
+		switch {
+		case err != nil:
+			fail("invalid pattern %q in schema", pattern)
+		case !re.MatchString(str):
+			fail("does not match pattern %q", pattern)
+		}
+	}
+}
+
+func validateNumber(node map[string]interface{}, num json.Number, fail func(string, ...interface{})) {
+	f, err := num.Float64()
+	if err != nil {
+		return
+	}
+	// OpenAPI 3.0 and JSON Schema draft 4 mark exclusive bounds with booleans, later drafts with numbers.
+	if n, ok := bound(node, "minimum"); ok && (f < n || (f == n && node["exclusiveMinimum"] == true)) {
+		fail("%v is less than the minimum %v", num, n)
+	}
+	if n, ok := bound(node, "maximum"); ok && (f > n || (f == n && node["exclusiveMaximum"] == true)) {
+		fail("%v is greater than the maximum %v", num, n)
+	}
+	if n, ok := bound(node, "exclusiveMinimum"); ok && f <= n {
+		fail("%v is not greater than %v", num, n)
+	}
+	if n, ok := bound(node, "exclusiveMaximum"); ok && f >= n {
+		fail("%v is not less than %v", num, n)
+	}
+	if n, ok := bound(node, "multipleOf"); ok && n > 0 {
+		if q := f / n; math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("%v is not a multiple of %v", num, n)
+		}
+	}
+}
+
+// bound returns the numeric value of keyword in node.
+func bound(node map[string]interface{}, keyword string) (float64, bool) {
+	n, ok := node[keyword].(float64)
+	return n, ok
+}
+
+// pattern returns the compiled regular expression of pattern.
+func (s *Schema) pattern(pattern string) (*regexp.Regexp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if re, ok := s.patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if s.patterns == nil {
+		s.patterns = map[string]*regexp.Regexp{}
+	}
+	s.patterns[pattern] = re
+	return re, nil
+}
+
+// resolve returns the node a reference within the document points to.
+func (s *Schema) resolve(ref string) (interface{}, bool) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, false
+	}
+	node := s.root
+	for _, token := range strings.Split(strings.TrimPrefix(ref[1:], "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch n := node.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if node, ok = n[token]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil, false
+			}
+			node = n[i]
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// escapePointer escapes token for use in a JSON Pointer.
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// matchesType reports whether v is of the type, or one of the types, t.
+func matchesType(t, v interface{}) bool {
+	switch t := t.(type) {
+	case string:
+		actual := jsonType(v)
+		return actual == t || (t == "number" && actual == "integer")
+	case []interface{}:
+		for _, tt := range t {
+			if matchesType(tt, v) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func typeNames(t interface{}) string {
+	if types, ok := t.([]interface{}); ok {
+		names := make([]string, len(types))
+		for i, tt := range types {
+			names[i] = fmt.Sprint(tt)
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+// jsonType returns the JSON Schema type of a decoded value.
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func containsValue(values []interface{}, v interface{}) bool {
+	for _, candidate := range values {
+		if equalJSON(candidate, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalJSON reports whether a and b are the same JSON value; numbers may be decoded as float64 or json.Number.
+func equalJSON(a, b interface{}) bool {
+	if an, ok := number(a); ok {
+		bn, ok := number(b)
+		return ok && an == bn
+	}
+	switch a := a.(type) {
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equalJSON(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, av := range a {
+			bv, ok := b[k]
+			if !ok || !equalJSON(av, bv) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,111 @@
+package apiclient
+
+import (
+	"errors"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// ErrShutdown is returned for calls made once the client has begun shutting down, see Shutdown.
+var ErrShutdown = errors.New("apiclient: client is shut down")
+
+// Shutdown shuts the client down gracefully, for instance during a rolling restart: calls made from then on fail with
+// ErrShutdown, while outstanding ones are waited for until their response body is closed, or until ctx is done.
+// The client is then torn down: calls still outstanding are cancelled as by CancelAll, its rate limiter and scheduler
+// are stopped, the limiter state is saved one last time if a LimiterStore is configured, and idle connections are
+// closed. The error is that of ctx if it was done before every call completed.
+//
+// Clients derived with WithDefaults share their parent's state, so shutting down either shuts down both.
+func (c *Client) Shutdown(ctx context.Context) error {
+	var err error
+	select {
+	case <-c.calls.close():
+	case <-ctx.Done():
+		err = ctx.Err()
+		c.CancelAll()
+	}
+	c.shutdownOnce.Do(func() {
+		c.rateLimiter.close()
+		c.scheduler.stop()
+		if c.limiterStore != nil {
+			c.limiterStore.Save(c.limiterState())
+		}
+		c.httpClient.CloseIdleConnections()
+		c.log("client shut down", map[string]interface{}{"drained": err == nil})
+	})
+	return err
+}
+
+// CancelAll cancels every outstanding call, for instance when the application abandons the upstream, and returns
+// how many there were. The calls fail with context.Canceled; calls made afterwards are sent as usual.
+func (c *Client) CancelAll() int {
+	n := c.calls.cancelAll()
+	if n > 0 {
+		c.log("calls cancelled", map[string]interface{}{"calls": n})
+	}
+	return n
+}
+
+// calls tracks the outstanding calls of a client, so that Shutdown can wait for them and CancelAll cancel them.
+type calls struct {
+	mu      sync.Mutex
+	closed  bool
+	next    uint64
+	cancels map[uint64]context.CancelFunc
+	// drained is closed once no call is outstanding after the tracker was closed.
+	drained chan struct{}
+}
+
+// begin counts a new call made with ctx, and returns the context to make it with and the func ending it, or fails
+// if the tracker is closed.
+func (t *calls) begin(ctx context.Context) (context.Context, func(), error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, nil, ErrShutdown
+	}
+	if t.cancels == nil {
+		t.cancels = map[uint64]context.CancelFunc{}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	id := t.next
+	t.next++
+	t.cancels[id] = cancel
+	var once sync.Once
+	return ctx, func() { once.Do(func() { t.end(id) }) }, nil
+}
+
+func (t *calls) end(id uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cancels[id]()
+	delete(t.cancels, id)
+	if t.closed && len(t.cancels) == 0 {
+		close(t.drained)
+	}
+}
+
+// cancelAll cancels the outstanding calls and returns how many there were.
+func (t *calls) cancelAll() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, cancel := range t.cancels {
+		cancel()
+	}
+	return len(t.cancels)
+}
+
+// close refuses new calls, and returns a channel closed once the outstanding ones have ended.
+func (t *calls) close() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.closed {
+		t.closed = true
+		t.drained = make(chan struct{})
+		if len(t.cancels) == 0 {
+			close(t.drained)
+		}
+	}
+	return t.drained
+}
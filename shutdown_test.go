@@ -0,0 +1,137 @@
+package apiclient
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// blockedCall is a call of trackedCalls, sent to a server that holds its request until released.
+type blockedCall struct {
+	name string
+	call func(c *Client, host string) error
+}
+
+// acceptWebSocket, if set, completes the WebSocket handshakes of newBlockingServer.
+var acceptWebSocket func(w http.ResponseWriter, r *http.Request)
+
+// trackedCalls are the calls that send to a fixed host rather than through send. Builds with WebSocket support add
+// Dial.
+var trackedCalls = []blockedCall{
+	{
+		name: "upload chunk",
+		call: func(c *Client, host string) error {
+			return c.Upload(context.Background(), &UploadState{URL: host + "/uploads/1", Size: 3}, strings.NewReader("abc"), 0)
+		},
+	},
+	{
+		name: "upload sync",
+		call: func(c *Client, host string) error {
+			return c.Upload(context.Background(), &UploadState{URL: host + "/uploads/1", Offset: 1, Size: 3}, strings.NewReader("abc"), 0)
+		},
+	},
+	{
+		name: "health probe",
+		call: func(c *Client, host string) error { return c.HealthCheck(context.Background()) },
+	},
+}
+
+// newBlockingServer returns a server that signals every request on arrived and answers it once release is closed.
+func newBlockingServer(t *testing.T) (url string, arrived chan struct{}, release chan struct{}) {
+	arrived, release = make(chan struct{}, 16), make(chan struct{})
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		switch {
+		case r.Header.Get("Upgrade") != "" && acceptWebSocket != nil:
+			acceptWebSocket(w, r)
+		case r.Method == "PATCH" || r.Method == "HEAD":
+			w.Header().Set("Upload-Offset", "3")
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	return srv.URL, arrived, release
+}
+
+func newBlockedClient(t *testing.T, host string) *Client {
+	return newTestClient(t, WithHealthProbe(HealthProbe{Config: &APIConfig{Host: host}, Request: testParams{}}))
+}
+
+func TestShutdownWaitsForTrackedCalls(t *testing.T) {
+	for _, tt := range trackedCalls {
+		t.Run(tt.name, func(t *testing.T) {
+			host, arrived, release := newBlockingServer(t)
+			c := newBlockedClient(t, host)
+			errs := make(chan error, 1)
+			go func() { errs <- tt.call(c, host) }()
+			<-arrived
+
+			shut := make(chan error, 1)
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				shut <- c.Shutdown(ctx)
+			}()
+			eventually(t, func() bool {
+				c.calls.mu.Lock()
+				defer c.calls.mu.Unlock()
+				return c.calls.closed
+			})
+			select {
+			case err := <-shut:
+				t.Fatalf("Shutdown returned %v with the call outstanding", err)
+			case <-time.After(20 * time.Millisecond):
+			}
+			if err := tt.call(c, host); !errors.Is(err, ErrShutdown) {
+				t.Errorf("call after Shutdown = %v, want ErrShutdown", err)
+			}
+
+			close(release)
+			if err := <-errs; err != nil {
+				t.Errorf("outstanding call failed: %v", err)
+			}
+			if err := <-shut; err != nil {
+				t.Errorf("Shutdown = %v", err)
+			}
+			if n := len(arrived); n != 0 {
+				t.Errorf("%d requests sent after Shutdown", n)
+			}
+		})
+	}
+}
+
+func TestCancelAllAbortsTrackedCalls(t *testing.T) {
+	for _, tt := range trackedCalls {
+		t.Run(tt.name, func(t *testing.T) {
+			host, arrived, release := newBlockingServer(t)
+			defer close(release)
+			c := newBlockedClient(t, host)
+			errs := make(chan error, 1)
+			go func() { errs <- tt.call(c, host) }()
+			<-arrived
+
+			if n := c.CancelAll(); n != 1 {
+				t.Errorf("CancelAll = %d, want 1", n)
+			}
+			select {
+			case err := <-errs:
+				if !errors.Is(err, context.Canceled) {
+					t.Errorf("cancelled call = %v, want context.Canceled", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("call not aborted by CancelAll")
+			}
+			if n := len(arrived); n != 0 {
+				t.Errorf("%d requests sent after CancelAll", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package apiclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// URLSigner signs the URLs made by SignURL, e.g. with an HMAC the API verifies, valid until expires unless it is
+// zero.
+type URLSigner interface {
+	SignURL(u *url.URL, expires time.Time) error
+}
+
+// WithURLSigner configures the client to sign the URLs made by SignURL with s.
+func WithURLSigner(s URLSigner) ClientOption {
+	return func(c *Client) error {
+		c.urlSigner = s
+		return nil
+	}
+}
+
+// HMACURLSigner is a URLSigner adding the expiration time, in seconds since the epoch, and the base64url HMAC of the
+// path and query to the query of a URL. The query is signed with its parameters sorted by name, as url.Values.Encode
+// writes them.
+type HMACURLSigner struct {
+	Secret []byte
+	// ExpiresParam defaults to "expires".
+	ExpiresParam string
+	// SignatureParam defaults to "signature".
+	SignatureParam string
+	// Hash defaults to sha256.New.
+	Hash func() hash.Hash
+}
+
+// SignURL signs u.
+func (s HMACURLSigner) SignURL(u *url.URL, expires time.Time) error {
+	if len(s.Secret) == 0 {
+		return errors.New("apiclient: empty URL signing secret")
+	}
+	expiresParam, signatureParam, h := s.ExpiresParam, s.SignatureParam, s.Hash
+	if expiresParam == "" {
+		expiresParam = "expires"
+	}
+	if signatureParam == "" {
+		signatureParam = "signature"
+	}
+	if h == nil {
+		h = sha256.New
+	}
+	q := u.Query()
+	q.Del(signatureParam)
+	if !expires.IsZero() {
+		q.Set(expiresParam, strconv.FormatInt(expires.Unix(), 10))
+	}
+	mac := hmac.New(h, s.Secret)
+	mac.Write([]byte(u.EscapedPath() + "?" + q.Encode()))
+	q.Set(signatureParam, base64.RawURLEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = q.Encode()
+	return nil
+}
+
+// SignURL returns the URL of a GET of the API endpoint with apiReq, carrying the client's credentials, so that it can
+// be handed to a browser or another service to fetch directly. With a URLSigner configured, the URL is signed and, if
+// expiry is positive, valid for that long; without one, an expiry is an error. So is a credential sent in a header,
+// which a URL cannot carry. The URL holds secrets: treat it like one.
+func (c *Client) SignURL(ctx context.Context, config *APIConfig, apiReq APIRequest, expiry time.Duration, opts ...RequestOption) (string, error) {
+	if expiry > 0 && c.urlSigner == nil {
+		return "", errors.New("apiclient: an expiring URL needs a URL signer")
+	}
+	o := c.newRequestOptions(opts)
+	ctx, cancel := o.context(ctx, config)
+	defer cancel()
+	path, err := expandPath(ctx, config, apiReq)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(c.host(ctx, config) + path)
+	if err != nil {
+		return "", err
+	}
+	q := apiReq.Params()
+	header := http.Header{}
+	if _, err := c.authenticate(ctx, header, q); err != nil {
+		return "", err
+	}
+	if len(header) > 0 {
+		names := make([]string, 0, len(header))
+		for name := range header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("apiclient: credentials sent in headers %v cannot be carried by a URL", names)
+	}
+	u.RawQuery = q.Encode()
+	if c.urlSigner != nil {
+		var expires time.Time
+		if expiry > 0 {
+			expires = c.clock.Now().Add(expiry)
+		}
+		if err := c.urlSigner.SignURL(u, expires); err != nil {
+			return "", err
+		}
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,46 @@
+//go:build apiclient_slim
+
+package apiclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestSlimLeavesOut(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  ClientOption
+	}{
+		{"brotli request compression", WithRequestCompression("br", 0)},
+		{"zstd request compression", WithRequestCompression("zstd", 0)},
+		{"h2c", WithHTTP2(true)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewClient(tt.opt); err == nil {
+				t.Error("NewClient succeeded")
+			}
+		})
+	}
+}
+
+func TestSlimKeepsUnsupportedCodings(t *testing.T) {
+	for _, coding := range []string{"br", "zstd"} {
+		t.Run(coding, func(t *testing.T) {
+			resp := &http.Response{
+				Header: http.Header{"Content-Encoding": {coding}},
+				Body:   io.NopCloser(bytes.NewReader([]byte("raw"))),
+			}
+			if err := decompress(resp, false); err != nil {
+				t.Fatal(err)
+			}
+			got, _ := io.ReadAll(resp.Body)
+			if string(got) != "raw" || resp.Header.Get("Content-Encoding") != coding {
+				t.Errorf("body %q with Content-Encoding %q, want it left as sent", got, resp.Header.Get("Content-Encoding"))
+			}
+		})
+	}
+}
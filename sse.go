@@ -0,0 +1,146 @@
+package apiclient
+
+import (
+	"bufio"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// defaultSSERetry is how long StreamEvents waits before reconnecting, unless the server says otherwise.
+const defaultSSERetry = 3 * time.Second
+
+// minSSERetry is the shortest retry delay StreamEvents accepts from a server, so that a retry of 0 does not make it
+// reconnect in a loop.
+const minSSERetry = 100 * time.Millisecond
+
+// Event is a Server-Sent Event.
+type Event struct {
+	ID string
+	// Event is the event type, "message" unless the server named it.
+	Event string
+	Data  string
+}
+
+// StreamEvents connects to a Server-Sent Events endpoint and calls handle with every event received, until ctx is done
+// or handle returns an error, which is then returned. When the connection drops, StreamEvents reconnects after the
+// server's retry delay (3 seconds by default, and no less than 100 milliseconds), sending the last event ID seen in
+// Last-Event-ID so the server can resume the stream. Failing to connect in the first place, or a non-200 response, ends
+// the stream with an error.
+func (c *Client) StreamEvents(ctx context.Context, config *APIConfig, apiReq APIRequest, handle func(Event) error) error {
+	ctx = c.withRequestID(c.defaultsContext(ctx))
+	s := &eventStream{retry: defaultSSERetry}
+	for connected := false; ; connected = true {
+		header := http.Header{}
+		header.Set("Accept", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		if s.lastID != "" {
+			header.Set("Last-Event-ID", s.lastID)
+		}
+		resp, err := c.send(ctx, "GET", config, apiReq, header, nil)
+		switch {
+		case err != nil && (!connected || ctx.Err() != nil):
+			return c.requestError(ctx, err)
+		case err == nil && resp.StatusCode == http.StatusNoContent:
+			// The server asks the client to stop reconnecting.
+			resp.Body.Close()
+			return nil
+		case err == nil && resp.StatusCode != http.StatusOK:
+			resp.Body.Close()
+			return c.requestError(ctx, &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status})
+		case err == nil:
+			err = s.read(resp, handle)
+			resp.Body.Close()
+			if he, ok := err.(handlerError); ok {
+				return he.err
+			}
+		}
+		if err := c.scheduler.sleep(ctx, s.retry); err != nil {
+			return err
+		}
+	}
+}
+
+// eventStream holds the state kept across the connections of a stream.
+type eventStream struct {
+	lastID string
+	retry  time.Duration
+}
+
+// handlerError wraps errors returned by the event handler, to tell them apart from read errors.
+type handlerError struct {
+	err error
+}
+
+func (e handlerError) Error() string { return e.err.Error() }
+
+// read parses events from resp until the body ends or handle fails.
+func (s *eventStream) read(resp *http.Response, handle func(Event) error) error {
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 4096), 1<<20)
+	var ev Event
+	var data strings.Builder
+	hasData := false
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			if hasData {
+				ev.Data = strings.TrimSuffix(data.String(), "\n")
+				ev.ID = s.lastID
+				if ev.Event == "" {
+					ev.Event = "message"
+				}
+				if err := handle(ev); err != nil {
+					return handlerError{err}
+				}
+			}
+			ev, hasData = Event{}, false
+			data.Reset()
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "event":
+			ev.Event = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.lastID = value
+			}
+		case "retry":
+			if d, ok := parseSSERetry(value); ok {
+				s.retry = d
+			}
+		}
+	}
+	return sc.Err()
+}
+
+// parseSSERetry parses the value of a retry field, a delay in milliseconds given in ASCII digits only, raised to
+// minSSERetry. It reports false for any other value, which the stream ignores.
+func parseSSERetry(value string) (time.Duration, bool) {
+	if value == "" || strings.TrimLeft(value, "0123456789") != "" {
+		return 0, false
+	}
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ms > int64(math.MaxInt64/time.Millisecond) {
+		return 0, false
+	}
+	if d := time.Duration(ms) * time.Millisecond; d > minSSERetry {
+		return d, true
+	}
+	return minSSERetry, true
+}
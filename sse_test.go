@@ -0,0 +1,41 @@
+package apiclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventStreamRetry(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		want  time.Duration
+	}{
+		{name: "delay", field: "retry: 5000", want: 5 * time.Second},
+		{name: "no space", field: "retry:250", want: 250 * time.Millisecond},
+		{name: "zero", field: "retry: 0", want: minSSERetry},
+		{name: "below the floor", field: "retry: 1", want: minSSERetry},
+		{name: "negative", field: "retry: -1", want: defaultSSERetry},
+		{name: "signed", field: "retry: +500", want: defaultSSERetry},
+		{name: "empty", field: "retry:", want: defaultSSERetry},
+		{name: "not a number", field: "retry: soon", want: defaultSSERetry},
+		{name: "fraction", field: "retry: 1.5", want: defaultSSERetry},
+		{name: "overflowing", field: "retry: 99999999999999999999", want: defaultSSERetry},
+		{name: "overflowing a duration", field: "retry: 9223372036854775807", want: defaultSSERetry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &eventStream{retry: defaultSSERetry}
+			resp := &http.Response{Body: ioutil.NopCloser(strings.NewReader(tt.field + "\n\n"))}
+			if err := s.read(resp, func(Event) error { return nil }); err != nil {
+				t.Fatal(err)
+			}
+			if s.retry != tt.want {
+				t.Errorf("retry = %s, want %s", s.retry, tt.want)
+			}
+		})
+	}
+}
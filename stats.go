@@ -0,0 +1,94 @@
+package apiclient
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are counters of a client's activity since it was created.
+type Stats struct {
+	// Requests is the number of requests sent, retries and failovers included.
+	Requests int64
+	// Status2xx to Status5xx count the responses by status class.
+	Status2xx int64
+	Status3xx int64
+	Status4xx int64
+	Status5xx int64
+	// Errors counts the requests that got no response.
+	Errors int64
+	// Retries counts the requests sent again by the retry policy.
+	Retries int64
+	// CacheHits counts the GETs answered from the cache, including those revalidated with the server.
+	CacheHits int64
+	// RateLimitWaits counts the requests that had to wait for the rate limiter, for RateLimitWaitTime in total.
+	RateLimitWaits    int64
+	RateLimitWaitTime time.Duration
+	// Overloaded counts the requests turned away by load shedding.
+	Overloaded int64
+	// BytesSent and BytesReceived count request and response bodies, as sent over the wire.
+	BytesSent     int64
+	BytesReceived int64
+	// AverageLatency is the mean time from sending a request until its response headers arrived.
+	AverageLatency time.Duration
+}
+
+// Stats returns a snapshot of the client's counters.
+func (c *Client) Stats() Stats {
+	s := c.stats
+	stats := Stats{
+		Requests:          atomic.LoadInt64(&s.requests),
+		Status2xx:         atomic.LoadInt64(&s.statusClasses[2]),
+		Status3xx:         atomic.LoadInt64(&s.statusClasses[3]),
+		Status4xx:         atomic.LoadInt64(&s.statusClasses[4]),
+		Status5xx:         atomic.LoadInt64(&s.statusClasses[5]),
+		Errors:            atomic.LoadInt64(&s.errors),
+		Retries:           atomic.LoadInt64(&s.retries),
+		CacheHits:         atomic.LoadInt64(&s.cacheHits),
+		RateLimitWaits:    atomic.LoadInt64(&c.rateLimiter.waits),
+		RateLimitWaitTime: time.Duration(atomic.LoadInt64(&c.rateLimiter.waited)),
+		Overloaded:        atomic.LoadInt64(&c.rateLimiter.shed),
+		BytesSent:         atomic.LoadInt64(&s.bytesSent),
+		BytesReceived:     atomic.LoadInt64(&s.bytesReceived),
+	}
+	if stats.Requests > 0 {
+		stats.AverageLatency = time.Duration(atomic.LoadInt64(&s.latency) / stats.Requests)
+	}
+	return stats
+}
+
+// clientStats is allocated on its own, so that its counters are 64-bit aligned for atomic access.
+type clientStats struct {
+	requests      int64
+	statusClasses [6]int64
+	errors        int64
+	retries       int64
+	cacheHits     int64
+	bytesSent     int64
+	bytesReceived int64
+	// latency is the sum of the latencies of the requests, in nanoseconds.
+	latency int64
+}
+
+// countResponse records the outcome of a request that took latency.
+func (s *clientStats) countResponse(statusCode int, err error, latency time.Duration) {
+	atomic.AddInt64(&s.requests, 1)
+	atomic.AddInt64(&s.latency, int64(latency))
+	if class := statusCode / 100; err == nil && class >= 1 && class <= 5 {
+		atomic.AddInt64(&s.statusClasses[class], 1)
+	} else if err != nil {
+		atomic.AddInt64(&s.errors, 1)
+	}
+}
+
+// countedBody counts the bytes read from a body into n.
+type countedBody struct {
+	io.ReadCloser
+	n *int64
+}
+
+func (b countedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddInt64(b.n, int64(n))
+	return n, err
+}
@@ -0,0 +1,94 @@
+package apiclient
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// WithBandwidthLimit caps the throughput of transfers to download and upload bytes per second, 0 meaning no cap.
+// The download cap covers reading the data of GetBinary, DownloadFile and GetBinaryRanges; the upload cap covers the
+// bodies sent by PostMultipart and Upload. Each cap is shared by all transfers in that direction.
+func WithBandwidthLimit(download, upload int64) ClientOption {
+	return func(c *Client) error {
+		if download < 0 || upload < 0 {
+			return errors.New("apiclient: bandwidth limits must not be negative")
+		}
+		if download > 0 {
+			c.downloadThrottle = &throttle{rate: download}
+		}
+		if upload > 0 {
+			c.uploadThrottle = &throttle{rate: upload}
+		}
+		return nil
+	}
+}
+
+// throttle paces transfers to rate bytes per second.
+type throttle struct {
+	rate int64
+	mu   sync.Mutex
+	// paid is when the transfers so far have been paid for at rate.
+	paid time.Time
+}
+
+// chunk is the most a single read may transfer, so transfers are paced about ten times a second.
+func (t *throttle) chunk() int {
+	if n := t.rate / 10; n > 0 {
+		return int(n)
+	}
+	return 1
+}
+
+// wait blocks until n more bytes fit under the rate.
+func (t *throttle) wait(ctx context.Context, c *Client, n int) error {
+	t.mu.Lock()
+	now := c.clock.Now()
+	if t.paid.Before(now) {
+		t.paid = now
+	}
+	t.paid = t.paid.Add(time.Duration(int64(n) * int64(time.Second) / t.rate))
+	d := t.paid.Sub(now)
+	t.mu.Unlock()
+	return c.scheduler.sleep(ctx, d)
+}
+
+// throttledReader paces the reads from r with t.
+type throttledReader struct {
+	ctx context.Context
+	c   *Client
+	t   *throttle
+	r   io.Reader
+}
+
+func (tr *throttledReader) Read(p []byte) (int, error) {
+	if max := tr.t.chunk(); len(p) > max {
+		p = p[:max]
+	}
+	n, err := tr.r.Read(p)
+	if n > 0 {
+		if werr := tr.t.wait(tr.ctx, tr.c, n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+// throttled returns r paced by t, or r itself if t is nil.
+func (c *Client) throttled(ctx context.Context, t *throttle, r io.Reader) io.Reader {
+	if t == nil {
+		return r
+	}
+	return &throttledReader{ctx: ctx, c: c, t: t, r: r}
+}
+
+// throttledBody returns body with its reads paced by t, or body itself if t is nil.
+func (c *Client) throttledBody(ctx context.Context, t *throttle, body io.ReadCloser) io.ReadCloser {
+	if t == nil {
+		return body
+	}
+	return readCloser{c.throttled(ctx, t, body), body}
+}
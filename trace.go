@@ -0,0 +1,103 @@
+package apiclient
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings holds the connection diagnostics captured for a single request.
+type Timings struct {
+	DNSLookup    time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	// TimeToFirstByte is measured from the start of the request to the first byte of the response.
+	TimeToFirstByte time.Duration
+	// ConnReused is true when the request went over a pooled connection, in which case DNSLookup, Connect and
+	// TLSHandshake are zero.
+	ConnReused bool
+}
+
+// TraceHook receives the timings of every request made by the client, once the response headers have arrived or
+// the request has failed.
+type TraceHook func(req *http.Request, t Timings)
+
+// WithHTTPTrace configures the client to capture DNS, connect, TLS and time-to-first-byte timings for each request
+// and report them to hook.
+func WithHTTPTrace(hook TraceHook) ClientOption {
+	return func(c *Client) error {
+		c.traceHook = hook
+		return nil
+	}
+}
+
+// requestTracer collects Timings through httptrace callbacks, which may fire from the dialing goroutines.
+type requestTracer struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	timings      Timings
+}
+
+func newRequestTracer() *requestTracer {
+	return &requestTracer{start: time.Now()}
+}
+
+func (rt *requestTracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			rt.mu.Lock()
+			rt.timings.ConnReused = info.Reused
+			rt.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			rt.mu.Lock()
+			rt.dnsStart = time.Now()
+			rt.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			rt.mu.Lock()
+			rt.timings.DNSLookup = time.Since(rt.dnsStart)
+			rt.mu.Unlock()
+		},
+		ConnectStart: func(network, addr string) {
+			rt.mu.Lock()
+			if rt.connectStart.IsZero() {
+				rt.connectStart = time.Now()
+			}
+			rt.mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			rt.mu.Lock()
+			if err == nil {
+				rt.timings.Connect = time.Since(rt.connectStart)
+			}
+			rt.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			rt.mu.Lock()
+			rt.tlsStart = time.Now()
+			rt.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			rt.mu.Lock()
+			rt.timings.TLSHandshake = time.Since(rt.tlsStart)
+			rt.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			rt.mu.Lock()
+			rt.timings.TimeToFirstByte = time.Since(rt.start)
+			rt.mu.Unlock()
+		},
+	}
+}
+
+func (rt *requestTracer) result() Timings {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.timings
+}
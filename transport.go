@@ -1,40 +1,107 @@
 package apiclient
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
+	"strings"
 )
 
 const userAgent = "ApiClientGo/0.1"
 
+// userAgentValues is shared by the headers of every request the client builds. Its capacity is one, so appending to
+// it never writes into the shared array.
+var userAgentValues = []string{userAgent}[:1:1]
+
 // transport is an http.RoundTripper that replaces or appends userAgent the request's User-Agent header.
+//
+// Requests built by the client already carry userAgent, see stampUserAgent, and are passed through untouched; only
+// requests made directly over a configured http.Client are cloned so their header can be changed.
 type transport struct {
 	Base http.RoundTripper
+	// protocols, if set, sends requests over HTTP/3 or cleartext HTTP/2 instead of Base.
+	protocols *protocols
 }
 
 // RoundTrip appends userAgent existing User-Agent header and performs the request via t.Base.
 func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
-	req = cloneRequest(req)
 	ua := req.Header.Get("User-Agent")
-	if ua == "" {
-		ua = userAgent
-	} else {
-		ua = fmt.Sprintf("%s;%s", ua, userAgent)
+	if !strings.HasSuffix(ua, userAgent) {
+		req = cloneRequest(req)
+		req.Header.Set("User-Agent", withUserAgent(ua))
+	}
+	if t.protocols != nil {
+		return t.protocols.roundTrip(req, t.Base)
 	}
-	req.Header.Set("User-Agent", ua)
 	return t.Base.RoundTrip(req)
 }
 
+// CloseIdleConnections closes the idle connections of t.Base and of the transports of other protocols.
+func (t *transport) CloseIdleConnections() {
+	type closeIdler interface {
+		CloseIdleConnections()
+	}
+	if ci, ok := t.Base.(closeIdler); ok {
+		ci.CloseIdleConnections()
+	}
+	if p := t.protocols; p != nil {
+		if ci, ok := p.h2c.(closeIdler); ok {
+			ci.CloseIdleConnections()
+		}
+		if ci, ok := p.h3.(closeIdler); ok {
+			ci.CloseIdleConnections()
+		}
+	}
+}
+
+// stampUserAgent sets the User-Agent header of a request the client owns, so transport need not clone it.
+func stampUserAgent(h http.Header) {
+	switch ua := h.Get("User-Agent"); {
+	case ua == "":
+		h["User-Agent"] = userAgentValues
+	case !strings.HasSuffix(ua, userAgent):
+		h.Set("User-Agent", withUserAgent(ua))
+	}
+}
+
+// withUserAgent returns ua with userAgent appended, or userAgent if ua is empty.
+func withUserAgent(ua string) string {
+	if ua == "" {
+		return userAgent
+	}
+	return ua + ";" + userAgent
+}
+
 // cloneRequest returns a clone of the provided *http.Request.
 // The clone is a shallow copy of the struct and its Header map.
 func cloneRequest(r *http.Request) *http.Request {
 	// shallow copy of the struct
 	r2 := new(http.Request)
 	*r2 = *r
-	// deep copy of the Header
-	r2.Header = make(http.Header)
+	// deep copy of the Header, leaving room for the User-Agent
+	r2.Header = make(http.Header, len(r.Header)+1)
 	for k, s := range r.Header {
 		r2.Header[k] = s
 	}
 	return r2
 }
+
+// baseTransport returns the *http.Transport the client's requests go through, for options to configure it. The
+// transport is cloned first, so neither http.DefaultTransport nor one shared with other clients is changed.
+func (c *Client) baseTransport() (*http.Transport, error) {
+	t, ok := c.httpClient.Transport.(*transport)
+	if !ok {
+		return nil, errCustomTransport
+	}
+	base, ok := t.Base.(*http.Transport)
+	if !ok {
+		return nil, errCustomTransport
+	}
+	if !c.ownTransport {
+		base = base.Clone()
+		t.Base = base
+		c.ownTransport = true
+	}
+	return base, nil
+}
+
+var errCustomTransport = errors.New("apiclient: the HTTP client's transport is not an *http.Transport")
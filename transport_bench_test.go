@@ -0,0 +1,90 @@
+package apiclient
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// stubTransport answers every request with a 200 and body, without any network, so benchmarks measure the client.
+type stubTransport struct {
+	body string
+}
+
+func (s stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(s.body)),
+		Request:    req,
+	}, nil
+}
+
+// nopTransport answers nothing, so benchmarks of transport measure the wrapper alone.
+type nopTransport struct{}
+
+func (nopTransport) RoundTrip(*http.Request) (*http.Response, error) { return nil, nil }
+
+func newBenchClient(b *testing.B, body string) *Client {
+	b.Helper()
+	c, err := NewClient(WithHTTPClient(&http.Client{Transport: stubTransport{body}}), WithRateLimit(1<<20))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return c
+}
+
+func BenchmarkTransportRoundTrip(b *testing.B) {
+	benchmarks := []struct {
+		name  string
+		stamp bool
+	}{
+		{"client-built", true},
+		{"external", false},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			t := &transport{Base: nopTransport{}}
+			req, _ := http.NewRequest("GET", "http://api.example.com/", nil)
+			if bm.stamp {
+				stampUserAgent(req.Header)
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				t.RoundTrip(req)
+			}
+		})
+	}
+}
+
+func BenchmarkGetJSON(b *testing.B) {
+	c := newBenchClient(b, `{"name":"Seattle","score":97}`)
+	config := &APIConfig{Host: "http://api.example.com", Path: "/score"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var resp struct {
+			Name  string
+			Score int
+		}
+		if err := c.GetJSON(context.Background(), config, testParams{}, &resp); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetBinary(b *testing.B) {
+	c := newBenchClient(b, strings.Repeat("x", 4096))
+	config := &APIConfig{Host: "http://api.example.com", Path: "/tile"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		resp, err := c.GetBinary(context.Background(), config, testParams{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Data)
+		resp.Data.Close()
+	}
+}
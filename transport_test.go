@@ -0,0 +1,64 @@
+package apiclient
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSharedHTTPClientIsolated(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  ClientOption
+		// configured reports whether the option applies to c.
+		configured func(c *Client) bool
+	}{
+		{
+			name: "proxy",
+			opt:  WithProxy("http://proxy.example.com:3128"),
+			configured: func(c *Client) bool {
+				return c.httpClient.Transport.(*transport).Base.(*http.Transport).Proxy != nil
+			},
+		},
+		{
+			name:       "h2c",
+			opt:        WithHTTP2(true),
+			configured: func(c *Client) bool { return c.httpClient.Transport.(*transport).protocols != nil },
+		},
+		{
+			name:       "http3",
+			opt:        WithHTTP3(nopTransport{}),
+			configured: func(c *Client) bool { return c.httpClient.Transport.(*transport).protocols != nil },
+		},
+		{
+			name:       "cookies",
+			opt:        WithSessionCookies(),
+			configured: func(c *Client) bool { return c.httpClient.Jar != nil },
+		},
+		{
+			name:       "timeout",
+			opt:        Config{Timeout: time.Second}.Options()[0],
+			configured: func(c *Client) bool { return c.httpClient.Timeout != 0 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewClient(tt.opt); err != nil {
+				t.Skipf("option not available in this build: %v", err)
+			}
+			base := &http.Transport{}
+			shared := &http.Client{Transport: base}
+			plain := newTestClient(t, WithHTTPClient(shared))
+			configured := newTestClient(t, WithHTTPClient(shared), tt.opt)
+			if !tt.configured(configured) {
+				t.Fatal("option not applied")
+			}
+			if tt.configured(plain) {
+				t.Error("option applied to the other client of the HTTP client")
+			}
+			if shared.Transport != base || shared.Jar != nil || shared.Timeout != 0 || base.Proxy != nil {
+				t.Error("option changed the HTTP client given to WithHTTPClient")
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"golang.org/x/net/context"
+)
+
+// Get makes a request to the API endpoint like GetJSON and returns the decoded response as a T, sparing the caller
+// from declaring a variable and passing a pointer to it:
+//
+//	place, err := apiclient.Get[Place](ctx, c, config, req)
+func Get[T any](ctx context.Context, c API, config *APIConfig, apiReq APIRequest, opts ...RequestOption) (T, error) {
+	var resp T
+	err := c.GetJSON(ctx, config, apiReq, &resp, opts...)
+	return resp, err
+}
+
+// Resource is a typed handle on one API endpoint, decoding its responses into T.
+type Resource[T any] struct {
+	Client *Client
+	Config *APIConfig
+}
+
+// Get requests the resource with apiReq and returns its decoded response.
+func (r Resource[T]) Get(ctx context.Context, apiReq APIRequest, opts ...RequestOption) (T, error) {
+	return Get[T](ctx, r.Client, r.Config, apiReq, opts...)
+}
+
+// StreamArray streams the elements of a JSON array response like GetJSONArrayStream, decoding each into a T:
+//
+//	err := apiclient.StreamArray(ctx, c, config, req, func(p Place) error { return index(p) })
+func StreamArray[T any](ctx context.Context, c *Client, config *APIConfig, apiReq APIRequest, handle func(T) error, opts ...RequestOption) error {
+	return c.GetJSONArrayStream(ctx, config, apiReq, func(raw json.RawMessage) error {
+		var elem T
+		if err := json.Unmarshal(raw, &elem); err != nil {
+			return err
+		}
+		return handle(elem)
+	}, opts...)
+}
+
+// StreamCSV streams the records of a CSV response like GetCSVStream, decoding each into a T like GetCSV does.
+func StreamCSV[T any](ctx context.Context, c *Client, config *APIConfig, apiReq APIRequest, handle func(T) error, opts ...RequestOption) error {
+	var zero T
+	t := reflect.TypeOf(zero)
+	if t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("apiclient: StreamCSV needs a struct, not %T", zero)
+	}
+	return c.csv(ctx, config, apiReq, opts, true, func(header []string) func([]string, int) error {
+		columns := csvColumns(t, header)
+		return func(record []string, line int) error {
+			var row T
+			if err := decodeCSVRecord(reflect.ValueOf(&row).Elem(), columns, header, record, line); err != nil {
+				return err
+			}
+			return handle(row)
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package apiclient
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// tusVersion is the version of the tus resumable upload protocol spoken by uploads.
+	tusVersion = "1.0.0"
+	// DefaultUploadChunkSize is the size of the chunks Upload sends when none is given.
+	DefaultUploadChunkSize = 8 << 20
+	// uploadRetries is how many times a failed chunk is retried before Upload gives up.
+	uploadRetries = 3
+	// uploadBackoff is the delay before the first retry of a chunk, doubled for every further one.
+	uploadBackoff = time.Second
+)
+
+// UploadState is the progress of a resumable upload. It can be saved, and passed to Upload again, even by another
+// process, to resume the upload where it stopped.
+type UploadState struct {
+	// URL is the upload's URL, assigned by the server when it was created.
+	URL string
+	// Offset is the number of bytes the server has received.
+	Offset int64
+	Size   int64
+}
+
+// Done reports whether the server has received the whole upload.
+func (s *UploadState) Done() bool {
+	return s.Offset >= s.Size
+}
+
+// CreateUpload initiates a resumable upload of size bytes at the endpoint of config and apiReq, following the tus
+// protocol (https://tus.io). metadata is sent along in the Upload-Metadata header. The returned state is then passed
+// to Upload.
+func (c *Client) CreateUpload(ctx context.Context, config *APIConfig, apiReq APIRequest, size int64, metadata map[string]string) (*UploadState, error) {
+	ctx = c.withRequestID(c.defaultsContext(ctx))
+	header := tusHeader()
+	header.Set("Upload-Length", strconv.FormatInt(size, 10))
+	if len(metadata) > 0 {
+		header.Set("Upload-Metadata", encodeUploadMetadata(metadata))
+	}
+	resp, err := c.send(ctx, "POST", config, apiReq, header, nil)
+	if err != nil {
+		return nil, c.requestError(ctx, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return nil, c.requestError(ctx, &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status})
+	}
+	loc, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.Header.Get("Location") == "" {
+		return nil, c.requestError(ctx, fmt.Errorf("apiclient: upload created without a valid Location: %q", resp.Header.Get("Location")))
+	}
+	return &UploadState{URL: loc.String(), Size: size}, nil
+}
+
+// Upload sends the rest of a resumable upload, read from data, in chunks of chunkSize bytes (DefaultUploadChunkSize
+// if 0), updating state after every chunk. The upload is complete once the last chunk is accepted. A chunk that
+// fails is retried up to three times, with increasing delays, after asking the server how much it received. A chunk
+// aborted by CancelAll or Shutdown is not retried.
+func (c *Client) Upload(ctx context.Context, state *UploadState, data io.ReaderAt, chunkSize int64) error {
+	ctx = c.withRequestID(c.defaultsContext(ctx))
+	if chunkSize <= 0 {
+		chunkSize = DefaultUploadChunkSize
+	}
+	up, err := newUploadTarget(state.URL)
+	if err != nil {
+		return c.requestError(ctx, err)
+	}
+	if state.Offset > 0 {
+		// The server may have received more than was saved before an interruption.
+		if err := c.syncUpload(ctx, up, state); err != nil {
+			return c.requestError(ctx, err)
+		}
+	}
+	retries, backoff := 0, uploadBackoff
+	for !state.Done() {
+		err := c.uploadChunk(ctx, up, state, data, chunkSize)
+		if err == nil {
+			retries, backoff = 0, uploadBackoff
+			continue
+		}
+		if he, ok := err.(*HTTPError); (ok && he.StatusCode < 500 && he.StatusCode != http.StatusConflict) || ctx.Err() != nil || errors.Is(err, ErrShutdown) || errors.Is(err, context.Canceled) || retries == uploadRetries {
+			return c.requestError(ctx, err)
+		}
+		retries++
+		if err := c.scheduler.sleep(ctx, backoff); err != nil {
+			return c.requestError(ctx, err)
+		}
+		backoff *= 2
+		if err := c.syncUpload(ctx, up, state); err != nil && ctx.Err() != nil {
+			return c.requestError(ctx, err)
+		}
+	}
+	return nil
+}
+
+// uploadChunk sends the chunk of data starting at state.Offset.
+func (c *Client) uploadChunk(ctx context.Context, up *uploadTarget, state *UploadState, data io.ReaderAt, chunkSize int64) error {
+	n := state.Size - state.Offset
+	if n > chunkSize {
+		n = chunkSize
+	}
+	offset := state.Offset
+	header := tusHeader()
+	header.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	body := &requestBody{
+		contentType: "application/offset+octet-stream",
+		size:        n,
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(io.NewSectionReader(data, offset, n)), nil
+		},
+		replayable: true,
+		// Upload offsets count the bytes of the file, not of a compressed body.
+		identity: true,
+	}
+	resp, err := c.sendDirect(ctx, up.host, "PATCH", &up.config, up.params, header, body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return state.setOffset(resp.Header)
+}
+
+// syncUpload asks the server how much of the upload it has received.
+func (c *Client) syncUpload(ctx context.Context, up *uploadTarget, state *UploadState) error {
+	resp, err := c.sendDirect(ctx, up.host, "HEAD", &up.config, up.params, tusHeader(), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return state.setOffset(resp.Header)
+}
+
+// setOffset records the Upload-Offset reported by the server.
+func (s *UploadState) setOffset(header http.Header) error {
+	offset, err := strconv.ParseInt(header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 || offset > s.Size {
+		return fmt.Errorf("apiclient: invalid Upload-Offset %q", header.Get("Upload-Offset"))
+	}
+	s.Offset = offset
+	return nil
+}
+
+// uploadTarget is the URL of an upload, split the way sendTo expects it. Uploads always go to this URL, whatever
+// the client's base URLs.
+type uploadTarget struct {
+	host   string
+	config APIConfig
+	params queryParams
+}
+
+func newUploadTarget(rawURL string) (*uploadTarget, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("apiclient: upload URL %q is not absolute", rawURL)
+	}
+	return &uploadTarget{host: u.Scheme + "://" + u.Host, config: APIConfig{Path: u.EscapedPath()}, params: queryParams(u.Query())}, nil
+}
+
+// queryParams is a request whose parameters are fixed.
+type queryParams url.Values
+
+func (q queryParams) Params() url.Values {
+	params := url.Values{}
+	for k, v := range q {
+		params[k] = append([]string(nil), v...)
+	}
+	return params
+}
+
+func tusHeader() http.Header {
+	h := http.Header{}
+	h.Set("Tus-Resumable", tusVersion)
+	return h
+}
+
+// encodeUploadMetadata encodes metadata for the Upload-Metadata header, as key and base64 value pairs in key order.
+func encodeUploadMetadata(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+	for k, v := range metadata {
+		pairs = append(pairs, k+" "+base64.StdEncoding.EncodeToString([]byte(v)))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
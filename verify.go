@@ -0,0 +1,93 @@
+package apiclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// VerifyProbe is the cheap request, e.g. a ping or whoami endpoint, Verify sends to check the client's setup.
+type VerifyProbe struct {
+	Config  *APIConfig
+	Request APIRequest
+	// MaxClockSkew is the largest difference tolerated between the local clock and the server's Date header.
+	// Zero disables the check.
+	MaxClockSkew time.Duration
+}
+
+// WithVerifyProbe configures the probe used by Verify.
+func WithVerifyProbe(probe VerifyProbe) ClientOption {
+	return func(c *Client) error {
+		c.verifyProbe = &probe
+		return nil
+	}
+}
+
+// VerifyReport describes the outcome of Verify.
+type VerifyReport struct {
+	// Reachable is true if the probe got an HTTP response.
+	Reachable bool
+	// TLSFailed is true if the connection failed because the server's certificate was not trusted or invalid.
+	TLSFailed bool
+	// Authenticated is false if the probe was rejected with 401 Unauthorized or 403 Forbidden.
+	Authenticated bool
+	StatusCode    int
+	// ClockSkew is the server's Date minus the local time. It is zero if the server sent no Date header.
+	ClockSkew time.Duration
+	Latency   time.Duration
+	// Err is the error the probe failed with, if it failed.
+	Err error
+}
+
+// Verify sends the configured VerifyProbe and reports whether connectivity, TLS trust, credentials and clock skew
+// check out. The returned error is nil only if every check passed.
+func (c *Client) Verify(ctx context.Context) (*VerifyReport, error) {
+	if c.verifyProbe == nil {
+		return nil, errors.New("apiclient: no verify probe configured")
+	}
+	ctx = c.defaultsContext(ctx)
+	report := &VerifyReport{}
+	start := time.Now()
+	resp, err := c.send(ctx, "GET", c.verifyProbe.Config, c.verifyProbe.Request, nil, nil)
+	report.Latency = time.Since(start)
+	if err != nil {
+		report.TLSFailed = isTLSError(err)
+		report.Err = err
+		return report, fmt.Errorf("apiclient: verify: %w", err)
+	}
+	resp.Body.Close()
+
+	report.Reachable = true
+	report.StatusCode = resp.StatusCode
+	report.Authenticated = resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden
+	if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		report.ClockSkew = date.Sub(start.Add(report.Latency / 2)).Round(time.Second)
+	}
+
+	switch {
+	case resp.StatusCode/100 != 2:
+		report.Err = &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	case c.verifyProbe.MaxClockSkew > 0 && (report.ClockSkew > c.verifyProbe.MaxClockSkew || -report.ClockSkew > c.verifyProbe.MaxClockSkew):
+		report.Err = fmt.Errorf("apiclient: clock skew of %v exceeds %v", report.ClockSkew, c.verifyProbe.MaxClockSkew)
+	}
+	if report.Err != nil {
+		return report, fmt.Errorf("apiclient: verify: %w", report.Err)
+	}
+	return report, nil
+}
+
+// isTLSError reports whether err was caused by an untrusted or invalid server certificate.
+func isTLSError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	var verification *tls.CertificateVerificationError
+	var record tls.RecordHeaderError
+	return errors.As(err, &unknownAuthority) || errors.As(err, &hostname) || errors.As(err, &invalid) ||
+		errors.As(err, &verification) || errors.As(err, &record)
+}
@@ -0,0 +1,117 @@
+package apiclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// Warmup opens conns connections (at least one) to each host the client sends requests to, so that its first calls
+// do not pay for DNS lookups and TCP and TLS handshakes. The hosts are its base URLs, or else the hosts of its
+// registered endpoints, plus any given in hosts as URLs such as "https://api.example.com". A host spoken HTTP/2
+// gets a single connection, which is enough for all its requests. Connections beyond WithMaxIdleConnsPerHost are
+// closed again.
+//
+// Each connection is opened by a HEAD request for the root of the host, outside the rate limiter and without
+// credentials; its response is discarded. Warmup does nothing in dry-run mode.
+func (c *Client) Warmup(ctx context.Context, conns int, hosts ...string) error {
+	if c.dryRun {
+		return nil
+	}
+	if conns < 1 {
+		conns = 1
+	}
+	var firstErr error
+	for _, host := range c.warmupHosts(hosts) {
+		if err := c.warmup(ctx, host, conns); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("apiclient: warm up %s: %w", host, err)
+		}
+	}
+	return firstErr
+}
+
+// warmupHosts returns the distinct scheme://host origins of the client's base URLs or regions, registered
+// endpoints and extra.
+func (c *Client) warmupHosts(extra []string) []string {
+	var bases []string
+	switch {
+	case c.regions != nil:
+		for _, e := range c.regions.set.snapshot() {
+			bases = append(bases, e.base)
+		}
+	case c.endpoints != nil:
+		for _, e := range c.endpoints.snapshot() {
+			bases = append(bases, e.base)
+		}
+	case c.currentBaseURL() != "":
+		bases = append(bases, c.currentBaseURL())
+	default:
+		c.registryMu.Lock()
+		for _, e := range c.registry {
+			bases = append(bases, e.config.Host)
+		}
+		c.registryMu.Unlock()
+	}
+	seen := map[string]bool{}
+	var origins []string
+	for _, base := range append(bases, extra...) {
+		u, err := url.Parse(base)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		origin := u.Scheme + "://" + u.Host
+		if !seen[origin] {
+			seen[origin] = true
+			origins = append(origins, origin)
+		}
+	}
+	sort.Strings(origins)
+	return origins
+}
+
+// warmup opens conns connections to origin. The first request tells whether the host speaks HTTP/2; otherwise the
+// others are sent concurrently, so each needs its own connection.
+func (c *Client) warmup(ctx context.Context, origin string, conns int) error {
+	proto, err := c.warmupRequest(ctx, origin)
+	if err != nil || proto == 2 || conns == 1 {
+		return err
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, conns)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = c.warmupRequest(ctx, origin)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// warmupRequest sends a HEAD request to origin, returning the major version of the protocol it was answered over.
+func (c *Client) warmupRequest(ctx context.Context, origin string) (int, error) {
+	req, err := http.NewRequest("HEAD", origin+"/", nil)
+	if err != nil {
+		return 0, err
+	}
+	stampUserAgent(req.Header)
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	// The connection returns to the pool only once the body has been read.
+	copyBuffer(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.ProtoMajor, nil
+}
@@ -0,0 +1,178 @@
+package apiclient
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidSignature is returned for webhooks whose signature was not made with any of the secrets.
+	ErrInvalidSignature = errors.New("apiclient: invalid webhook signature")
+	// ErrStaleWebhook is returned for webhooks whose timestamp is outside the tolerance window, such as replays.
+	ErrStaleWebhook = errors.New("apiclient: webhook timestamp outside tolerance")
+)
+
+const (
+	defaultWebhookTolerance = 5 * time.Minute
+	defaultWebhookMaxBody   = 1 << 20
+)
+
+// WebhookVerifier checks the signatures of the webhooks an API sends back, an HMAC of the request's timestamp and
+// body. By default the signature is the hex or base64 HMAC-SHA256 of the timestamp, a dot and the body, sent in the
+// X-Signature header along with the timestamp, in seconds since the epoch, in X-Timestamp. The signature header may
+// hold several signatures separated by commas or spaces, each optionally prefixed as in "v1=" or "sha256=", and may
+// carry the timestamp itself as "t=", as some providers do.
+type WebhookVerifier struct {
+	// Secrets are the signing secrets, any of which may have signed a webhook, so that a secret can be rotated:
+	// add the new secret, then remove the old one once the API signs with the new.
+	Secrets [][]byte
+	// SignatureHeader defaults to X-Signature.
+	SignatureHeader string
+	// TimestampHeader defaults to X-Timestamp.
+	TimestampHeader string
+	// Tolerance is how far the timestamp may be from the current time. Defaults to 5 minutes.
+	Tolerance time.Duration
+	// Hash defaults to sha256.New.
+	Hash func() hash.Hash
+	// Payload returns the signed bytes of a webhook. Defaults to the timestamp, a dot and the body.
+	Payload func(timestamp string, body []byte) []byte
+	// MaxBody bounds the body read by VerifyRequest. Defaults to 1 MiB.
+	MaxBody int64
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// Verify checks the signature in header of a webhook with body. It fails with ErrStaleWebhook if the timestamp is
+// outside the tolerance window, and with ErrInvalidSignature if no secret made the signature.
+func (v *WebhookVerifier) Verify(header http.Header, body []byte) error {
+	timestamp, signatures := v.parse(header.Get(v.signatureHeader()))
+	if ts := header.Get(v.timestampHeader()); ts != "" {
+		timestamp = ts
+	}
+	secs, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp %q", ErrStaleWebhook, timestamp)
+	}
+	tolerance := v.Tolerance
+	if tolerance <= 0 {
+		tolerance = defaultWebhookTolerance
+	}
+	if d := v.now().Sub(time.Unix(secs, 0)); d > tolerance || d < -tolerance {
+		return ErrStaleWebhook
+	}
+	payload := v.payload(timestamp, body)
+	for _, secret := range v.Secrets {
+		mac := v.mac(secret, payload)
+		for _, sig := range signatures {
+			if hmac.Equal(sig, mac) {
+				return nil
+			}
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// VerifyRequest reads the body of the webhook r, checks its signature like Verify, and returns the body, which is
+// also left to be read again from r.
+func (v *WebhookVerifier) VerifyRequest(r *http.Request) ([]byte, error) {
+	max := v.MaxBody
+	if max <= 0 {
+		max = defaultWebhookMaxBody
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, max+1))
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > max {
+		return nil, fmt.Errorf("apiclient: webhook body larger than %d bytes", max)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err := v.Verify(r.Header, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// Sign returns the headers signing body at t with the first secret, as the API would, e.g. to test a webhook handler.
+func (v *WebhookVerifier) Sign(body []byte, t time.Time) http.Header {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	h := http.Header{}
+	h.Set(v.timestampHeader(), timestamp)
+	if len(v.Secrets) > 0 {
+		h.Set(v.signatureHeader(), hex.EncodeToString(v.mac(v.Secrets[0], v.payload(timestamp, body))))
+	}
+	return h
+}
+
+// parse splits a signature header into the timestamp it carries, if any, and the decoded signatures.
+func (v *WebhookVerifier) parse(value string) (timestamp string, signatures [][]byte) {
+	for _, field := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		name, rest, ok := strings.Cut(field, "=")
+		if ok && name == "t" {
+			timestamp = rest
+			continue
+		}
+		if ok && strings.TrimRight(rest, "=") != "" {
+			// Base64 only has "=" as trailing padding, so one followed by more is the end of a prefix.
+			field = rest
+		}
+		if sig, err := hex.DecodeString(field); err == nil {
+			signatures = append(signatures, sig)
+		} else if sig, err := base64.StdEncoding.DecodeString(field); err == nil {
+			signatures = append(signatures, sig)
+		} else if sig, err := base64.RawURLEncoding.DecodeString(field); err == nil {
+			signatures = append(signatures, sig)
+		}
+	}
+	return timestamp, signatures
+}
+
+func (v *WebhookVerifier) mac(secret, payload []byte) []byte {
+	h := v.Hash
+	if h == nil {
+		h = sha256.New
+	}
+	mac := hmac.New(h, secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func (v *WebhookVerifier) payload(timestamp string, body []byte) []byte {
+	if v.Payload != nil {
+		return v.Payload(timestamp, body)
+	}
+	return append([]byte(timestamp+"."), body...)
+}
+
+func (v *WebhookVerifier) signatureHeader() string {
+	if v.SignatureHeader == "" {
+		return "X-Signature"
+	}
+	return v.SignatureHeader
+}
+
+func (v *WebhookVerifier) timestampHeader() string {
+	if v.TimestampHeader == "" {
+		return "X-Timestamp"
+	}
+	return v.TimestampHeader
+}
+
+func (v *WebhookVerifier) now() time.Time {
+	if v.Now == nil {
+		return time.Now()
+	}
+	return v.Now()
+}
@@ -0,0 +1,115 @@
+//go:build !apiclient_slim
+
+package apiclient
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/websocket"
+)
+
+// WSConn is a WebSocket connection opened with Dial. Binaries built with the apiclient_slim tag leave WebSocket support
+// out.
+type WSConn struct {
+	conn *websocket.Conn
+}
+
+// ReadJSON reads the next message and decodes it as JSON into v.
+func (w *WSConn) ReadJSON(v interface{}) error {
+	return websocket.JSON.Receive(w.conn, v)
+}
+
+// WriteJSON sends v encoded as JSON in a single message.
+func (w *WSConn) WriteJSON(v interface{}) error {
+	return websocket.JSON.Send(w.conn, v)
+}
+
+// Close closes the connection.
+func (w *WSConn) Close() error {
+	return w.conn.Close()
+}
+
+// Dial opens a WebSocket connection to the endpoint, upgrading from the host and path it would use for GETs (http
+// becomes ws, https becomes wss). The handshake carries the same credentials, impersonation and request ID headers
+// as any other request, along with the headers of the client's defaults, and waits for the rate limiter. Like a call,
+// the handshake is waited for by Shutdown and aborted by CancelAll; the connection it opens is not.
+func (c *Client) Dial(ctx context.Context, config *APIConfig, apiReq APIRequest) (*WSConn, error) {
+	ctx = c.withRequestID(c.defaultsContext(ctx))
+	// The handshake is an outstanding call, holding a bulkhead and in-flight slot, until it completes.
+	callCtx, done, err := c.calls.begin(ctx)
+	if err != nil {
+		return nil, c.requestError(ctx, err)
+	}
+	defer done()
+	ctx = callCtx
+	if err := c.checkRequest(ctx, "GET", config, apiReq); err != nil {
+		return nil, c.requestError(ctx, err)
+	}
+	origin := c.host(ctx, config)
+	path, err := expandPath(ctx, config, apiReq)
+	if err != nil {
+		return nil, c.requestError(ctx, err)
+	}
+	location := origin + path
+	switch {
+	case strings.HasPrefix(location, "https://"):
+		location = "wss://" + strings.TrimPrefix(location, "https://")
+	case strings.HasPrefix(location, "http://"):
+		location = "ws://" + strings.TrimPrefix(location, "http://")
+	}
+	wsConfig, err := websocket.NewConfig(location, origin)
+	if err != nil {
+		return nil, c.requestError(ctx, err)
+	}
+
+	wsConfig.Header = http.Header{}
+	for k, v := range headerFromContext(ctx) {
+		wsConfig.Header[k] = append([]string(nil), v...)
+	}
+	if c.impersonation != nil {
+		if err := c.impersonation.apply(ctx, wsConfig.Header); err != nil {
+			return nil, c.requestError(ctx, err)
+		}
+	}
+	q := apiReq.Params()
+	if _, err := c.authenticate(ctx, wsConfig.Header, q); err != nil {
+		return nil, c.requestError(ctx, err)
+	}
+	wsConfig.Location.RawQuery = q.Encode()
+	if id := RequestIDFromContext(ctx); id != "" && c.requestIDHeader != "" {
+		wsConfig.Header.Set(c.requestIDHeader, id)
+	}
+	if t, ok := c.httpClient.Transport.(*transport); ok {
+		if base, ok := t.Base.(*http.Transport); ok && base.TLSClientConfig != nil {
+			wsConfig.TlsConfig = base.TLSClientConfig.Clone()
+		}
+	}
+
+	leave, err := c.enterBulkhead(ctx, "GET", config)
+	if err != nil {
+		return nil, c.requestError(ctx, err)
+	}
+	defer leave()
+	slot, err := acquire(ctx, c.inFlight)
+	if err != nil {
+		return nil, c.requestError(ctx, err)
+	}
+	defer slot()
+	if err := c.takeQuota(ctx); err != nil {
+		return nil, c.requestError(ctx, err)
+	}
+	if err := c.rateLimiter.wait(ctx, readTraffic, priorityFromContext(ctx)); err != nil {
+		return nil, c.requestError(ctx, err)
+	}
+	conn, err := wsConfig.DialContext(ctx)
+	if err != nil && ctx.Err() != nil {
+		// Aborted, for instance by CancelAll: fail like any other call.
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, c.requestError(ctx, err)
+	}
+	return &WSConn{conn: conn}, nil
+}
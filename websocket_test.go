@@ -0,0 +1,69 @@
+//go:build !apiclient_slim
+
+package apiclient
+
+import (
+	"net/http"
+	"testing"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/websocket"
+)
+
+func TestDialAppliesDefaults(t *testing.T) {
+	parent := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("%s %s sent to the parent's host", r.Method, r.URL.Path)
+	})
+	headers := make(chan http.Header, 1)
+	tenantSrv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Clone()
+		websocket.Handler(func(ws *websocket.Conn) { ws.Close() }).ServeHTTP(w, r)
+	})
+	c := newTestClient(t)
+	tests := []struct {
+		name   string
+		client *Client
+		want   http.Header
+	}{
+		{
+			name:   "header",
+			client: c.WithDefaults(WithBaseURL(tenantSrv.URL), WithHeader("X-Call", "tenant")),
+			want:   http.Header{"X-Call": {"tenant"}},
+		},
+		{
+			name: "credentials",
+			client: c.WithDefaults(WithBaseURL(tenantSrv.URL),
+				WithRequestCredentials(Credential{Name: "X-Tenant", Value: "acme", In: InHeader})),
+			want: http.Header{"X-Tenant": {"acme"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := tt.client.Dial(context.Background(), &APIConfig{Host: parent.URL}, testParams{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn.Close()
+			h := <-headers
+			for k := range tt.want {
+				if h.Get(k) != tt.want.Get(k) {
+					t.Errorf("handshake %s = %q, want %q", k, h.Get(k), tt.want.Get(k))
+				}
+			}
+		})
+	}
+}
+
+func init() {
+	acceptWebSocket = websocket.Handler(func(ws *websocket.Conn) { ws.Close() }).ServeHTTP
+	trackedCalls = append(trackedCalls, blockedCall{
+		name: "websocket handshake",
+		call: func(c *Client, host string) error {
+			conn, err := c.Dial(context.Background(), &APIConfig{Host: host}, testParams{})
+			if err == nil {
+				conn.Close()
+			}
+			return err
+		},
+	})
+}
@@ -0,0 +1,79 @@
+package apiclient
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/html/charset"
+)
+
+// XMLError reports a response body that is not valid XML.
+type XMLError struct {
+	Err error
+}
+
+func (e *XMLError) Error() string {
+	return fmt.Sprintf("apiclient: invalid XML response: %v", e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *XMLError) Unwrap() error {
+	return e.Err
+}
+
+// GetXML makes a request to the API endpoint and decodes its XML response into resp with encoding/xml. The body is
+// read in the charset given by its Content-Type, or else by its XML declaration. A body that is not valid XML fails
+// with an *XMLError.
+func (c *Client) GetXML(ctx context.Context, config *APIConfig, apiReq APIRequest, resp interface{}, opts ...RequestOption) error {
+	o := c.newRequestOptions(opts)
+	ctx, cancel := o.context(ctx, config)
+	defer cancel()
+	ctx = c.withRequestID(defaultAccept(ctx, "application/xml, text/xml;q=0.9"))
+	httpResp, err := c.get(ctx, config, apiReq)
+	if err != nil {
+		return c.requestError(ctx, err)
+	}
+	defer httpResp.Body.Close()
+	if o.meta != nil {
+		o.meta.fill(httpResp)
+	}
+	return c.requestError(ctx, c.decodeXML(httpResp, resp, o))
+}
+
+// decodeXML decodes the XML body of httpResp into resp, with the call's decoder if it has one.
+func (c *Client) decodeXML(httpResp *http.Response, resp interface{}, o *requestOptions) error {
+	body, err := c.textBody(httpResp)
+	if err != nil {
+		return err
+	}
+	httpResp.Body = body
+	if o.decoder != nil {
+		return o.decoder(httpResp, resp)
+	}
+	dec := xml.NewDecoder(body)
+	transcoded := headerDeclaresCharset(httpResp.Header.Get("Content-Type"))
+	dec.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		if transcoded {
+			// The header's charset wins over the XML declaration, and has been applied already.
+			return input, nil
+		}
+		return charset.NewReaderLabel(label, input)
+	}
+	err = dec.Decode(resp)
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) || err == io.EOF || err == io.ErrUnexpectedEOF {
+		return &XMLError{Err: err}
+	}
+	return err
+}
+
+// headerDeclaresCharset reports whether contentType names a charset.
+func headerDeclaresCharset(contentType string) bool {
+	_, params, err := mime.ParseMediaType(contentType)
+	return err == nil && params["charset"] != ""
+}
@@ -0,0 +1,42 @@
+package apiclient
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// DefaultXSSIPrefixes are the anti-XSSI prefixes stripped when WithXSSIPrefixes is called without arguments.
+var DefaultXSSIPrefixes = []string{")]}',\n", ")]}'\n", ")]}'", "while(1);", "for(;;);"}
+
+// WithXSSIPrefixes configures GetJSON to strip any of the given security prefixes from the start of a response body
+// before decoding it. Without arguments DefaultXSSIPrefixes is used.
+func WithXSSIPrefixes(prefixes ...string) ClientOption {
+	return func(c *Client) error {
+		if len(prefixes) == 0 {
+			prefixes = DefaultXSSIPrefixes
+		}
+		c.xssiPrefixes = prefixes
+		return nil
+	}
+}
+
+// stripPrefix returns a body with the longest matching prefix removed from the front of body.
+func stripPrefix(body io.ReadCloser, prefixes []string) io.ReadCloser {
+	max := 0
+	for _, p := range prefixes {
+		if len(p) > max {
+			max = len(p)
+		}
+	}
+	br := bufio.NewReaderSize(body, max)
+	head, _ := br.Peek(max)
+	n := 0
+	for _, p := range prefixes {
+		if len(p) > n && bytes.HasPrefix(head, []byte(p)) {
+			n = len(p)
+		}
+	}
+	br.Discard(n)
+	return readCloser{br, body}
+}